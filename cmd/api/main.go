@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -10,6 +13,8 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 
 	"go-fiber-api/docs"
@@ -20,6 +25,10 @@ import (
 	"go-fiber-api/internal/service"
 	"go-fiber-api/pkg/database"
 	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/notifier"
+	"go-fiber-api/pkg/ratelimit"
+	"go-fiber-api/pkg/scanner"
+	"go-fiber-api/pkg/storage"
 	"go-fiber-api/pkg/utils"
 )
 
@@ -40,10 +49,183 @@ func setupMongoDB(cfg *config.Config) (*mongo.Client, error) {
 	return client, nil
 }
 
+// bodyLimit lets multipart uploads up to the configured request size through,
+// with room for the multipart framing and the other form fields
+func bodyLimit(cfg *config.Config) int {
+	if cfg.UploadMaxRequestSize <= 0 {
+		return fiber.DefaultBodyLimit
+	}
+	return int(cfg.UploadMaxRequestSize) + 1<<20
+}
+
+// prepareDatabase seeds default data and creates the indexes the services rely on
+func prepareDatabase(policyService *service.PolicyService, userService *service.UserService, shopMemberService *service.ShopMemberService, shopService *service.ShopService, budgetService *service.BudgetService, categoryService *service.CategoryService, fileStoreService *service.FileStoreService, auditService *service.AuditService) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := policyService.SeedRoles(ctx); err != nil {
+		return err
+	}
+	if err := userService.MigrateEmailVerified(ctx); err != nil {
+		return err
+	}
+	if err := shopMemberService.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := shopMemberService.MigrateCreators(ctx); err != nil {
+		return err
+	}
+	if err := shopService.MigrateBudgets(ctx); err != nil {
+		return err
+	}
+	if err := budgetService.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := categoryService.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := auditService.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	return fileStoreService.MigrateStorage(ctx)
+}
+
+// startReconcile schedules the reconciliation when RECONCILE_INTERVAL is set
+func startReconcile(reconcileService *service.ReconcileService, cfg *config.Config) error {
+	if cfg.ReconcileInterval == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(cfg.ReconcileInterval)
+	if err != nil {
+		return err
+	}
+	reconcileService.Start(context.Background(), interval, cfg.ReconcileApply, cfg.ReconcileReportDir)
+	return nil
+}
+
+// startTrashPurge schedules the purge of expired trash when TRASH_PURGE_INTERVAL is set
+func startTrashPurge(trashService *service.TrashService, cfg *config.Config) error {
+	if cfg.TrashPurgeInterval == "" {
+		return nil
+	}
+	interval, err := time.ParseDuration(cfg.TrashPurgeInterval)
+	if err != nil {
+		return err
+	}
+	trashService.Start(context.Background(), interval)
+	return nil
+}
+
+// runReconcile is the reconcile subcommand: it compares file rows, blobs and
+// stored objects once and writes the report
+func runReconcile(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("reconcile", flag.ExitOnError)
+	apply := flags.Bool("apply", false, "delete orphans and fix reference counts instead of only reporting them")
+	grace := flags.Duration("grace", service.ReconcileGrace, "leave objects and blobs changed more recently alone")
+	reportPath := flags.String("report", "-", "file the JSON report is written to, - for stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	mongoClient, err := setupMongoDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	backends, err := storage.New(cfg)
+	if err != nil {
+		return err
+	}
+
+	db := mongoClient.Database(cfg.MongoDBDatabase)
+	fileStoreRepository := repository.NewFileStoreRepository(db)
+	blobRepository := repository.NewBlobRepository(db)
+	blobService := service.NewBlobService(blobRepository, backends)
+	quotaService, err := service.NewQuotaService(repository.NewShopRepository(db), fileStoreRepository, cfg)
+	if err != nil {
+		return err
+	}
+	derivativeService, err := service.NewDerivativeService(fileStoreRepository, blobService, backends, quotaService, cfg)
+	if err != nil {
+		return err
+	}
+	fileScanner, err := scanner.New(cfg)
+	if err != nil {
+		return err
+	}
+	scanService := service.NewScanService(fileStoreRepository, backends, fileScanner, derivativeService, cfg)
+	fileStoreService := service.NewFileStoreService(fileStoreRepository, blobService, backends, scanService, quotaService, cfg)
+	reconcileService := service.NewReconcileService(fileStoreRepository, blobRepository, backends, fileStoreService)
+
+	report, err := reconcileService.Run(context.Background(), *apply, *grace)
+	if err != nil {
+		return err
+	}
+	log.Println(report.Summary())
+
+	if *reportPath == "-" {
+		return report.WriteJSON(os.Stdout)
+	}
+	file, err := os.Create(*reportPath)
+	if err != nil {
+		return err
+	}
+	if err := report.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// runPromoteAdmin is the promote-admin subcommand: it gives the admin role to
+// a registered user, the way to get the first admin
+func runPromoteAdmin(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("promote-admin", flag.ExitOnError)
+	email := flags.String("email", "", "email of the registered user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return errors.New("-email is required")
+	}
+
+	mongoClient, err := setupMongoDB(cfg)
+	if err != nil {
+		return err
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userRepository := repository.NewUserRepository(mongoClient.Database(cfg.MongoDBDatabase))
+	user, err := userRepository.FindOne(ctx, bson.M{"email": *email})
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fmt.Errorf("no user with email %s", *email)
+	}
+	for _, role := range user.Roles {
+		if role == string(utils.AdminRole) {
+			log.Printf("%s is already an admin", *email)
+			return nil
+		}
+	}
+
+	if _, err := userRepository.UpdateRoles(ctx, user.ID, append(user.Roles, string(utils.AdminRole))); err != nil {
+		return err
+	}
+	log.Printf("%s is now an admin, the role applies from the next login", *email)
+	return nil
+}
+
 func setupServer(cfg *config.Config) (*routes.Application, error) {
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
-		AppName: "Go Fiber API v1.0",
+		AppName:   "Go Fiber API v1.0",
+		BodyLimit: bodyLimit(cfg),
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
 			code := fiber.StatusInternalServerError
 			if e, ok := err.(*fiber.Error); ok {
@@ -61,13 +243,16 @@ func setupServer(cfg *config.Config) (*routes.Application, error) {
 	// Setup CORS
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     "*",
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS,PATCH",
-		AllowHeaders:     "Origin,Authorization,Content-Type",
-		ExposeHeaders:    "Content-Length",
+		AllowMethods:     "GET,HEAD,POST,PUT,DELETE,OPTIONS,PATCH",
+		AllowHeaders:     "Origin,Authorization,Content-Type,Range,If-Range,If-None-Match,If-Modified-Since,Tus-Resumable,Upload-Length,Upload-Metadata,Upload-Offset",
+		ExposeHeaders:    "X-Request-ID,X-RateLimit-Limit,X-RateLimit-Remaining,X-RateLimit-Reset,Retry-After,Content-Length,Content-Range,Content-Disposition,Accept-Ranges,ETag,Last-Modified,Location,Tus-Resumable,Tus-Version,Tus-Extension,Tus-Max-Size,Upload-Offset,Upload-Length,Upload-Expires,Upload-Metadata,File-Id",
 		AllowCredentials: cfg.ServerState == "production",
 		MaxAge:           12 * 60 * 60, // 12 hours
 	}))
 
+	// Every response carries an X-Request-ID, audit entries record it
+	app.Use(requestid.New())
+
 	// Setup MongoDB
 	mongoClient, err := setupMongoDB(cfg)
 	if err != nil {
@@ -80,41 +265,130 @@ func setupServer(cfg *config.Config) (*routes.Application, error) {
 		return nil, err
 	}
 
+	// Setup file storage
+	backends, err := storage.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize repositories
 	db := mongoClient.Database(cfg.MongoDBDatabase)
 	userRepository := repository.NewUserRepository(db)
 	shopRepository := repository.NewShopRepository(db)
 	categoryRepository := repository.NewCategoryRepository(db)
 	fileStoreRepository := repository.NewFileStoreRepository(db)
+	productRepository := repository.NewProductRepository(db)
+	roleRepository := repository.NewRoleRepository(db)
+	shopMemberRepository := repository.NewShopMemberRepository(db)
+	budgetRepository := repository.NewBudgetRepository(db)
+	blobRepository := repository.NewBlobRepository(db)
 	httpServiceRepository := repository.NewHttpServiceRepository()
 
 	// Initialize services
-	userService := service.NewUserService(userRepository, redisClient, cfg)
+	notify := notifier.New(cfg)
+	userService := service.NewUserService(userRepository, roleRepository, redisClient, notify, cfg)
+	policyService := service.NewPolicyService(roleRepository)
 	shopService := service.NewShopService(shopRepository)
 	categoryService := service.NewCategoryService(categoryRepository)
-	fileStoreService := service.NewFileStoreService(fileStoreRepository)
+	blobService := service.NewBlobService(blobRepository, backends)
+	quotaService, err := service.NewQuotaService(shopRepository, fileStoreRepository, cfg)
+	if err != nil {
+		return nil, err
+	}
+	derivativeService, err := service.NewDerivativeService(fileStoreRepository, blobService, backends, quotaService, cfg)
+	if err != nil {
+		return nil, err
+	}
+	fileScanner, err := scanner.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	scanService := service.NewScanService(fileStoreRepository, backends, fileScanner, derivativeService, cfg)
+	fileStoreService := service.NewFileStoreService(fileStoreRepository, blobService, backends, scanService, quotaService, cfg)
+	productService := service.NewProductService(productRepository, categoryRepository, fileStoreRepository)
 	artworkApiService := service.NewArtworkApiService(httpServiceRepository, cfg)
+	shopMemberService := service.NewShopMemberService(shopMemberRepository, notify, cfg)
+	budgetService := service.NewBudgetService(budgetRepository)
+	fileLinkService, err := service.NewFileLinkService(redisClient, cfg)
+	if err != nil {
+		return nil, err
+	}
+	reconcileService := service.NewReconcileService(fileStoreRepository, blobRepository, backends, fileStoreService)
+	tusService, err := service.NewTusService(redisClient, fileStoreService, cfg)
+	if err != nil {
+		return nil, err
+	}
+	deletionService, err := service.NewDeletionService(repository.NewDeletionRepository(db), blobService, cfg)
+	if err != nil {
+		return nil, err
+	}
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	trashService, err := service.NewTrashService(userRepository, shopRepository, categoryRepository, deletionService, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := prepareDatabase(policyService, userService, shopMemberService, shopService, budgetService, categoryService, fileStoreService, auditService); err != nil {
+		return nil, err
+	}
+	derivativeService.Start(context.Background())
+	scanService.Start(context.Background())
+	tusService.StartCleanup(context.Background(), time.Hour)
+	// Entries still queued are written before the server exits
+	auditService.Start()
+	app.Hooks().OnShutdown(func() error {
+		auditService.Stop()
+		return nil
+	})
+	if err := startReconcile(reconcileService, cfg); err != nil {
+		return nil, err
+	}
+	if err := startTrashPurge(trashService, cfg); err != nil {
+		return nil, err
+	}
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
-	shopHandler := handlers.NewShopHandler(shopService, fileStoreService)
-	categoryHandler := handlers.NewCategoryHandler(categoryService, shopService)
-	fileStoreHandler := handlers.NewFileStoreHandler(fileStoreService, shopService)
+	shopHandler := handlers.NewShopHandler(shopService, fileStoreService, shopMemberService, budgetService, policyService)
+	categoryHandler := handlers.NewCategoryHandler(categoryService, shopService, policyService)
+	fileStoreHandler := handlers.NewFileStoreHandler(fileStoreService, fileLinkService, shopService, policyService)
+	productHandler := handlers.NewProductHandler(productService, shopService, policyService)
+	roleHandler := handlers.NewRoleHandler(policyService)
+	shopMemberHandler := handlers.NewShopMemberHandler(shopMemberService, shopService, policyService)
+	budgetHandler := handlers.NewBudgetHandler(budgetService, shopService, policyService)
+	uploadHandler := handlers.NewUploadHandler(tusService, shopService, policyService)
 	otherHandler := handlers.NewOtherHandler(artworkApiService)
+	trashHandler := handlers.NewTrashHandler(trashService, userService)
+	auditHandler := handlers.NewAuditHandler(auditService)
 
 	// Initialize middleware
-	authMiddleware := middleware.NewAuthMiddleware(userService, cfg)
+	authMiddleware := middleware.NewAuthMiddleware(userService, policyService, cfg)
+	auditMiddleware := middleware.NewAuditMiddleware(auditService)
+	rateLimitStore, err := ratelimit.New(cfg, redisClient)
+	if err != nil {
+		return nil, err
+	}
+	rateLimiter := middleware.NewRateLimiter(rateLimitStore)
 
 	// Create application instance
 	application := &routes.Application{
-		App:              app,
-		UserHandler:      userHandler,
-		ShopHandler:      shopHandler,
-		CategoryHandler:  categoryHandler,
-		FileStoreHandler: fileStoreHandler,
-		OtherHandler:     otherHandler,
-		AuthMiddleware:   authMiddleware,
-		Config:           cfg,
+		App:               app,
+		UserHandler:       userHandler,
+		ShopHandler:       shopHandler,
+		CategoryHandler:   categoryHandler,
+		FileStoreHandler:  fileStoreHandler,
+		ProductHandler:    productHandler,
+		OtherHandler:      otherHandler,
+		RoleHandler:       roleHandler,
+		ShopMemberHandler: shopMemberHandler,
+		BudgetHandler:     budgetHandler,
+		UploadHandler:     uploadHandler,
+		TrashHandler:      trashHandler,
+		AuditHandler:      auditHandler,
+		AuthMiddleware:    authMiddleware,
+		AuditMiddleware:   auditMiddleware,
+		RateLimiter:       rateLimiter,
+		Config:            cfg,
 	}
 
 	// Setup routes
@@ -141,6 +415,20 @@ func setupServer(cfg *config.Config) (*routes.Application, error) {
 func main() {
 	cfg := config.LoadConfig()
 
+	if len(os.Args) > 1 && os.Args[1] == "reconcile" {
+		if err := runReconcile(cfg, os.Args[2:]); err != nil {
+			log.Fatal("Reconcile failed:", err)
+		}
+		return
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "promote-admin" {
+		if err := runPromoteAdmin(cfg, os.Args[2:]); err != nil {
+			log.Fatal("Promote admin failed:", err)
+		}
+		return
+	}
+
 	application, err := setupServer(cfg)
 	if err != nil {
 		log.Fatal("Failed to setup server:", err)
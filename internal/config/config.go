@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -23,6 +25,73 @@ type Config struct {
 	ArtworkApiURL string
 
 	RedisURL string
+
+	ClientURL string
+
+	PasswordResetExpiresIn string
+
+	// EmailVerificationMode is off, limit (read only until verified) or block
+	EmailVerificationMode      string
+	EmailVerificationExpiresIn string
+
+	NotifierDriver   string
+	NotifierFilePath string
+
+	// StorageDriver is local or s3, files already stored keep their backend
+	StorageDriver    string
+	StorageLocalPath string
+
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3UsePathStyle bool
+
+	// Upload limits in bytes, the allowed types are sniffed from the content
+	UploadMaxFileSize    int64
+	UploadMaxRequestSize int64
+	UploadAllowedTypes   []string
+
+	// ImageVariants lists the derivatives rendered for images as name:WIDTHxHEIGHT:format,
+	// images of more than ImageMaxPixels get none
+	ImageVariants  string
+	ImageWorkers   int
+	ImageMaxPixels int64
+
+	// FileSigningKeys signs download links as id:secret pairs, the first one signs new links.
+	// Left empty, signed links are disabled
+	FileSigningKeys   string
+	FileLinkMaxExpiry string
+
+	// TusStagingPath holds resumable uploads until they are complete
+	TusStagingPath string
+	TusExpiresIn   string
+
+	// ReconcileInterval runs the storage reconciliation periodically, empty disables it
+	ReconcileInterval  string
+	ReconcileApply     bool
+	ReconcileReportDir string
+
+	// StoragePlans lists the storage quota of each plan as name:bytes, 0 is unlimited
+	StoragePlans       []string
+	StorageDefaultPlan string
+
+	// ScannerDriver is none or clamav, files are blocked from download until they are scanned clean
+	ScannerDriver string
+	ClamdAddress  string
+	ScanWorkers   int
+
+	// DeletionPolicies sets how deleting a shop or user treats each relation, as relation:policy
+	DeletionPolicies []string
+
+	// TrashRetention is how long soft deleted items are kept, TrashPurgeInterval
+	// how often the expired ones are purged, empty disables the purge
+	TrashRetention     string
+	TrashPurgeInterval string
+
+	// RateLimitStore is redis, shared by all replicas, or memory for a single process
+	RateLimitStore string
 }
 
 func LoadConfig() *Config {
@@ -47,5 +116,85 @@ func LoadConfig() *Config {
 		ArtworkApiURL: os.Getenv("ART_WORK_API_URL"),
 
 		RedisURL: os.Getenv("REDIS_URI"),
+
+		ClientURL: getEnv("CLIENT_URL", "http://localhost:3000"),
+
+		PasswordResetExpiresIn: getEnv("PASSWORD_RESET_EXPIRY", "30m"),
+
+		EmailVerificationMode:      getEnv("EMAIL_VERIFICATION_MODE", "off"),
+		EmailVerificationExpiresIn: getEnv("EMAIL_VERIFICATION_EXPIRY", "24h"),
+
+		NotifierDriver:   getEnv("NOTIFIER_DRIVER", "log"),
+		NotifierFilePath: getEnv("NOTIFIER_FILE_PATH", "./tmp/notifications.log"),
+
+		StorageDriver:    getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath: getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+
+		S3Endpoint:     os.Getenv("S3_ENDPOINT"),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       os.Getenv("S3_BUCKET"),
+		S3AccessKey:    os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey:    os.Getenv("S3_SECRET_KEY"),
+		S3UsePathStyle: getEnv("S3_USE_PATH_STYLE", "false") == "true",
+
+		UploadMaxFileSize:    getEnvInt64("UPLOAD_MAX_FILE_SIZE", 10<<20),
+		UploadMaxRequestSize: getEnvInt64("UPLOAD_MAX_REQUEST_SIZE", 50<<20),
+		UploadAllowedTypes:   getEnvList("UPLOAD_ALLOWED_TYPES", "image/*,application/pdf"),
+
+		ImageVariants:  getEnv("IMAGE_VARIANTS", "thumb:200x200:jpeg,medium:800x800:jpeg,full:0x0:jpeg"),
+		ImageWorkers:   int(getEnvInt64("IMAGE_WORKERS", 2)),
+		ImageMaxPixels: getEnvInt64("IMAGE_MAX_PIXELS", 40000000),
+
+		FileSigningKeys:   os.Getenv("FILE_SIGNING_KEYS"),
+		FileLinkMaxExpiry: getEnv("FILE_LINK_MAX_EXPIRY", "168h"),
+
+		TusStagingPath: getEnv("TUS_STAGING_PATH", "./tmp/tus"),
+		TusExpiresIn:   getEnv("TUS_EXPIRES_IN", "24h"),
+
+		ReconcileInterval:  os.Getenv("RECONCILE_INTERVAL"),
+		ReconcileApply:     getEnv("RECONCILE_APPLY", "false") == "true",
+		ReconcileReportDir: getEnv("RECONCILE_REPORT_DIR", "./tmp/reconcile"),
+
+		StoragePlans:       getEnvList("STORAGE_PLANS", "free:104857600,pro:10737418240,unlimited:0"),
+		StorageDefaultPlan: getEnv("STORAGE_DEFAULT_PLAN", "free"),
+
+		ScannerDriver: getEnv("SCANNER_DRIVER", "none"),
+		ClamdAddress:  getEnv("CLAMD_ADDRESS", "tcp://localhost:3310"),
+		ScanWorkers:   int(getEnvInt64("SCAN_WORKERS", 2)),
+
+		DeletionPolicies: getEnvList("DELETION_POLICIES", ""),
+
+		TrashRetention:     getEnv("TRASH_RETENTION", "720h"),
+		TrashPurgeInterval: os.Getenv("TRASH_PURGE_INTERVAL"),
+
+		RateLimitStore: getEnv("RATE_LIMIT_STORE", "redis"),
+	}
+}
+
+// getEnv returns the environment value of key or fallback when it is not set
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok && value != "" {
+		return value
+	}
+	return fallback
+}
+
+// getEnvInt64 parses the environment value of key, using fallback when it is not a number
+func getEnvInt64(key string, fallback int64) int64 {
+	value, err := strconv.ParseInt(getEnv(key, ""), 10, 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList splits a comma separated environment value
+func getEnvList(key, fallback string) []string {
+	var list []string
+	for _, item := range strings.Split(getEnv(key, fallback), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
 	}
+	return list
 }
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// @Summary List audit log
+// @Description Get paginated audit entries of the writes made through the API, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param actor query string false "Filter by acting user ID"
+// @Param resource_type query string false "Filter by resource type, e.g. shop, category, user or file"
+// @Param resource_id query string false "Filter by resource ID"
+// @Param action query string false "Filter by action, e.g. shop.update"
+// @Param from query string false "Only entries at or after this RFC 3339 time"
+// @Param to query string false "Only entries before this RFC 3339 time"
+// @Success 200
+// @Router /admin/audit [get]
+func (a *AuditHandler) AuditList(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
+	filter := bson.M{}
+	if actor := c.Query("actor"); actor != "" {
+		actorID, err := primitive.ObjectIDFromHex(actor)
+		if err != nil {
+			return utils.SendError(c, http.StatusBadRequest, "Invalid actor ID format")
+		}
+		filter["actor_id"] = actorID
+	}
+	for _, field := range []string{"resource_type", "resource_id", "action"} {
+		if value := c.Query(field); value != "" {
+			filter[field] = value
+		}
+	}
+
+	createdAt := bson.M{}
+	for param, operator := range map[string]string{"from": "$gte", "to": "$lt"} {
+		if value := c.Query(param); value != "" {
+			at, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return utils.SendError(c, http.StatusBadRequest, "Invalid "+param+" time, use RFC 3339")
+			}
+			createdAt[operator] = at
+		}
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	total, err := a.auditService.Count(ctx, filter)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to count audit entries: "+err.Error())
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	entries, err := a.auditService.FindAll(ctx, filter, opts)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, entries)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BudgetHandler struct {
+	budgetService *service.BudgetService
+	shopService   *service.ShopService
+	policyService *service.PolicyService
+}
+
+func NewBudgetHandler(budgetService *service.BudgetService, shopService *service.ShopService, policyService *service.PolicyService) *BudgetHandler {
+	return &BudgetHandler{
+		budgetService: budgetService,
+		shopService:   shopService,
+		policyService: policyService,
+	}
+}
+
+// @Summary List budget transactions
+// @Description Get paginated ledger of a shop budget, newest first
+// @Tags budget
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param type query string false "Filter by type (credit or debit)"
+// @Success 200
+// @Router /shop/{id}/budget/transactions [get]
+func (b *BudgetHandler) TransactionList(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := b.findManagedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	filter := bson.M{"shop_id": shop.ID}
+	if kind := c.Query("type"); kind != "" {
+		filter["type"] = kind
+	}
+
+	total, err := b.budgetService.Count(ctx, filter)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to count transactions: "+err.Error())
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	transactions, err := b.budgetService.FindAll(ctx, filter, opts)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	responses := make([]*dto.BudgetTransactionResponse, len(transactions))
+	for i := range transactions {
+		responses[i] = transactionResponse(&transactions[i])
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, responses)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Credit budget endpoint
+// @Description Post the API's credit of a shop budget
+// @Tags budget
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param Idempotency-Key header string false "Idempotency key, replaces the one in the body"
+// @Param request body dto.BudgetTransactionRequest true "Transaction details"
+// @Router /shop/{id}/budget/credit [post]
+func (b *BudgetHandler) Credit(c *fiber.Ctx) error {
+	return b.transaction(c, model.BudgetCredit)
+}
+
+// @Summary Debit budget endpoint
+// @Description Post the API's debit of a shop budget, the balance can never go negative
+// @Tags budget
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param Idempotency-Key header string false "Idempotency key, replaces the one in the body"
+// @Param request body dto.BudgetTransactionRequest true "Transaction details"
+// @Router /shop/{id}/budget/debit [post]
+func (b *BudgetHandler) Debit(c *fiber.Ctx) error {
+	return b.transaction(c, model.BudgetDebit)
+}
+
+func (b *BudgetHandler) transaction(c *fiber.Ctx, kind string) error {
+	var req dto.BudgetTransactionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if key := c.Get("Idempotency-Key"); key != "" {
+		req.IdempotencyKey = key
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	amount, err := utils.ParseMoney(req.Amount)
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid amount")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, user, err := b.findManagedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	var entry *model.BudgetTransaction
+	if kind == model.BudgetCredit {
+		entry, err = b.budgetService.Credit(ctx, shop, user, amount, req.Reason, req.IdempotencyKey)
+	} else {
+		entry, err = b.budgetService.Debit(ctx, shop, user, amount, req.Reason, req.IdempotencyKey)
+	}
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to apply transaction")
+	}
+	middleware.Audit(c, "budget."+kind, "shop", shop.ID.Hex(),
+		fiber.Map{"budget": shop.Budget},
+		fiber.Map{"budget": entry.BalanceAfter, "reason": entry.Reason, "transaction_id": entry.ID})
+
+	return utils.SendSuccess(c, http.StatusCreated, transactionResponse(entry))
+}
+
+// findManagedShop loads the shop from the route and makes sure the current user may manage its budget
+func (b *BudgetHandler) findManagedShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, *model.User, error) {
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := b.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return nil, nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := b.policyService.AuthorizeShop(ctx, user, utils.BudgetManage, shop); err != nil {
+		return nil, nil, err
+	}
+	return shop, user, nil
+}
+
+// transactionResponse formats the amounts of entry the way they are given in requests
+func transactionResponse(entry *model.BudgetTransaction) *dto.BudgetTransactionResponse {
+	return &dto.BudgetTransactionResponse{
+		BudgetTransaction: entry,
+		Amount:            utils.FormatMoney(entry.Amount),
+		BalanceAfter:      utils.FormatMoney(entry.BalanceAfter),
+	}
+}
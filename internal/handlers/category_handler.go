@@ -2,26 +2,32 @@ package handlers
 
 import (
 	"context"
+	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/service"
 	"go-fiber-api/pkg/dto"
 	"go-fiber-api/pkg/middleware"
 	"go-fiber-api/pkg/utils"
 	"net/http"
+	"regexp"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CategoryHandler struct {
 	categoryService *service.CategoryService
 	shopService     *service.ShopService
+	policyService   *service.PolicyService
 }
 
-func NewCategoryHandler(categoryService *service.CategoryService, shopService *service.ShopService) *CategoryHandler {
+func NewCategoryHandler(categoryService *service.CategoryService, shopService *service.ShopService, policyService *service.PolicyService) *CategoryHandler {
 	return &CategoryHandler{
 		categoryService: categoryService,
 		shopService:     shopService,
+		policyService:   policyService,
 	}
 }
 
@@ -61,52 +67,230 @@ func (h *CategoryHandler) Create(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
 	}
 
-	if shop.CreatedBy != user.ID {
-		return utils.SendError(c, http.StatusUnauthorized, "Unauthorized to create category")
+	if err := h.policyService.AuthorizeShop(ctx, user, utils.CategoryManage, shop); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized to create category")
 	}
 
 	category, err := h.categoryService.Create(ctx, &req, shop)
 	if err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, "Failed to create category")
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to create category")
 	}
 
+	middleware.Audit(c, "category.create", "category", category.ID.Hex(), nil, category)
 	return utils.SendSuccess(c, http.StatusCreated, category, "Category created successfully")
 }
 
-// @Summary Get All Categories endpoint
-// @Description Get the API's get all categories
+// @Summary List shop categories
+// @Description Get paginated list of categories of a shop, sorted by name
 // @Tags category
 // @Accept json
 // @Produce json
 // @Security Bearer
-// @Router /category/list [get]
-func (h *CategoryHandler) GetAll(c *fiber.Ctx) error {
+// @Param id path string true "Shop ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param name query string false "Filter by name"
+// @Success 200
+// @Router /shop/{id}/categories [get]
+func (h *CategoryHandler) ShopCategories(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	categories, err := h.categoryService.FindAll(ctx)
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := h.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
+	}
+
+	filter := bson.M{"shop_id": shop.ID}
+	if name := c.Query("name"); name != "" {
+		filter["name"] = primitive.Regex{Pattern: regexp.QuoteMeta(name), Options: "i"}
+	}
+
+	total, err := h.categoryService.Count(ctx, filter)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to count categories: "+err.Error())
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).
+		SetCollation(&options.Collation{Locale: "en", Strength: 2})
+
+	categories, err := h.categoryService.FindAll(ctx, filter, opts)
 	if err != nil {
 		return utils.SendError(c, http.StatusInternalServerError, "Failed to fetch categories")
 	}
 
-	return utils.SendSuccess(c, http.StatusOK, categories, "Categories fetched successfully")
+	response := utils.CreatePagination(page, pageSize, total, categories)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Update Category endpoint
+// @Description Put the API's update category, a category stays in its shop
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Category ID"
+// @Param request body dto.UpdateCategoryRequest true "Category details"
+// @Router /category/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *fiber.Ctx) error {
+	var req dto.UpdateCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.findManagedCategory(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized to update category")
+	}
+
+	updated, err := h.categoryService.Update(ctx, category, &req)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to update category")
+	}
+
+	middleware.Audit(c, "category.update", "category", category.ID.Hex(), category, updated)
+	return utils.SendSuccess(c, http.StatusOK, updated, "Category updated successfully")
 }
 
 // @Summary Delete Category endpoint
-// @Description Get the API's delete category
+// @Description Get the API's move category to the trash, admins restore or purge it from there
 // @Tags category
 // @Accept json
 // @Produce json
 // @Security Bearer
 // @Param id path string true "Category ID"
+// @Param cascade query bool false "Trash the subcategories too instead of refusing"
 // @Router /category/{id} [delete]
 func (h *CategoryHandler) DeleteCategory(c *fiber.Ctx) error {
-	id := c.Params("id")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.findManagedCategory(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized to delete category")
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+	}
+
+	deleted, err := h.categoryService.Delete(ctx, category, c.QueryBool("cascade"), user.ID)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to delete category")
+	}
 
+	middleware.Audit(c, "category.delete", "category", category.ID.Hex(), category, nil)
+	return utils.SendSuccess(c, http.StatusOK, fiber.Map{"deleted": deleted}, "Category deleted successfully")
+}
+
+// @Summary Shop category tree
+// @Description Get every category of a shop nested below its parent, children in order
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Success 200 {array} dto.CategoryNode
+// @Router /shop/{id}/categories/tree [get]
+func (h *CategoryHandler) ShopCategoryTree(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	shopId, err := primitive.ObjectIDFromHex(id)
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := h.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
+	}
+
+	tree, err := h.categoryService.Tree(ctx, shop.ID)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to fetch categories")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, tree)
+}
+
+// @Summary Move Category endpoint
+// @Description Put the API's move of a category with its subcategories below another parent of the shop
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Category ID"
+// @Param request body dto.MoveCategoryRequest true "New parent and position"
+// @Router /category/{id}/move [put]
+func (h *CategoryHandler) MoveCategory(c *fiber.Ctx) error {
+	var req dto.MoveCategoryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	category, err := h.findManagedCategory(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized to move category")
+	}
+
+	moved, err := h.categoryService.Move(ctx, category, &req)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to move category")
+	}
+
+	middleware.Audit(c, "category.move", "category", category.ID.Hex(), category, moved)
+	return utils.SendSuccess(c, http.StatusOK, moved, "Category moved successfully")
+}
+
+// @Summary Reorder categories
+// @Description Put the API's order of the categories below one parent, or of the roots
+// @Tags category
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param request body dto.ReorderCategoriesRequest true "Every category of the parent in the new order"
+// @Router /shop/{id}/categories/reorder [put]
+func (h *CategoryHandler) ReorderCategories(c *fiber.Ctx) error {
+	var req dto.ReorderCategoriesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.SendError(c, http.StatusBadRequest, "Invalid shop ID format")
 	}
@@ -121,13 +305,43 @@ func (h *CategoryHandler) DeleteCategory(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
 	}
 
-	if shop.CreatedBy != user.ID {
-		return utils.SendError(c, http.StatusUnauthorized, "Unauthorized to delete category")
+	if err := h.policyService.AuthorizeShop(ctx, user, utils.CategoryManage, shop); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized to reorder categories")
 	}
 
-	if err := h.categoryService.Delete(ctx, shop.ID); err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, "Failed to delete category")
+	categories, err := h.categoryService.Reorder(ctx, shop, &req)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to reorder categories")
+	}
+
+	middleware.Audit(c, "category.reorder", "shop", shop.ID.Hex(), nil, &req)
+	return utils.SendSuccess(c, http.StatusOK, categories, "Categories reordered successfully")
+}
+
+// findManagedCategory loads the category from the route and makes sure the current user may manage the categories of its shop
+func (h *CategoryHandler) findManagedCategory(ctx context.Context, c *fiber.Ctx) (*model.Category, error) {
+	categoryId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid category ID format")
+	}
+
+	category, err := h.categoryService.FindByID(ctx, categoryId)
+	if err != nil || category == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find category")
 	}
 
-	return utils.SendSuccess(c, http.StatusOK, nil, "Category deleted successfully")
+	shop, err := h.shopService.FindByID(ctx, category.ShopID)
+	if err != nil || shop == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := h.policyService.AuthorizeShop(ctx, user, utils.CategoryManage, shop); err != nil {
+		return nil, err
+	}
+	return category, nil
 }
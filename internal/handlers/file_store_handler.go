@@ -2,26 +2,46 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/storage"
 	"go-fiber-api/pkg/utils"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
-	"path/filepath"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// uploadTimeout bounds writing uploaded files to storage, which takes far longer
+// than the lookups done under the request timeout
+const uploadTimeout = 10 * time.Minute
+
 type FileStoreHandler struct {
 	fileStoreService *service.FileStoreService
+	fileLinkService  *service.FileLinkService
 	shopService      *service.ShopService
+	policyService    *service.PolicyService
 }
 
-func NewFileStoreHandler(fileStoreService *service.FileStoreService, shopService *service.ShopService) *FileStoreHandler {
+func NewFileStoreHandler(fileStoreService *service.FileStoreService, fileLinkService *service.FileLinkService, shopService *service.ShopService, policyService *service.PolicyService) *FileStoreHandler {
 	return &FileStoreHandler{
 		fileStoreService: fileStoreService,
+		fileLinkService:  fileLinkService,
 		shopService:      shopService,
+		policyService:    policyService,
 	}
 }
 
@@ -33,6 +53,17 @@ func NewFileStoreHandler(fileStoreService *service.FileStoreService, shopService
 // @Security Bearer
 // @Param shop_id path string true "Shop ID"
 // @Param file_id path string true "File Store ID"
+// @Param variant query string false "Image derivative to download, e.g. thumb"
+// @Param download query bool false "Always send as an attachment"
+// @Param Range header string false "Byte ranges, e.g. bytes=0-1023"
+// @Param If-None-Match header string false "Entity tag of a cached copy"
+// @Param If-Modified-Since header string false "Date of a cached copy"
+// @Success 200
+// @Success 206
+// @Success 304
+// @Failure 403
+// @Failure 409
+// @Failure 416
 // @Router /file/shop/{shop_id}/download/{file_id} [get]
 func (f *FileStoreHandler) Download(c *fiber.Ctx) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -50,6 +81,15 @@ func (f *FileStoreHandler) Download(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusNotFound, "Failed to find file store")
 	}
 
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := f.policyService.AuthorizeShop(ctx, user, utils.FileDownload, shop); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized")
+	}
+
 	id := c.Params("file_id")
 
 	objID, err = primitive.ObjectIDFromHex(id)
@@ -57,11 +97,712 @@ func (f *FileStoreHandler) Download(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
 	}
 
-	fileStore, err := f.fileStoreService.FindOne(ctx, bson.M{"_id": objID})
+	fileStore, err := f.fileStoreService.FindOne(ctx, bson.M{"_id": objID, "shop_id": shop.ID})
+	if err != nil || fileStore == nil {
+		return utils.SendError(c, http.StatusNotFound, "Failed to find file store")
+	}
+
+	if err := service.CheckScanned(fileStore); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "File is not available")
+	}
+
+	fileStore, err = f.findVariant(ctx, fileStore, c.Query("variant"))
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Variant is not available")
+	}
+
+	return f.send(c, fileStore, nil)
+}
+
+// @Summary Create signed file link
+// @Description Post the API's signed link to download a file without a bearer token
+// @Tags file-store
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param file_id path string true "File Store ID"
+// @Param request body dto.FileLinkRequest true "Link options"
+// @Router /shop/{id}/files/{file_id}/link [post]
+func (f *FileStoreHandler) CreateLink(c *fiber.Ctx) error {
+	var req dto.FileLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileDownload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	fileStore, err := f.findFile(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find file store")
+	}
+
+	if err := service.CheckScanned(fileStore); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "File is not available")
+	}
+
+	if _, err := f.findVariant(ctx, fileStore, req.Variant); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Variant is not available")
+	}
+
+	query, expiresAt, err := f.fileLinkService.Issue(fileStore, req.Variant, time.Duration(req.ExpiresIn)*time.Second, req.SingleUse)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to sign link")
+	}
+	middleware.Audit(c, "file.link", "file", fileStore.ID.Hex(), nil, fiber.Map{
+		"variant":    req.Variant,
+		"expires_at": expiresAt,
+		"single_use": req.SingleUse,
+	})
+
+	return utils.SendSuccess(c, http.StatusCreated, dto.FileLinkResponse{
+		URL:       c.BaseURL() + "/api/v1/file/public/" + fileStore.ID.Hex() + "?" + query,
+		ExpiresAt: expiresAt,
+	})
+}
+
+// @Summary Download file with a signed link
+// @Description Get the API's download of a file authorized by the link signature instead of a bearer token
+// @Tags file-store
+// @Produce octet-stream
+// @Param file_id path string true "File Store ID"
+// @Param exp query int true "Expiry as unix time"
+// @Param kid query string true "Signing key ID"
+// @Param sig query string true "Signature"
+// @Param variant query string false "Image derivative"
+// @Param once query string false "Single-use marker"
+// @Param nonce query string false "Single-use nonce"
+// @Router /file/public/{file_id} [get]
+func (f *FileStoreHandler) PublicDownload(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fileId, err := primitive.ObjectIDFromHex(c.Params("file_id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid file ID format")
+	}
+
+	values, err := url.ParseQuery(string(c.Request().URI().QueryString()))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid query string")
+	}
+
+	claims, err := f.fileLinkService.Verify(ctx, fileId.Hex(), values)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to check link")
+	}
+
+	fileStore, err := f.fileStoreService.FindOne(ctx, bson.M{"_id": fileId})
 	if err != nil || fileStore == nil {
 		return utils.SendError(c, http.StatusNotFound, "Failed to find file store")
 	}
 
-	fullPath := filepath.Join(fileStore.BasePath, fileStore.Name)
-	return c.Download(fullPath)
+	// Links stop working while their shop is in the trash
+	if shop, err := f.shopService.FindByID(ctx, fileStore.ShopID); err != nil || shop == nil {
+		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
+	}
+
+	if err := service.CheckScanned(fileStore); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "File is not available")
+	}
+
+	fileStore, err = f.findVariant(ctx, fileStore, claims.Variant)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Variant is not available")
+	}
+
+	return f.send(c, fileStore, func(ctx context.Context) error {
+		return f.fileLinkService.Consume(ctx, fileId.Hex(), claims)
+	})
+}
+
+// findVariant returns the derivative of fileStore called variant, or fileStore itself when variant is empty
+func (f *FileStoreHandler) findVariant(ctx context.Context, fileStore *model.FileStore, variant string) (*model.FileStore, error) {
+	if variant == "" {
+		return fileStore, nil
+	}
+	derivative, err := f.fileStoreService.FindOne(ctx, bson.M{"parent_id": fileStore.ID, "variant": variant})
+	if err != nil || derivative == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Variant is not available")
+	}
+	return derivative, nil
+}
+
+// send streams the content of fileStore, honouring conditional and range
+// requests. beforeBody, when set, runs before any content, whole or ranged, is
+// sent in answer to a GET and stops the response when it fails.
+func (f *FileStoreHandler) send(c *fiber.Ctx, fileStore *model.FileStore, beforeBody func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// Files stored before hashing get their hash on first download
+	if err := f.fileStoreService.EnsureHash(ctx, fileStore); err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return utils.SendError(c, http.StatusNotFound, "File content is missing")
+		}
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	etag := `"` + fileStore.Hash + `"`
+	lastModified := fileStore.UpdatedAt.UTC().Truncate(time.Second)
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderLastModified, lastModified.Format(http.TimeFormat))
+	c.Set(fiber.HeaderAcceptRanges, "bytes")
+	c.Set(fiber.HeaderCacheControl, "private, no-cache")
+	c.Set(fiber.HeaderXContentTypeOptions, "nosniff")
+	c.Set(fiber.HeaderContentDisposition, contentDisposition(fileStore, c.QueryBool("download")))
+
+	if notModified(c, etag, lastModified) {
+		return c.SendStatus(http.StatusNotModified)
+	}
+
+	contentType := fileStore.ContentType
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+	size := fileStore.Size
+
+	ranges, err := utils.ParseRange(c.Get(fiber.HeaderRange), size)
+	if ifRange := c.Get(fiber.HeaderIfRange); ifRange != "" && !ifRangeMatches(ifRange, etag, lastModified) {
+		ranges, err = nil, nil
+	}
+	if err != nil {
+		c.Set(fiber.HeaderContentRange, "bytes */"+strconv.FormatInt(size, 10))
+		return utils.SendError(c, http.StatusRequestedRangeNotSatisfiable, "Range not satisfiable")
+	}
+
+	if beforeBody != nil && c.Method() == fiber.MethodGet {
+		if err := beforeBody(ctx); err != nil {
+			return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to check link")
+		}
+	}
+
+	// The body is streamed after the handler returns, so reading must outlive ctx
+	switch len(ranges) {
+	case 0:
+		reader, _, err := f.fileStoreService.Open(context.Background(), fileStore)
+		if err != nil {
+			return utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		c.Set(fiber.HeaderContentType, contentType)
+		return c.SendStream(reader, int(size))
+	case 1:
+		reader, err := f.fileStoreService.OpenRange(context.Background(), fileStore, ranges[0].Start, ranges[0].Length)
+		if err != nil {
+			return utils.SendError(c, http.StatusInternalServerError, err.Error())
+		}
+		c.Status(http.StatusPartialContent)
+		c.Set(fiber.HeaderContentType, contentType)
+		c.Set(fiber.HeaderContentRange, ranges[0].ContentRange(size))
+		return c.SendStream(reader, int(ranges[0].Length))
+	}
+
+	pr, pw := io.Pipe()
+	parts := multipart.NewWriter(pw)
+	go func() {
+		for _, r := range ranges {
+			part, err := parts.CreatePart(textproto.MIMEHeader{
+				fiber.HeaderContentType:  {contentType},
+				fiber.HeaderContentRange: {r.ContentRange(size)},
+			})
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			reader, err := f.fileStoreService.OpenRange(context.Background(), fileStore, r.Start, r.Length)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			_, err = io.Copy(part, reader)
+			reader.Close()
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(parts.Close())
+	}()
+
+	c.Status(http.StatusPartialContent)
+	c.Set(fiber.HeaderContentType, "multipart/byteranges; boundary="+parts.Boundary())
+	return c.SendStream(pr)
+}
+
+// notModified evaluates If-None-Match, or If-Modified-Since when no entity tag was sent
+func notModified(c *fiber.Ctx, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := c.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" {
+		return utils.ETagMatches(ifNoneMatch, etag)
+	}
+	if since, err := http.ParseTime(c.Get(fiber.HeaderIfModifiedSince)); err == nil {
+		return !lastModified.After(since)
+	}
+	return false
+}
+
+// ifRangeMatches reports whether the representation is still the one If-Range refers to
+func ifRangeMatches(ifRange, etag string, lastModified time.Time) bool {
+	if strings.HasPrefix(ifRange, `"`) {
+		return ifRange == etag
+	}
+	date, err := http.ParseTime(ifRange)
+	return err == nil && date.Equal(lastModified)
+}
+
+// contentDisposition shows media browsers can render safely inline and downloads everything else
+func contentDisposition(fileStore *model.FileStore, forceDownload bool) string {
+	filename := fileStore.Name
+	if fileStore.OriginalName != "" {
+		filename = fileStore.OriginalName
+	}
+
+	disposition := "attachment"
+	contentType := fileStore.ContentType
+	inline := strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/") ||
+		strings.HasPrefix(contentType, "audio/") || contentType == "application/pdf" || contentType == "text/plain"
+	// SVG can carry scripts, it is never rendered inline
+	if inline && contentType != "image/svg+xml" && !forceDownload {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// @Summary List shop files
+// @Description Get the files of a shop in their display order
+// @Tags file-store
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Success 200
+// @Router /shop/{id}/files [get]
+func (f *FileStoreHandler) FileList(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileDownload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	files, err := f.fileStoreService.FindShopFiles(ctx, shop.ID)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+	if files == nil {
+		files = []model.FileStore{}
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, files)
+}
+
+// @Summary Download shop files as ZIP
+// @Description Get a ZIP archive of the shop files with a manifest.json, streamed as it is built
+// @Tags file-store
+// @Produce application/zip
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param ids query string false "Comma separated file IDs to include"
+// @Param ext query string false "Comma separated extensions to include, e.g. png,pdf"
+// @Success 200
+// @Router /shop/{id}/files/archive [get]
+func (f *FileStoreHandler) Archive(c *fiber.Ctx) error {
+	var ids []primitive.ObjectID
+	for _, id := range splitQuery(c.Query("ids")) {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return utils.SendError(c, http.StatusBadRequest, "Invalid file ID format")
+		}
+		ids = append(ids, objID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileDownload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	files, err := f.fileStoreService.FindArchiveFiles(ctx, shop.ID, ids, splitQuery(c.Query("ext")))
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+	if len(files) == 0 {
+		return utils.SendError(c, http.StatusNotFound, "No files to archive")
+	}
+
+	// The archive is written while the response streams, after the handler returns
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(f.fileStoreService.WriteArchive(context.Background(), pw, shop, files))
+	}()
+
+	c.Set(fiber.HeaderContentType, "application/zip")
+	c.Set(fiber.HeaderContentDisposition, mime.FormatMediaType("attachment", map[string]string{"filename": shop.Name + ".zip"}))
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	return c.SendStream(pr)
+}
+
+// fileIDs lists the IDs of files in their order
+func fileIDs(files []model.FileStore) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, len(files))
+	for i, file := range files {
+		ids[i] = file.ID
+	}
+	return ids
+}
+
+// storagePlan is the part of shop the storage plan endpoint changes
+func storagePlan(shop *model.Shop) fiber.Map {
+	return fiber.Map{"plan": shop.Plan, "storage_quota": shop.StorageQuota}
+}
+
+// splitQuery splits a comma separated query value, dropping empty items
+func splitQuery(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// @Summary Add shop files
+// @Description Post the API's upload of new files, appended after the existing ones
+// @Tags file-store
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param files formData []file true "Multiple files to upload"
+// @Router /shop/{id}/files [post]
+func (f *FileStoreHandler) AddFiles(c *fiber.Ctx) error {
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["files"]) == 0 {
+		return utils.SendError(c, http.StatusBadRequest, "At least one file is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, user, err := f.findAuthorizedShop(ctx, c, utils.FileUpload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	var fileHeaders []multipart.FileHeader
+	for _, file := range form.File["files"] {
+		fileHeaders = append(fileHeaders, *file)
+	}
+	reqUpload := dto.FileStoreRequest{Files: fileHeaders, ShopID: shop.ID, UploadedBy: user.ID}
+	uploadCtx, cancelUpload := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancelUpload()
+	files, err := f.fileStoreService.Uploads(uploadCtx, &reqUpload, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to upload files")
+	}
+	for _, file := range files {
+		middleware.Audit(c, "file.upload", "file", file.ID.Hex(), nil, file)
+	}
+
+	return utils.SendSuccess(c, http.StatusCreated, files)
+}
+
+// @Summary Replace shop file
+// @Description Put the API's replacement of a file content, keeping its ID and position
+// @Tags file-store
+// @Accept multipart/form-data
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param file_id path string true "File Store ID"
+// @Param file formData file true "New file content"
+// @Router /shop/{id}/files/{file_id} [put]
+func (f *FileStoreHandler) ReplaceFile(c *fiber.Ctx) error {
+	upload, err := c.FormFile("file")
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "File is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, user, err := f.findAuthorizedShop(ctx, c, utils.FileUpload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	fileStore, err := f.findFile(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find file store")
+	}
+
+	uploadCtx, cancelUpload := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancelUpload()
+	replaced, err := f.fileStoreService.Replace(uploadCtx, shop, fileStore, upload, user.ID)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to replace file")
+	}
+	middleware.Audit(c, "file.replace", "file", fileStore.ID.Hex(), fileStore, replaced)
+	fileStore = replaced
+
+	return utils.SendSuccess(c, http.StatusOK, fileStore)
+}
+
+// @Summary Delete shop file
+// @Description Delete a single file of a shop
+// @Tags file-store
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param file_id path string true "File Store ID"
+// @Router /shop/{id}/files/{file_id} [delete]
+func (f *FileStoreHandler) DeleteFile(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileUpload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	fileStore, err := f.findFile(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find file store")
+	}
+
+	if err := f.fileStoreService.Delete(ctx, fileStore.ID); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+	middleware.Audit(c, "file.delete", "file", fileStore.ID.Hex(), fileStore, nil)
+
+	return utils.SendSuccess(c, http.StatusOK, "File deleted successfully")
+}
+
+// @Summary Reorder shop files
+// @Description Put the API's order of the shop files, file_ids must list every file of the shop
+// @Tags file-store
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param request body dto.ReorderFilesRequest true "File IDs in their new order"
+// @Router /shop/{id}/files/order [put]
+func (f *FileStoreHandler) ReorderFiles(c *fiber.Ctx) error {
+	var req dto.ReorderFilesRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileUpload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	previous, err := f.fileStoreService.FindShopFiles(ctx, shop.ID)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	files, err := f.fileStoreService.Reorder(ctx, shop, req.FileIDs)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to reorder files")
+	}
+	middleware.Audit(c, "file.reorder", "shop", shop.ID.Hex(), fiber.Map{"file_ids": fileIDs(previous)}, fiber.Map{"file_ids": fileIDs(files)})
+
+	return utils.SendSuccess(c, http.StatusOK, files)
+}
+
+// @Summary Shop storage usage
+// @Description Get the storage quota of a shop with its file count and bytes by content type
+// @Tags file-store
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Success 200 {object} dto.StorageUsageResponse
+// @Router /shop/{id}/usage [get]
+func (f *FileStoreHandler) StorageUsage(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := f.findAuthorizedShop(ctx, c, utils.FileDownload)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	usage, err := f.fileStoreService.Usage(ctx, shop)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, usage)
+}
+
+// @Summary Update shop storage plan
+// @Description Put the API's storage plan of a shop, quota overrides the plan quota in bytes
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param request body dto.UpdateStoragePlanRequest true "Storage plan"
+// @Router /admin/shop/{id}/storage [put]
+func (f *FileStoreHandler) UpdateStoragePlan(c *fiber.Ctx) error {
+	var req dto.UpdateStoragePlanRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	previous, err := f.shopService.FindByID(ctx, shopId)
+	if err != nil || previous == nil {
+		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
+	}
+
+	shop, err := f.fileStoreService.UpdateStoragePlan(ctx, shopId, &req)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+	middleware.Audit(c, "shop.update_storage_plan", "shop", shop.ID.Hex(), storagePlan(previous), storagePlan(shop))
+
+	return utils.SendSuccess(c, http.StatusOK, shopResponse(shop))
+}
+
+// @Summary Deduplication report
+// @Description Get how much storage is saved by sharing identical file content between files
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Success 200 {object} model.BlobStats
+// @Router /admin/storage/dedup [get]
+func (f *FileStoreHandler) DedupReport(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	stats, err := f.fileStoreService.DedupStats(ctx)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, stats)
+}
+
+// @Summary List quarantined files
+// @Description Get paginated files held back by the malware scanner, newest first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param status query string false "Scan status (infected, pending or error)" default(infected)
+// @Param shop_id query string false "Filter by shop ID"
+// @Success 200
+// @Router /admin/files/quarantine [get]
+func (f *FileStoreHandler) QuarantineList(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
+	status := c.Query("status", model.ScanInfected)
+	if status != model.ScanInfected && status != model.ScanPending && status != model.ScanError {
+		return utils.SendError(c, http.StatusBadRequest, "Status must be infected, pending or error")
+	}
+
+	filter := bson.M{"scan_status": status}
+	if shopId := c.Query("shop_id"); shopId != "" {
+		objID, err := primitive.ObjectIDFromHex(shopId)
+		if err != nil {
+			return utils.SendError(c, http.StatusBadRequest, "Invalid shop ID format")
+		}
+		filter["shop_id"] = objID
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	total, err := f.fileStoreService.Count(ctx, filter)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to count files: "+err.Error())
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "updated_at", Value: -1}})
+
+	files, err := f.fileStoreService.FindPage(ctx, filter, opts)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, files)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// findAuthorizedShop loads the shop from the route and makes sure the current user may perform action on its files
+func (f *FileStoreHandler) findAuthorizedShop(ctx context.Context, c *fiber.Ctx, action utils.Action) (*model.Shop, *model.User, error) {
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := f.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return nil, nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := f.policyService.AuthorizeShop(ctx, user, action, shop); err != nil {
+		return nil, nil, err
+	}
+	return shop, user, nil
+}
+
+func (f *FileStoreHandler) findFile(ctx context.Context, c *fiber.Ctx, shop *model.Shop) (*model.FileStore, error) {
+	fileId, err := primitive.ObjectIDFromHex(c.Params("file_id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid file ID format")
+	}
+
+	fileStore, err := f.fileStoreService.FindOne(ctx, bson.M{"_id": fileId, "shop_id": shop.ID, "parent_id": bson.M{"$exists": false}})
+	if err != nil || fileStore == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find file store")
+	}
+	return fileStore, nil
 }
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProductHandler struct {
+	productService *service.ProductService
+	shopService    *service.ShopService
+	policyService  *service.PolicyService
+}
+
+func NewProductHandler(productService *service.ProductService, shopService *service.ShopService, policyService *service.PolicyService) *ProductHandler {
+	return &ProductHandler{
+		productService: productService,
+		shopService:    shopService,
+		policyService:  policyService,
+	}
+}
+
+// @Summary List products
+// @Description Get paginated list of products of a shop
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param name query string false "Filter by name"
+// @Param category_id query string false "Filter by category ID"
+// @Success 200
+// @Router /shop/{id}/products [get]
+func (p *ProductHandler) ProductList(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := p.findShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	filter := bson.M{"shop_id": shop.ID}
+	if name := c.Query("name"); name != "" {
+		filter["name"] = primitive.Regex{Pattern: name, Options: "i"}
+	}
+	if categoryId := c.Query("category_id"); categoryId != "" {
+		objID, err := primitive.ObjectIDFromHex(categoryId)
+		if err != nil {
+			return utils.SendError(c, http.StatusBadRequest, "Invalid category ID format")
+		}
+		filter["category_id"] = objID
+	}
+
+	total, err := p.productService.Count(ctx, filter)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to count products: "+err.Error())
+	}
+
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	products, err := p.productService.FindAll(ctx, filter, opts)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, products)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Create Product endpoint
+// @Description Post the API's create product
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param request body dto.ProductRequest true "Product details"
+// @Router /shop/{id}/products [post]
+func (p *ProductHandler) CreateProduct(c *fiber.Ctx) error {
+	var req dto.ProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := p.findOwnedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	product, err := p.productService.Create(ctx, &req, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to create product")
+	}
+
+	return utils.SendSuccess(c, http.StatusCreated, product, "Product created successfully")
+}
+
+// @Summary Get Product endpoint
+// @Description Get the API's get product
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param product_id path string true "Product ID"
+// @Router /shop/{id}/products/{product_id} [get]
+func (p *ProductHandler) GetProduct(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := p.findShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	product, err := p.findProduct(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find product")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, product)
+}
+
+// @Summary Update Product endpoint
+// @Description Put the API's update product
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param product_id path string true "Product ID"
+// @Param request body dto.ProductRequest true "Product details"
+// @Router /shop/{id}/products/{product_id} [put]
+func (p *ProductHandler) UpdateProduct(c *fiber.Ctx) error {
+	var req dto.ProductRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := p.findOwnedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	product, err := p.findProduct(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find product")
+	}
+
+	product, err = p.productService.Update(ctx, product.ID, &req, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to update product")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, product, "Product updated successfully")
+}
+
+// @Summary Delete Product endpoint
+// @Description Delete the API's delete product
+// @Tags product
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param product_id path string true "Product ID"
+// @Router /shop/{id}/products/{product_id} [delete]
+func (p *ProductHandler) DeleteProduct(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := p.findOwnedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	product, err := p.findProduct(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find product")
+	}
+
+	if err := p.productService.Delete(ctx, product.ID); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to delete product")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "Product deleted successfully")
+}
+
+func (p *ProductHandler) findShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, error) {
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := p.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+	return shop, nil
+}
+
+// findOwnedShop loads the shop from the route and makes sure the current user may manage its products
+func (p *ProductHandler) findOwnedShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, error) {
+	shop, err := p.findShop(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := p.policyService.AuthorizeShop(ctx, user, utils.ProductManage, shop); err != nil {
+		return nil, err
+	}
+	return shop, nil
+}
+
+func (p *ProductHandler) findProduct(ctx context.Context, c *fiber.Ctx, shop *model.Shop) (*model.Product, error) {
+	productId, err := primitive.ObjectIDFromHex(c.Params("product_id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid product ID format")
+	}
+
+	product, err := p.productService.FindByID(ctx, shop, productId)
+	if err != nil || product == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find product")
+	}
+	return product, nil
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleHandler struct {
+	policyService *service.PolicyService
+}
+
+func NewRoleHandler(policyService *service.PolicyService) *RoleHandler {
+	return &RoleHandler{
+		policyService: policyService,
+	}
+}
+
+// @Summary List roles
+// @Description Get the API's roles with their permissions
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /admin/roles [get]
+func (r *RoleHandler) RoleList(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	roles, err := r.policyService.ListRoles(ctx)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, fiber.Map{
+		"roles":       roles,
+		"permissions": utils.AllPermissions,
+	})
+}
+
+// @Summary Save role endpoint
+// @Description Put the API's create or replace of a role permissions
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param name path string true "Role name"
+// @Param request body dto.RoleRequest true "Role permissions"
+// @Router /admin/roles/{name} [put]
+func (r *RoleHandler) SaveRole(c *fiber.Ctx) error {
+	var req dto.RoleRequest
+	name := c.Params("name")
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	role, err := r.policyService.SaveRole(ctx, name, req.Permissions)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to save role")
+	}
+	middleware.Audit(c, "role.save", "role", name, nil, role)
+
+	return utils.SendSuccess(c, http.StatusOK, role, "Role saved successfully")
+}
@@ -9,7 +9,6 @@ import (
 	"go-fiber-api/pkg/utils"
 	"mime/multipart"
 	"net/http"
-	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -19,14 +18,20 @@ import (
 )
 
 type ShopHandler struct {
-	shopService      *service.ShopService
-	fileStoreService *service.FileStoreService
+	shopService       *service.ShopService
+	fileStoreService  *service.FileStoreService
+	shopMemberService *service.ShopMemberService
+	budgetService     *service.BudgetService
+	policyService     *service.PolicyService
 }
 
-func NewShopHandler(shopService *service.ShopService, fileStoreService *service.FileStoreService) *ShopHandler {
+func NewShopHandler(shopService *service.ShopService, fileStoreService *service.FileStoreService, shopMemberService *service.ShopMemberService, budgetService *service.BudgetService, policyService *service.PolicyService) *ShopHandler {
 	return &ShopHandler{
-		shopService:      shopService,
-		fileStoreService: fileStoreService,
+		shopService:       shopService,
+		fileStoreService:  fileStoreService,
+		shopMemberService: shopMemberService,
+		budgetService:     budgetService,
+		policyService:     policyService,
 	}
 }
 
@@ -62,7 +67,12 @@ func (s *ShopHandler) ShopList(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
 
-	response := utils.CreatePagination(page, pageSize, total, shops)
+	responses := make([]*dto.ShopResponse, len(shops))
+	for i := range shops {
+		responses[i] = shopResponse(&shops[i])
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, responses)
 	return utils.SendSuccess(c, http.StatusOK, response)
 }
 
@@ -73,14 +83,18 @@ func (s *ShopHandler) ShopList(c *fiber.Ctx) error {
 // @Produce json
 // @Security Bearer
 // @Param name formData string true "Shop name" minlength(3) maxlength(30)
-// @Param budget formData number true "Shop budget"
+// @Param budget formData string true "Shop budget as a decimal, e.g. 100.50"
 // @Param files formData []file false "Multiple files to upload"
 // @Router /shop [post]
 func (s *ShopHandler) CreateShop(c *fiber.Ctx) error {
 	var req dto.ShopRequest
 
 	req.Name = c.FormValue("name")
-	req.Budget, _ = strconv.ParseFloat(c.FormValue("budget"), 64)
+	budget, err := utils.ParseMoney(c.FormValue("budget"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid budget")
+	}
+	req.Budget = budget
 
 	if err := utils.ValidateStruct(&req); err != nil {
 		return utils.SendValidationError(c, err)
@@ -94,7 +108,19 @@ func (s *ShopHandler) CreateShop(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
 	}
 
-	shop, err := s.shopService.Create(ctx, &req, user)
+	// Without its owner membership nobody could manage the shop, so it is saved with it or not at all
+	var shop *model.Shop
+	err = s.shopService.WithTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if shop, err = s.shopService.Create(ctx, &req, user); err != nil {
+			return err
+		}
+		if _, err := s.shopMemberService.AddOwner(ctx, shop, user); err != nil {
+			return err
+		}
+		_, err = s.budgetService.RecordOpening(ctx, shop, user)
+		return err
+	})
 	if err != nil {
 		return utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
@@ -106,19 +132,23 @@ func (s *ShopHandler) CreateShop(c *fiber.Ctx) error {
 			for _, file := range files {
 				fileHeaders = append(fileHeaders, *file)
 			}
-			reqUpload := dto.FileStoreRequest{Files: fileHeaders, ShopID: shop.ID}
-			resUploads, err := s.fileStoreService.Uploads(ctx, &reqUpload, shop)
+			reqUpload := dto.FileStoreRequest{Files: fileHeaders, ShopID: shop.ID, UploadedBy: user.ID}
+			uploadCtx, cancelUpload := context.WithTimeout(context.Background(), uploadTimeout)
+			defer cancelUpload()
+			resUploads, err := s.fileStoreService.Uploads(uploadCtx, &reqUpload, shop)
 			if err != nil {
-				return utils.SendError(c, http.StatusInternalServerError, err.Error())
+				return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to upload files")
 			}
 			filesResponse = resUploads
 		}
 	}
 
+	middleware.Audit(c, "shop.create", "shop", shop.ID.Hex(), nil, shop)
+
 	res := &dto.UpdateShopResponse{
 		ID:        shop.ID,
 		Name:      shop.Name,
-		Budget:    shop.Budget,
+		Budget:    utils.FormatMoney(shop.Budget),
 		Files:     filesResponse,
 		CreatedAt: shop.CreatedAt,
 		UpdatedAt: shop.UpdatedAt,
@@ -146,29 +176,30 @@ func (s *ShopHandler) GetShop(c *fiber.Ctx) error {
 	}
 
 	shop, err := s.shopService.FindByID(ctx, objID)
-	if err != nil {
+	if err != nil || shop == nil {
 		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
 	}
 
-	return utils.SendSuccess(c, http.StatusOK, shop)
+	return utils.SendSuccess(c, http.StatusOK, shopResponse(shop))
 }
 
 // @Summary Update Shop endpoint
-// @Description Get the API's update shop
+// @Description Get the API's update shop, the budget changes through the budget credit and debit endpoints
 // @Tags shop
 // @Accept multipart/form-data
 // @Produce json
 // @Security Bearer
 // @Param id path string true "Shop ID"
 // @Param name formData string true "Shop name" minlength(3) maxlength(30)
-// @Param budget formData number true "Shop budget"
-// @Param files formData []file false "Multiple files to upload"
+// @Failure 400
 // @Router /shop/{id} [put]
 func (s *ShopHandler) UpdateShop(c *fiber.Ctx) error {
-	var req dto.ShopRequest
+	var req dto.UpdateShopRequest
 
 	req.Name = c.FormValue("name")
-	req.Budget, _ = strconv.ParseFloat(c.FormValue("budget"), 64)
+	if c.FormValue("budget") != "" {
+		return utils.SendError(c, http.StatusBadRequest, "The budget is changed through the budget credit and debit endpoints")
+	}
 
 	if err := utils.ValidateStruct(&req); err != nil {
 		return utils.SendValidationError(c, err)
@@ -193,47 +224,21 @@ func (s *ShopHandler) UpdateShop(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusNotFound, "Failed to find shop")
 	}
 
-	if shop.CreatedBy != user.ID {
-		return utils.SendError(c, http.StatusUnauthorized, "Unauthorized")
-	}
-
-	shop, err = s.shopService.Update(ctx, shopId, &req)
-	if err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	if err := s.policyService.AuthorizeShop(ctx, user, utils.ShopUpdate, shop); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized")
 	}
 
-	filesOnShop, err := s.fileStoreService.FindAll(ctx, bson.M{"shop_id": shop.ID})
+	updated, err := s.shopService.Update(ctx, shopId, &req)
 	if err != nil {
 		return utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
-	for _, file := range filesOnShop {
-		err = s.fileStoreService.Delete(ctx, file.ID)
-		if err != nil {
-			return utils.SendError(c, http.StatusInternalServerError, err.Error())
-		}
-	}
-
-	var filesResponse []*model.FileStore
-	if form, err := c.MultipartForm(); err == nil {
-		if files := form.File["files"]; len(files) > 0 {
-			var fileHeaders []multipart.FileHeader
-			for _, file := range files {
-				fileHeaders = append(fileHeaders, *file)
-			}
-			reqUpload := dto.FileStoreRequest{Files: fileHeaders, ShopID: shop.ID}
-			resUploads, err := s.fileStoreService.Uploads(ctx, &reqUpload, shop)
-			if err != nil {
-				return utils.SendError(c, http.StatusInternalServerError, err.Error())
-			}
-			filesResponse = resUploads
-		}
-	}
+	middleware.Audit(c, "shop.update", "shop", shop.ID.Hex(), shop, updated)
+	shop = updated
 
 	res := &dto.UpdateShopResponse{
 		ID:        shop.ID,
 		Name:      shop.Name,
-		Budget:    shop.Budget,
-		Files:     filesResponse,
+		Budget:    utils.FormatMoney(shop.Budget),
 		CreatedAt: shop.CreatedAt,
 		UpdatedAt: shop.UpdatedAt,
 	}
@@ -241,7 +246,7 @@ func (s *ShopHandler) UpdateShop(c *fiber.Ctx) error {
 }
 
 // @Summary Delete Shop endpoint
-// @Description Get the API's delete shop
+// @Description Get the API's move shop to the trash, its categories, products and files stay until an admin purges it
 // @Tags shop
 // @Accept json
 // @Produce json
@@ -269,25 +274,19 @@ func (s *ShopHandler) DeleteShop(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
 	}
 
-	if shop.CreatedBy != user.ID {
-		return utils.SendError(c, http.StatusUnauthorized, "Unauthorized")
-	}
-
-	filesOnShop, err := s.fileStoreService.FindAll(ctx, bson.M{"shop_id": shop.ID})
-	if err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, err.Error())
-	}
-	for _, file := range filesOnShop {
-		err = s.fileStoreService.Delete(ctx, file.ID)
-		if err != nil {
-			return utils.SendError(c, http.StatusInternalServerError, err.Error())
-		}
+	if err := s.policyService.AuthorizeShop(ctx, user, utils.ShopDelete, shop); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusForbidden, "Unauthorized")
 	}
 
-	err = s.shopService.Delete(ctx, shopId)
-	if err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, "test")
+	if err := s.shopService.Delete(ctx, shop.ID, user.ID); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to delete shop")
 	}
+	middleware.Audit(c, "shop.delete", "shop", shop.ID.Hex(), shop, nil)
 
 	return utils.SendSuccess(c, http.StatusOK, nil, "Shop deleted successfully")
 }
+
+// shopResponse formats the budget of shop the way it is given in requests
+func shopResponse(shop *model.Shop) *dto.ShopResponse {
+	return &dto.ShopResponse{Shop: shop, Budget: utils.FormatMoney(shop.Budget)}
+}
@@ -0,0 +1,277 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShopMemberHandler struct {
+	shopMemberService *service.ShopMemberService
+	shopService       *service.ShopService
+	policyService     *service.PolicyService
+}
+
+func NewShopMemberHandler(shopMemberService *service.ShopMemberService, shopService *service.ShopService, policyService *service.PolicyService) *ShopMemberHandler {
+	return &ShopMemberHandler{
+		shopMemberService: shopMemberService,
+		shopService:       shopService,
+		policyService:     policyService,
+	}
+}
+
+// @Summary List shop members
+// @Description Get the API's members and invitations of a shop
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Router /shop/{id}/members [get]
+func (h *ShopMemberHandler) MemberList(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, err := h.findShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	members, err := h.shopMemberService.FindAll(ctx, shop)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, members)
+}
+
+// @Summary Invite shop member endpoint
+// @Description Post the API's invite of a user to a shop by email
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param request body dto.InviteMemberRequest true "Invitation details"
+// @Router /shop/{id}/members [post]
+func (h *ShopMemberHandler) InviteMember(c *fiber.Ctx) error {
+	var req dto.InviteMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, user, err := h.findManagedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	member, err := h.shopMemberService.Invite(ctx, shop, user, req.Email, req.Role)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to invite member")
+	}
+
+	return utils.SendSuccess(c, http.StatusCreated, member, "Invitation sent successfully")
+}
+
+// @Summary Update shop member endpoint
+// @Description Put the API's change of a member role
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param member_id path string true "Member ID"
+// @Param request body dto.UpdateMemberRequest true "Member role"
+// @Router /shop/{id}/members/{member_id} [put]
+func (h *ShopMemberHandler) UpdateMember(c *fiber.Ctx) error {
+	var req dto.UpdateMemberRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := h.findManagedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	member, err := h.findMember(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find member")
+	}
+
+	member, err = h.shopMemberService.UpdateRole(ctx, member, req.Role)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to update member")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, member, "Member updated successfully")
+}
+
+// @Summary Remove shop member endpoint
+// @Description Delete the API's removal of a member or invitation
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param member_id path string true "Member ID"
+// @Router /shop/{id}/members/{member_id} [delete]
+func (h *ShopMemberHandler) RemoveMember(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, _, err := h.findManagedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	member, err := h.findMember(ctx, c, shop)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find member")
+	}
+
+	if err := h.shopMemberService.Remove(ctx, member); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to remove member")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "Member removed successfully")
+}
+
+// @Summary List invitations
+// @Description Get the API's pending shop invitations of the current user, who needs a verified email address while email verification is on
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Failure 403
+// @Router /user/invitations [get]
+func (h *ShopMemberHandler) InvitationList(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+	}
+
+	invitations, err := h.shopMemberService.Invitations(ctx, user)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to list invitations")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, invitations)
+}
+
+// @Summary Accept invitation endpoint
+// @Description Post the API's accept of a shop invitation
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param member_id path string true "Invitation ID"
+// @Failure 403
+// @Router /user/invitations/{member_id}/accept [post]
+func (h *ShopMemberHandler) AcceptInvitation(c *fiber.Ctx) error {
+	return h.respond(c, true)
+}
+
+// @Summary Decline invitation endpoint
+// @Description Post the API's decline of a shop invitation
+// @Tags shop-member
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param member_id path string true "Invitation ID"
+// @Failure 403
+// @Router /user/invitations/{member_id}/decline [post]
+func (h *ShopMemberHandler) DeclineInvitation(c *fiber.Ctx) error {
+	return h.respond(c, false)
+}
+
+func (h *ShopMemberHandler) respond(c *fiber.Ctx, accept bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+	}
+
+	memberId, err := primitive.ObjectIDFromHex(c.Params("member_id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid invitation ID format")
+	}
+
+	member, err := h.shopMemberService.Respond(ctx, user, memberId, accept)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to respond to invitation")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, member)
+}
+
+func (h *ShopMemberHandler) findShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, error) {
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := h.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+	return shop, nil
+}
+
+// findManagedShop loads the shop from the route and makes sure the current user may manage its members
+func (h *ShopMemberHandler) findManagedShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, *model.User, error) {
+	shop, err := h.findShop(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := h.policyService.AuthorizeShop(ctx, user, utils.MemberManage, shop); err != nil {
+		return nil, nil, err
+	}
+	return shop, user, nil
+}
+
+func (h *ShopMemberHandler) findMember(ctx context.Context, c *fiber.Ctx, shop *model.Shop) (*model.ShopMember, error) {
+	memberId, err := primitive.ObjectIDFromHex(c.Params("member_id"))
+	if err != nil {
+		return nil, fiber.NewError(http.StatusBadRequest, "Invalid member ID format")
+	}
+
+	member, err := h.shopMemberService.FindByID(ctx, shop, memberId)
+	if err != nil || member == nil {
+		return nil, fiber.NewError(http.StatusNotFound, "Failed to find member")
+	}
+	return member, nil
+}
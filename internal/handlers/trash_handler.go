@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type TrashHandler struct {
+	trashService *service.TrashService
+	userService  *service.UserService
+}
+
+func NewTrashHandler(trashService *service.TrashService, userService *service.UserService) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+		userService:  userService,
+	}
+}
+
+// @Summary List trash
+// @Description Get paginated soft deleted users, shops or categories, most recently deleted first
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(10)
+// @Param type query string false "Item type (user, shop or category)" default(shop)
+// @Success 200
+// @Router /admin/trash [get]
+func (t *TrashHandler) TrashList(c *fiber.Ctx) error {
+	page, pageSize := utils.PaginationParams(c)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	items, total, err := t.trashService.List(ctx, c.Query("type", service.TrashShop), page, pageSize)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to list trash")
+	}
+
+	response := utils.CreatePagination(page, pageSize, total, items)
+	return utils.SendSuccess(c, http.StatusOK, response)
+}
+
+// @Summary Restore from trash
+// @Description Post the API's restore of a soft deleted user, shop or category, a category comes back with the subcategories deleted along with it
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param type path string true "Item type (user, shop or category)"
+// @Param id path string true "Item ID"
+// @Failure 409
+// @Router /admin/trash/{type}/{id}/restore [post]
+func (t *TrashHandler) Restore(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := t.trashService.Restore(ctx, c.Params("type"), id); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to restore")
+	}
+	middleware.Audit(c, "trash.restore", c.Params("type"), id.Hex(), nil, nil)
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "Restored successfully")
+}
+
+// @Summary Purge from trash
+// @Description Delete the API's permanent deletion of a soft deleted user, shop or category with their related documents, as the deletion policies allow
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param type path string true "Item type (user, shop or category)"
+// @Param id path string true "Item ID"
+// @Param dry_run query bool false "Only report what would be deleted"
+// @Param policy query string false "Users only: comma separated relation:policy overrides, e.g. user.shops:reassign"
+// @Param reassign_to query string false "Users only: user ID receiving reassigned shops, defaults to the current user"
+// @Success 200 {object} dto.DeletionReport
+// @Failure 409
+// @Router /admin/trash/{type}/{id} [delete]
+func (t *TrashHandler) Purge(c *fiber.Ctx) error {
+	id, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+	}
+
+	// Deleting many files and their content takes longer than a regular request
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	dryRun := c.QueryBool("dry_run")
+	var report *dto.DeletionReport
+	switch c.Params("type") {
+	case service.TrashUser:
+		auth, ok := middleware.GetUserFromContext(c)
+		if !ok {
+			return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+		}
+		newOwner := auth
+		if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+			newOwner, err = t.userService.FindByID(ctx, reassignTo)
+			if err != nil || newOwner == nil {
+				return utils.SendError(c, http.StatusBadRequest, "User to reassign shops to not found")
+			}
+		}
+		report, err = t.trashService.PurgeUser(ctx, id, newOwner, splitQuery(c.Query("policy")), dryRun)
+	case service.TrashShop:
+		report, err = t.trashService.PurgeShop(ctx, id, dryRun)
+	case service.TrashCategory:
+		report, err = t.trashService.PurgeCategory(ctx, id, dryRun)
+	default:
+		return utils.SendError(c, http.StatusBadRequest, "Type must be one of user, shop, category")
+	}
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to purge")
+	}
+
+	if dryRun {
+		middleware.SkipAudit(c)
+		return utils.SendSuccess(c, http.StatusOK, report, "Purge dry run")
+	}
+	middleware.Audit(c, "trash.purge", c.Params("type"), id.Hex(), nil, report)
+	return utils.SendSuccess(c, http.StatusOK, report, "Purged successfully")
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadHandler serves resumable uploads following the tus 1.0 protocol
+type UploadHandler struct {
+	tusService    *service.TusService
+	shopService   *service.ShopService
+	policyService *service.PolicyService
+}
+
+func NewUploadHandler(tusService *service.TusService, shopService *service.ShopService, policyService *service.PolicyService) *UploadHandler {
+	return &UploadHandler{
+		tusService:    tusService,
+		shopService:   shopService,
+		policyService: policyService,
+	}
+}
+
+// @Summary Resumable upload capabilities
+// @Description Options of the tus server: version, extensions and maximum size
+// @Tags upload
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Success 204
+// @Router /shop/{id}/uploads [options]
+func (u *UploadHandler) Options(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", service.TusVersion)
+	c.Set("Tus-Version", service.TusVersion)
+	c.Set("Tus-Extension", service.TusExtensions)
+	if max := u.tusService.MaxSize(); max > 0 {
+		c.Set("Tus-Max-Size", strconv.FormatInt(max, 10))
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// @Summary Create resumable upload
+// @Description Post the API's creation of a tus upload, chunks are then sent with PATCH to the returned Location
+// @Tags upload
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Param Upload-Length header int true "Total size in bytes"
+// @Param Upload-Metadata header string false "Comma separated key and base64 value pairs, e.g. filename ZG9nLnBuZw=="
+// @Success 201
+// @Failure 412
+// @Failure 413
+// @Router /shop/{id}/uploads [post]
+func (u *UploadHandler) Create(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	length, err := strconv.ParseInt(c.Get("Upload-Length"), 10, 64)
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Upload-Length is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, user, err := u.findAuthorizedShop(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Failed to find shop")
+	}
+
+	upload, err := u.tusService.Create(ctx, shop, user, length, c.Get("Upload-Metadata"))
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to create upload")
+	}
+
+	c.Location(strings.TrimSuffix(c.Path(), "/") + "/" + upload.ID)
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	return c.SendStatus(http.StatusCreated)
+}
+
+// @Summary Resumable upload status
+// @Description Head of a tus upload with the number of bytes received so far
+// @Tags upload
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param upload_id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Success 200
+// @Failure 404
+// @Router /shop/{id}/uploads/{upload_id} [head]
+func (u *UploadHandler) Status(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, upload, err := u.findUpload(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Upload not found")
+	}
+
+	c.Set("Cache-Control", "no-store")
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	if upload.Metadata != "" {
+		c.Set("Upload-Metadata", upload.Metadata)
+	}
+	return c.SendStatus(http.StatusOK)
+}
+
+// @Summary Append to resumable upload
+// @Description Patch of a tus upload with the next chunk. Once all bytes are received the upload becomes a shop file, its ID is returned in the File-Id header
+// @Tags upload
+// @Accept application/offset+octet-stream
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param upload_id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Param Upload-Offset header int true "Offset the chunk starts at"
+// @Success 204
+// @Failure 409
+// @Failure 415
+// @Failure 423
+// @Router /shop/{id}/uploads/{upload_id} [patch]
+func (u *UploadHandler) Append(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	if c.Get(fiber.HeaderContentType) != "application/offset+octet-stream" {
+		return utils.SendError(c, http.StatusUnsupportedMediaType, "Content-Type must be application/offset+octet-stream")
+	}
+
+	offset, err := strconv.ParseInt(c.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		return utils.SendError(c, http.StatusBadRequest, "Upload-Offset is required")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	shop, upload, err := u.findUpload(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Upload not found")
+	}
+
+	fileStore, err := u.tusService.Append(ctx, shop, upload, offset, bytes.NewReader(c.Body()))
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to write upload")
+	}
+
+	c.Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	if upload.Offset < upload.Length {
+		c.Set("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	if fileStore != nil {
+		c.Set("File-Id", fileStore.ID.Hex())
+		middleware.Audit(c, "file.upload", "file", fileStore.ID.Hex(), nil, fileStore)
+	} else {
+		// Only the chunk completing an upload is worth an audit entry
+		middleware.SkipAudit(c)
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// @Summary Terminate resumable upload
+// @Description Delete an unfinished tus upload and the data received so far
+// @Tags upload
+// @Security Bearer
+// @Param id path string true "Shop ID"
+// @Param upload_id path string true "Upload ID"
+// @Param Tus-Resumable header string true "Protocol version, 1.0.0"
+// @Success 204
+// @Router /shop/{id}/uploads/{upload_id} [delete]
+func (u *UploadHandler) Terminate(c *fiber.Ctx) error {
+	if err := checkTusResumable(c); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, upload, err := u.findUpload(ctx, c)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusNotFound, "Upload not found")
+	}
+
+	if err := u.tusService.Terminate(ctx, upload); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to terminate upload")
+	}
+	return c.SendStatus(http.StatusNoContent)
+}
+
+// checkTusResumable sets the protocol header on the response and rejects
+// clients speaking another version
+func checkTusResumable(c *fiber.Ctx) error {
+	c.Set("Tus-Resumable", service.TusVersion)
+	if c.Get("Tus-Resumable") != service.TusVersion {
+		c.Set("Tus-Version", service.TusVersion)
+		return utils.SendError(c, http.StatusPreconditionFailed, "Unsupported Tus-Resumable version")
+	}
+	return nil
+}
+
+func (u *UploadHandler) findAuthorizedShop(ctx context.Context, c *fiber.Ctx) (*model.Shop, *model.User, error) {
+	shopId, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, nil, fiber.NewError(http.StatusBadRequest, "Invalid shop ID format")
+	}
+
+	shop, err := u.shopService.FindByID(ctx, shopId)
+	if err != nil || shop == nil {
+		return nil, nil, fiber.NewError(http.StatusNotFound, "Failed to find shop")
+	}
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return nil, nil, fiber.NewError(http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := u.policyService.AuthorizeShop(ctx, user, utils.FileUpload, shop); err != nil {
+		return nil, nil, err
+	}
+	return shop, user, nil
+}
+
+// findUpload loads the upload from the route, only the user who created it may continue it
+func (u *UploadHandler) findUpload(ctx context.Context, c *fiber.Ctx) (*model.Shop, *service.TusUpload, error) {
+	shop, user, err := u.findAuthorizedShop(ctx, c)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	upload, err := u.tusService.Find(ctx, shop, c.Params("upload_id"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if upload.UserID != user.ID {
+		return nil, nil, fiber.NewError(http.StatusNotFound, "Upload not found")
+	}
+	return shop, upload, nil
+}
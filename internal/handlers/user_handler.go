@@ -8,6 +8,7 @@ import (
 	"go-fiber-api/pkg/middleware"
 	"go-fiber-api/pkg/utils"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -35,6 +36,7 @@ func NewUserHandler(userService *service.UserService) *UserHandler {
 // @Param page query int false "Page number" default(1)
 // @Param page_size query int false "Page size" default(10)
 // @Param name query string false "Filter by name"
+// @Param email_verified query bool false "Filter by email verification"
 // @Success 200
 // @Router /admin/users [get]
 func (u *UserHandler) UserList(c *fiber.Ctx) error {
@@ -58,6 +60,13 @@ func (u *UserHandler) UserList(c *fiber.Ctx) error {
 			}},
 		})
 	}
+	if verified := c.Query("email_verified"); verified != "" {
+		emailVerified, err := strconv.ParseBool(verified)
+		if err != nil {
+			return utils.SendError(c, http.StatusBadRequest, "Invalid filter parameters")
+		}
+		mongoFilter = append(mongoFilter, bson.E{Key: "email_verified", Value: emailVerified})
+	}
 
 	total, err := u.userService.Count(ctx, mongoFilter)
 	if err != nil {
@@ -113,16 +122,28 @@ func (u *UserHandler) Register(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
 
-	token, err := u.userService.Login(ctx, req.Password, user)
-	if err != nil {
-		return utils.SendError(c, http.StatusUnauthorized, "Invalid password")
+	middleware.Audit(c, "user.register", "user", user.ID.Hex(), nil, user)
+
+	if err := u.userService.SendVerification(ctx, user); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to send verification email")
 	}
 
 	info := &model.User{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-		Roles: user.Roles,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified,
+	}
+
+	// Unverified accounts cannot sign in when verification is enforced
+	if u.userService.RequiresVerifiedEmail(user) {
+		return utils.SendSuccess(c, http.StatusOK, fiber.Map{"info": info}, "Please verify your email before logging in")
+	}
+
+	token, err := u.userService.Login(ctx, req.Password, user)
+	if err != nil {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid password")
 	}
 
 	res := fiber.Map{
@@ -160,11 +181,148 @@ func (u *UserHandler) Login(c *fiber.Ctx) error {
 
 	tokenPair, err := u.userService.Login(ctx, req.Password, user)
 	if err != nil {
-		return utils.SendError(c, http.StatusUnauthorized, "Invalid password")
+		return utils.SendErrorFrom(c, err, http.StatusUnauthorized, "Invalid password")
 	}
 	return utils.SendSuccess(c, http.StatusOK, tokenPair, "Login successful")
 }
 
+// @Summary Forgot password endpoint
+// @Description Post the API's forgot password, sends a reset token when the email exists
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ForgotPasswordRequest true "Account email"
+// @Router /auth/password/forgot [post]
+func (u *UserHandler) ForgotPassword(c *fiber.Ctx) error {
+	var req dto.ForgotPasswordRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := u.userService.ForgotPassword(ctx, req.Email); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to send reset password")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "If the email exists, a reset link has been sent")
+}
+
+// @Summary Reset password endpoint
+// @Description Post the API's reset password with a reset token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResetPasswordRequest true "Reset token and new password"
+// @Router /auth/password/reset [post]
+func (u *UserHandler) ResetPassword(c *fiber.Ctx) error {
+	var req dto.ResetPasswordRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := u.userService.ResetPassword(ctx, req.Token, req.Password); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to reset password")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "Password reset successful")
+}
+
+// @Summary Request email verification endpoint
+// @Description Post the API's request of a verification email for the current user
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Router /auth/email/verification [post]
+func (u *UserHandler) RequestVerification(c *fiber.Ctx) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, ok := middleware.GetUserFromContext(c)
+	if !ok {
+		return utils.SendError(c, http.StatusUnauthorized, "Invalid session")
+	}
+
+	if err := u.userService.SendVerification(ctx, user); err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to send verification email")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "Verification email sent")
+}
+
+// @Summary Resend email verification endpoint
+// @Description Post the API's resend of a verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.ResendVerificationRequest true "Account email"
+// @Router /auth/email/verification/resend [post]
+func (u *UserHandler) ResendVerification(c *fiber.Ctx) error {
+	var req dto.ResendVerificationRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := u.userService.ResendVerification(ctx, req.Email); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to send verification email")
+	}
+
+	return utils.SendSuccess(c, http.StatusOK, nil, "If the email needs verification, a new link has been sent")
+}
+
+// @Summary Verify email endpoint
+// @Description Post the API's confirm of an email verification token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body dto.VerifyEmailRequest true "Verification token"
+// @Router /auth/email/verify [post]
+func (u *UserHandler) VerifyEmail(c *fiber.Ctx) error {
+	var req dto.VerifyEmailRequest
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	user, err := u.userService.VerifyEmail(ctx, req.Token)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to verify email")
+	}
+	middleware.Audit(c, "user.verify_email", "user", user.ID.Hex(), nil, fiber.Map{"email_verified": true})
+
+	return utils.SendSuccess(c, http.StatusOK, user, "Email verified successfully")
+}
+
 // @Summary Refresh endpoint
 // @Description Post the API's refresh token
 // @Tags auth
@@ -213,12 +371,14 @@ func (u *UserHandler) GetProfile(c *fiber.Ctx) error {
 	}
 
 	res := &model.User{
-		ID:        user.ID,
-		Name:      user.Name,
-		Email:     user.Email,
-		Roles:     user.Roles,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		Roles:         user.Roles,
+		EmailVerified: user.EmailVerified,
+		VerifiedAt:    user.VerifiedAt,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
 	}
 
 	return utils.SendSuccess(c, http.StatusOK, res)
@@ -261,6 +421,9 @@ func (u *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	if err := u.userService.UpdateById(ctx, objID, &req); err != nil {
 		return utils.SendError(c, http.StatusInternalServerError, err.Error())
 	}
+	updated := *user
+	updated.Name = req.Name
+	middleware.Audit(c, "user.update", "user", user.ID.Hex(), user, &updated)
 
 	res := fiber.Map{
 		"id":   user.ID,
@@ -270,8 +433,96 @@ func (u *UserHandler) UpdateUser(c *fiber.Ctx) error {
 	return utils.SendSuccess(c, http.StatusOK, res, "Profile updated successfully")
 }
 
+// @Summary Update email verification endpoint
+// @Description Put the API's override of a user email verification
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Param request body dto.UpdateEmailVerifiedRequest true "Email verification state"
+// @Router /admin/user/{id}/verification [put]
+func (u *UserHandler) UpdateEmailVerified(c *fiber.Ctx) error {
+	var req dto.UpdateEmailVerifiedRequest
+	id := c.Params("id")
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+	}
+
+	user, err := u.userService.FindByID(ctx, objID.Hex())
+	if err != nil || user == nil {
+		return utils.SendError(c, http.StatusNotFound, "User not found")
+	}
+
+	updated, err := u.userService.SetEmailVerified(ctx, user.ID, *req.EmailVerified)
+	if err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	}
+	middleware.Audit(c, "user.update_verification", "user", user.ID.Hex(), user, updated)
+	user = updated
+
+	return utils.SendSuccess(c, http.StatusOK, user, "Email verification updated successfully")
+}
+
+// @Summary Update roles endpoint
+// @Description Put the API's update of a user roles, the user is signed out everywhere
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security Bearer
+// @Param id path string true "User ID"
+// @Param request body dto.UpdateUserRolesRequest true "User roles"
+// @Router /admin/user/{id}/roles [put]
+func (u *UserHandler) UpdateUserRoles(c *fiber.Ctx) error {
+	var req dto.UpdateUserRolesRequest
+	id := c.Params("id")
+
+	if err := c.BodyParser(&req); err != nil {
+		return utils.SendError(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.ValidateStruct(&req); err != nil {
+		return utils.SendValidationError(c, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return utils.SendError(c, http.StatusBadRequest, "Invalid ID format")
+	}
+
+	user, err := u.userService.FindByID(ctx, objID.Hex())
+	if err != nil || user == nil {
+		return utils.SendError(c, http.StatusNotFound, "User not found")
+	}
+
+	updated, err := u.userService.UpdateRoles(ctx, user, req.Roles)
+	if err != nil {
+		return utils.SendErrorFrom(c, err, http.StatusInternalServerError, "Failed to update roles")
+	}
+	middleware.Audit(c, "user.update_roles", "user", user.ID.Hex(), user, updated)
+	user = updated
+
+	return utils.SendSuccess(c, http.StatusOK, user, "Roles updated successfully")
+}
+
 // @Summary Delete endpoint
-// @Description Get the API's delete user
+// @Description Get the API's move user to the trash and sign them out, their shops stay until an admin purges the user
 // @Tags admin
 // @Accept json
 // @Produce json
@@ -303,8 +554,13 @@ func (u *UserHandler) DeleteUser(c *fiber.Ctx) error {
 		return utils.SendError(c, http.StatusUnauthorized, "You cannot delete yourself")
 	}
 
-	if err := u.userService.Delete(ctx, paramId); err != nil {
-		return utils.SendError(c, http.StatusInternalServerError, err.Error())
+	if err := u.userService.Delete(ctx, user.ID, auth.ID); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "Failed to delete user")
+	}
+	middleware.Audit(c, "user.delete", "user", user.ID.Hex(), user, nil)
+
+	if err := u.userService.RevokeSessions(ctx, user.ID); err != nil {
+		return utils.SendError(c, http.StatusInternalServerError, "User deleted but their sessions could not be revoked: "+err.Error())
 	}
 
 	return utils.SendSuccess(c, http.StatusOK, nil, "User deleted successfully")
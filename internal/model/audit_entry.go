@@ -0,0 +1,33 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AuditEntry records one write made through the API. Entries are append-only,
+// nothing updates or deletes them. Anonymous calls have no actor.
+type AuditEntry struct {
+	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ActorID      *primitive.ObjectID    `bson:"actor_id,omitempty" json:"actor_id,omitempty"`
+	ActorEmail   string                 `bson:"actor_email,omitempty" json:"actor_email,omitempty"`
+	Action       string                 `bson:"action" json:"action"`
+	ResourceType string                 `bson:"resource_type,omitempty" json:"resource_type,omitempty"`
+	ResourceID   string                 `bson:"resource_id,omitempty" json:"resource_id,omitempty"`
+	Changes      map[string]AuditChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	Method       string                 `bson:"method" json:"method"`
+	Path         string                 `bson:"path" json:"path"`
+	Status       int                    `bson:"status" json:"status"`
+	IP           string                 `bson:"ip" json:"ip"`
+	UserAgent    string                 `bson:"user_agent" json:"user_agent"`
+	RequestID    string                 `bson:"request_id" json:"request_id"`
+	CreatedAt    time.Time              `bson:"created_at" json:"created_at"`
+}
+
+// AuditChange is the value of a field before and after a write, nil when the
+// field did not exist on that side
+type AuditChange struct {
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Blob is stored content shared by every FileStore with the same SHA-256 hash
+// in the same backend. RefCount is the number of FileStores pointing at Key,
+// the content is deleted when it drops to zero.
+type Blob struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Hash        string             `bson:"hash" json:"hash"`
+	Backend     string             `bson:"backend" json:"backend"`
+	Key         string             `bson:"key" json:"key"`
+	Size        int64              `bson:"size" json:"size"`
+	ContentType string             `bson:"content_type" json:"content_type"`
+	RefCount    int64              `bson:"ref_count" json:"ref_count"`
+	// Pending is set until the content of a new blob is written, Deleting
+	// while the content of an unreferenced one is deleted
+	Pending   bool      `bson:"pending,omitempty" json:"pending,omitempty"`
+	Deleting  bool      `bson:"deleting,omitempty" json:"deleting,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// BlobStats sums up the blobs: StoredBytes is what the backends hold,
+// ReferencedBytes what they would hold without deduplication
+type BlobStats struct {
+	Blobs           int64 `bson:"blobs" json:"blobs"`
+	References      int64 `bson:"references" json:"references"`
+	StoredBytes     int64 `bson:"stored_bytes" json:"stored_bytes"`
+	ReferencedBytes int64 `bson:"referenced_bytes" json:"referenced_bytes"`
+	SavedBytes      int64 `bson:"-" json:"saved_bytes"`
+}
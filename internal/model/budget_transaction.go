@@ -0,0 +1,26 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	BudgetCredit = "credit"
+	BudgetDebit  = "debit"
+)
+
+// BudgetTransaction is an append-only ledger entry of a shop budget. Amounts are
+// minor units (cents) and always positive, Type tells the direction.
+type BudgetTransaction struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ShopID         primitive.ObjectID `bson:"shop_id" json:"shop_id"`
+	Type           string             `bson:"type" json:"type"`
+	Amount         int64              `bson:"amount" json:"amount"`
+	BalanceAfter   int64              `bson:"balance_after" json:"balance_after"`
+	Reason         string             `bson:"reason" json:"reason"`
+	ActorID        primitive.ObjectID `bson:"actor_id" json:"actor_id"`
+	IdempotencyKey string             `bson:"idempotency_key,omitempty" json:"idempotency_key,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+}
@@ -6,10 +6,28 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Category of a shop, nested below ParentID when it is set. Path lists the IDs
+// from the root down to the category itself as /root/.../id/, the subtree of a
+// category is every category whose Path starts with its Path. Position orders
+// the children of the same parent. Trashed categories have DeletedAt set.
 type Category struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id"`
-	Name      string             `json:"name" bson:"name"`
-	ShopID    primitive.ObjectID `json:"shop_id" bson:"shop_id"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID        primitive.ObjectID  `json:"id" bson:"_id"`
+	Name      string              `json:"name" bson:"name"`
+	ShopID    primitive.ObjectID  `json:"shop_id" bson:"shop_id"`
+	ParentID  *primitive.ObjectID `json:"parent_id" bson:"parent_id"`
+	Position  int                 `json:"position" bson:"position"`
+	Path      string              `json:"path" bson:"path"`
+	DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
+	CreatedAt time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// CategoryPath is the Path of the category id below a parent with parentPath,
+// an empty parentPath makes it a root
+func CategoryPath(parentPath string, id primitive.ObjectID) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return parentPath + id.Hex() + "/"
 }
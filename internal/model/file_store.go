@@ -6,12 +6,52 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const (
+	DerivativesPending = "pending"
+	DerivativesReady   = "ready"
+	DerivativesFailed  = "failed"
+
+	ScanPending  = "pending"
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	ScanError    = "error"
+)
+
+// FileStore is a file of a shop. Its content lives in the storage Backend under
+// Key, BasePath is only set on files uploaded before storage backends existed.
+// Image derivatives are FileStores too, pointing at the original with ParentID.
+// Hash is the hex SHA-256 of the content, it is the ETag of downloads.
+// Files with the same Hash share one Blob, Key is the key of that blob.
+// Only files with ScanStatus clean can be downloaded.
 type FileStore struct {
-	ID        primitive.ObjectID `json:"_id" bson:"_id"`
-	Name      string             `json:"name" bson:"name"`
-	BasePath  string             `json:"base_path" bson:"base_path"`
-	Extension string             `json:"extension" bson:"extension"`
-	ShopID    primitive.ObjectID `json:"shop_id" bson:"shop_id"`
-	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
-	UpdatedAt time.Time          `json:"updated_at" bson:"updated_at"`
+	ID            primitive.ObjectID  `json:"_id" bson:"_id"`
+	Name          string              `json:"name" bson:"name"`
+	OriginalName  string              `json:"original_name" bson:"original_name"`
+	BasePath      string              `json:"base_path,omitempty" bson:"base_path,omitempty"`
+	Backend       string              `json:"backend" bson:"backend"`
+	Key           string              `json:"key" bson:"key"`
+	Extension     string              `json:"extension" bson:"extension"`
+	Size          int64               `json:"size" bson:"size"`
+	ContentType   string              `json:"content_type" bson:"content_type"`
+	Hash          string              `json:"hash,omitempty" bson:"hash,omitempty"`
+	ShopID        primitive.ObjectID  `json:"shop_id" bson:"shop_id"`
+	UploadedBy    primitive.ObjectID  `json:"uploaded_by,omitempty" bson:"uploaded_by,omitempty"`
+	Position      int                 `json:"position" bson:"position"`
+	ParentID      *primitive.ObjectID `json:"parent_id,omitempty" bson:"parent_id,omitempty"`
+	Variant       string              `json:"variant,omitempty" bson:"variant,omitempty"`
+	Variants      []string            `json:"variants,omitempty" bson:"variants,omitempty"`
+	Derivatives   string              `json:"derivatives,omitempty" bson:"derivatives,omitempty"`
+	ScanStatus    string              `json:"scan_status" bson:"scan_status"`
+	ScanSignature string              `json:"scan_signature,omitempty" bson:"scan_signature,omitempty"`
+	ScannedBy     string              `json:"scanned_by,omitempty" bson:"scanned_by,omitempty"`
+	ScannedAt     *time.Time          `json:"scanned_at,omitempty" bson:"scanned_at,omitempty"`
+	CreatedAt     time.Time           `json:"created_at" bson:"created_at"`
+	UpdatedAt     time.Time           `json:"updated_at" bson:"updated_at"`
+}
+
+// ContentTypeUsage sums up the files of a shop with the same content type
+type ContentTypeUsage struct {
+	ContentType string `bson:"_id" json:"content_type"`
+	Files       int64  `bson:"files" json:"files"`
+	Bytes       int64  `bson:"bytes" json:"bytes"`
 }
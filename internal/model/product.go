@@ -1,6 +1,10 @@
 package model
 
-import "go.mongodb.org/mongo-driver/bson/primitive"
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
 
 type Product struct {
 	ID          primitive.ObjectID `json:"id" bson:"_id"`
@@ -10,4 +14,6 @@ type Product struct {
 	ImageID     primitive.ObjectID `json:"image_id" bson:"image_id,omitempty"`
 	CategoryID  primitive.ObjectID `json:"category_id" bson:"category_id,omitempty"`
 	ShopID      primitive.ObjectID `json:"shop_id" bson:"shop_id"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time          `json:"updated_at" bson:"updated_at"`
 }
@@ -0,0 +1,17 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type Role struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name        string             `bson:"name" json:"name"`
+	Permissions []string           `bson:"permissions" json:"permissions"`
+	// SeededPermissions are the defaults already applied, so removed ones are not granted again
+	SeededPermissions []string  `bson:"seeded_permissions" json:"-"`
+	CreatedAt         time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time `bson:"updated_at" json:"updated_at"`
+}
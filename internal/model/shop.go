@@ -7,13 +7,20 @@ import (
 )
 
 type Shop struct {
-	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
-	Name       string              `bson:"name" json:"name"`
-	Budget     float64             `bson:"budget,default=0" json:"budget"`
-	CreatedBy  primitive.ObjectID  `bson:"created_by" json:"created_by"`
-	User       *UserResponseOnShop `bson:"user,omitempty" json:"user"`
-	Categories []*Category         `bson:"categories,omitempty" json:"categories,omitempty"`
-	Files      []*FileStore        `bson:"files,omitempty" json:"files,omitempty"`
-	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
-	UpdatedAt  time.Time           `bson:"updated_at" json:"updated_at"`
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name         string              `bson:"name" json:"name"`
+	Budget       int64               `bson:"budget" json:"budget"` // minor units, changed through the budget ledger
+	CreatedBy    primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	Plan         string              `bson:"plan,omitempty" json:"plan,omitempty"`
+	StorageQuota *int64              `bson:"storage_quota,omitempty" json:"storage_quota,omitempty"` // bytes, overrides the quota of the plan
+	StorageUsed  int64               `bson:"storage_used" json:"storage_used"`                       // bytes of the shop files
+	User         *UserResponseOnShop `bson:"user,omitempty" json:"user"`
+	Categories   []*Category         `bson:"categories,omitempty" json:"categories,omitempty"`
+	Files        []*FileStore        `bson:"files,omitempty" json:"files,omitempty"`
+	Members      []*ShopMember       `bson:"members,omitempty" json:"members,omitempty"`
+	HasMembers   bool                `bson:"has_members,omitempty" json:"-"` // read only, any membership exists, pending and declined included
+	DeletedAt    *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy    *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
 }
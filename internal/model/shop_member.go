@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MemberPending  = "pending"
+	MemberAccepted = "accepted"
+	MemberDeclined = "declined"
+)
+
+type ShopMember struct {
+	ID        primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ShopID    primitive.ObjectID  `bson:"shop_id" json:"shop_id"`
+	UserID    *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Email     string              `bson:"email" json:"email"`
+	Role      string              `bson:"role" json:"role"`
+	Status    string              `bson:"status" json:"status"`
+	InvitedBy primitive.ObjectID  `bson:"invited_by" json:"invited_by"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+}
@@ -7,13 +7,17 @@ import (
 )
 
 type User struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Email     string             `bson:"email" json:"email"`
-	Password  string             `bson:"password" json:"-"` // "-" means this field won't be included in JSON
-	Name      string             `bson:"name" json:"name"`
-	Roles     []string           `bson:"roles" json:"roles,omitempty"`
-	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Email         string              `bson:"email" json:"email"`
+	Password      string              `bson:"password" json:"-"` // "-" means this field won't be included in JSON
+	Name          string              `bson:"name" json:"name"`
+	Roles         []string            `bson:"roles" json:"roles,omitempty"`
+	EmailVerified bool                `bson:"email_verified" json:"email_verified"`
+	VerifiedAt    *time.Time          `bson:"verified_at,omitempty" json:"verified_at,omitempty"`
+	DeletedAt     *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy     *primitive.ObjectID `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
 }
 
 type UserResponseOnShop struct {
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditRepository is append-only on purpose: there is no way to change or
+// remove an entry through it
+type AuditRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, entry *model.AuditEntry) error
+	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.AuditEntry, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+}
+
+type auditRepository struct {
+	collection *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) AuditRepository {
+	return &auditRepository{
+		collection: db.Collection("audit_log"),
+	}
+}
+
+func (r *auditRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "actor_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "resource_type", Value: 1}, {Key: "resource_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+func (r *auditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	entry.ID = primitive.NewObjectID()
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	_, err := r.collection.InsertOne(ctx, entry)
+	return err
+}
+
+func (r *auditRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.AuditEntry, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []model.AuditEntry{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *auditRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
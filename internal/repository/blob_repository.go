@@ -0,0 +1,194 @@
+package repository
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BlobRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Acquire(ctx context.Context, blob *model.Blob) (*model.Blob, error)
+	MarkStored(ctx context.Context, id primitive.ObjectID) error
+	Release(ctx context.Context, backend, key string) (*model.Blob, error)
+	MarkDeleting(ctx context.Context, id primitive.ObjectID) (bool, error)
+	UnmarkDeleting(ctx context.Context, id primitive.ObjectID) error
+	Remove(ctx context.Context, id primitive.ObjectID) error
+	RemoveByKey(ctx context.Context, backend, key string) error
+	FindAll(ctx context.Context) ([]model.Blob, error)
+	SetRefCount(ctx context.Context, id primitive.ObjectID, refCount int64) error
+	Stats(ctx context.Context) (*model.BlobStats, error)
+}
+
+type blobRepository struct {
+	collection *mongo.Collection
+}
+
+func NewBlobRepository(db *mongo.Database) BlobRepository {
+	return &blobRepository{
+		collection: db.Collection("blobs"),
+	}
+}
+
+func (r *blobRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "backend", Value: 1}, {Key: "hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "backend", Value: 1}, {Key: "key", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	})
+	return err
+}
+
+// Acquire adds a reference to the blob with the hash of blob, inserting it as
+// pending when it is not known yet. It returns nil while a blob with that hash
+// is being deleted, the caller retries once the deletion is done.
+func (r *blobRepository) Acquire(ctx context.Context, blob *model.Blob) (*model.Blob, error) {
+	var result model.Blob
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"backend": blob.Backend, "hash": blob.Hash, "deleting": bson.M{"$ne": true}},
+		bson.M{
+			"$inc": bson.M{"ref_count": 1},
+			"$setOnInsert": bson.M{
+				"_id":          primitive.NewObjectID(),
+				"key":          blob.Key,
+				"size":         blob.Size,
+				"content_type": blob.ContentType,
+				"pending":      true,
+				"created_at":   time.Now(),
+			},
+			"$currentDate": bson.M{"updated_at": true},
+		},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&result)
+	// The unique hash index rejects the insert next to a deleting blob, or
+	// next to one inserted concurrently
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// MarkStored records that the content of a pending blob was written
+func (r *blobRepository) MarkStored(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$unset": bson.M{"pending": ""}})
+	return err
+}
+
+// Release drops a reference to the blob at key, it returns nil when the key is no blob
+func (r *blobRepository) Release(ctx context.Context, backend, key string) (*model.Blob, error) {
+	return r.update(ctx, bson.M{"backend": backend, "key": key, "ref_count": bson.M{"$gt": 0}}, -1)
+}
+
+// MarkDeleting turns the blob into a tombstone if it is still unreferenced,
+// Acquire no longer matches it. It returns false when it was referenced again.
+func (r *blobRepository) MarkDeleting(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "ref_count": bson.M{"$lte": 0}, "deleting": bson.M{"$ne": true}},
+		bson.M{"$set": bson.M{"deleting": true}, "$currentDate": bson.M{"updated_at": true}},
+	)
+	if err != nil {
+		return false, err
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// UnmarkDeleting makes the blob available again after its content could not be deleted
+func (r *blobRepository) UnmarkDeleting(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$unset":       bson.M{"deleting": ""},
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	return err
+}
+
+// Remove deletes the tombstone of a blob whose content is gone
+func (r *blobRepository) Remove(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "deleting": true})
+	return err
+}
+
+// RemoveByKey deletes the blob record of key whatever its references
+func (r *blobRepository) RemoveByKey(ctx context.Context, backend, key string) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"backend": backend, "key": key})
+	return err
+}
+
+func (r *blobRepository) FindAll(ctx context.Context) ([]model.Blob, error) {
+	var blobs []model.Blob
+	cursor, err := r.collection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &blobs); err != nil {
+		return nil, err
+	}
+	return blobs, nil
+}
+
+func (r *blobRepository) SetRefCount(ctx context.Context, id primitive.ObjectID, refCount int64) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set":         bson.M{"ref_count": refCount},
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	return err
+}
+
+func (r *blobRepository) Stats(ctx context.Context) (*model.BlobStats, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.M{
+			"_id":              nil,
+			"blobs":            bson.M{"$sum": 1},
+			"references":       bson.M{"$sum": "$ref_count"},
+			"stored_bytes":     bson.M{"$sum": "$size"},
+			"referenced_bytes": bson.M{"$sum": bson.M{"$multiply": bson.A{"$size", "$ref_count"}}},
+		}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	stats := &model.BlobStats{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(stats); err != nil {
+			return nil, err
+		}
+	}
+	stats.SavedBytes = stats.ReferencedBytes - stats.StoredBytes
+	return stats, cursor.Err()
+}
+
+func (r *blobRepository) update(ctx context.Context, filter bson.M, delta int64) (*model.Blob, error) {
+	var result model.Blob
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		bson.M{
+			"$inc":         bson.M{"ref_count": delta},
+			"$currentDate": bson.M{"updated_at": true},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&result)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInsufficientBudget = errors.New("insufficient budget")
+	ErrIdempotencyReused  = errors.New("idempotency key already used for a different transaction")
+)
+
+type BudgetRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Apply(ctx context.Context, entry *model.BudgetTransaction) (*model.BudgetTransaction, error)
+	Record(ctx context.Context, entry *model.BudgetTransaction) (*model.BudgetTransaction, error)
+	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.BudgetTransaction, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+}
+
+type budgetRepository struct {
+	collection *mongo.Collection
+	shops      *mongo.Collection
+}
+
+func NewBudgetRepository(db *mongo.Database) BudgetRepository {
+	return &budgetRepository{
+		collection: db.Collection("budget_transactions"),
+		shops:      db.Collection("shops"),
+	}
+}
+
+func (r *budgetRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "idempotency_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"idempotency_key": bson.M{"$type": "string"},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	})
+	return err
+}
+
+// Apply moves the shop balance and appends the ledger entry in one transaction.
+// A debit that would make the balance negative fails with ErrInsufficientBudget,
+// a shop in the trash with mongo.ErrNoDocuments.
+// Replaying an idempotency key returns the entry recorded the first time.
+func (r *budgetRepository) Apply(ctx context.Context, entry *model.BudgetTransaction) (*model.BudgetTransaction, error) {
+	result, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		if entry.IdempotencyKey != "" {
+			var existing model.BudgetTransaction
+			err := r.collection.FindOne(sessCtx, bson.M{
+				"shop_id":         entry.ShopID,
+				"idempotency_key": entry.IdempotencyKey,
+			}).Decode(&existing)
+			if err == nil {
+				if existing.Type != entry.Type || existing.Amount != entry.Amount {
+					return nil, ErrIdempotencyReused
+				}
+				return &existing, nil
+			}
+			if err != mongo.ErrNoDocuments {
+				return nil, err
+			}
+		}
+
+		delta := entry.Amount
+		filter := notDeleted(bson.M{"_id": entry.ShopID})
+		if entry.Type == model.BudgetDebit {
+			delta = -entry.Amount
+			filter["budget"] = bson.M{"$gte": entry.Amount}
+		}
+
+		var shop model.Shop
+		err := r.shops.FindOneAndUpdate(
+			sessCtx,
+			filter,
+			bson.M{
+				"$inc": bson.M{"budget": delta},
+				"$currentDate": bson.M{
+					"updated_at": true,
+				},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&shop)
+		if err == mongo.ErrNoDocuments && entry.Type == model.BudgetDebit {
+			// The debit filter also misses shops that are gone or in the trash
+			shops, err := r.shops.CountDocuments(sessCtx, notDeleted(bson.M{"_id": entry.ShopID}))
+			if err != nil {
+				return nil, err
+			}
+			if shops == 0 {
+				return nil, mongo.ErrNoDocuments
+			}
+			return nil, ErrInsufficientBudget
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		entry.ID = primitive.NewObjectID()
+		entry.BalanceAfter = shop.Budget
+		entry.CreatedAt = time.Now()
+		if _, err := r.collection.InsertOne(sessCtx, entry); err != nil {
+			return nil, err
+		}
+		return entry, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.BudgetTransaction), nil
+}
+
+// Record appends an entry without touching the balance, used for the opening
+// balance a shop is created with
+func (r *budgetRepository) Record(ctx context.Context, entry *model.BudgetTransaction) (*model.BudgetTransaction, error) {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, entry)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (r *budgetRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.BudgetTransaction, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	transactions := []model.BudgetTransaction{}
+	if err := cursor.All(ctx, &transactions); err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+func (r *budgetRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
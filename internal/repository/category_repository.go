@@ -2,19 +2,32 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
+	"regexp"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CategoryRepository interface {
+	EnsureIndexes(ctx context.Context) error
 	Create(ctx context.Context, category *model.Category) (*model.Category, error)
 	Get(ctx context.Context, id primitive.ObjectID) (*model.Category, error)
-	List(ctx context.Context) ([]model.Category, error)
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Category, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.Category, error)
+	Delete(ctx context.Context, query bson.M, deletedBy primitive.ObjectID) (int64, error)
+	Restore(ctx context.Context, query bson.M) (int64, error)
+	MigrateTree(ctx context.Context) error
+	NextPosition(ctx context.Context, shopID primitive.ObjectID, parentID *primitive.ObjectID) (int, error)
+	Move(ctx context.Context, category *model.Category, parentID *primitive.ObjectID, path string, position int) error
+	Reorder(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID) error
 }
 
 type categoryRepository struct {
@@ -27,8 +40,88 @@ func NewCategoryRepository(db *mongo.Database) CategoryRepository {
 	}
 }
 
+// EnsureIndexes makes category names unique per shop, ignoring case and the
+// trash. Duplicates created before the index existed are renamed with a number first.
+func (r *categoryRepository) EnsureIndexes(ctx context.Context) error {
+	if err := r.renameDuplicates(ctx); err != nil {
+		return err
+	}
+	// Replaced by the index below, trashed categories must not hold on to their name
+	if _, err := r.collection.Indexes().DropOne(ctx, "shop_id_name_unique"); err != nil && !isIndexNotFound(err) {
+		return err
+	}
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "name", Value: 1}, {Key: "deleted_at", Value: 1}},
+			Options: options.Index().
+				SetName("shop_id_name_deleted_at_unique").
+				SetUnique(true).
+				SetCollation(&options.Collation{Locale: "en", Strength: 2}),
+		},
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "parent_id", Value: 1}, {Key: "position", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "shop_id", Value: 1}, {Key: "path", Value: 1}},
+		},
+	})
+	return err
+}
+
+// MigrateTree makes the categories created before nesting existed roots
+func (r *categoryRepository) MigrateTree(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"path": bson.M{"$exists": false}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"parent_id": nil,
+				"position":  0,
+				"path":      bson.M{"$concat": bson.A{"/", bson.M{"$toString": "$_id"}, "/"}},
+			}}},
+		},
+	)
+	return err
+}
+
+func (r *categoryRepository) renameDuplicates(ctx context.Context) error {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$sort", Value: bson.M{"created_at": 1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"shop_id": "$shop_id", "name": bson.M{"$toLower": "$name"}, "deleted_at": "$deleted_at"},
+			"ids":   bson.M{"$push": "$_id"},
+			"names": bson.M{"$push": "$name"},
+		}}},
+		{{Key: "$match", Value: bson.M{"ids.1": bson.M{"$exists": true}}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	var duplicates []struct {
+		IDs   []primitive.ObjectID `bson:"ids"`
+		Names []string             `bson:"names"`
+	}
+	if err := cursor.All(ctx, &duplicates); err != nil {
+		return err
+	}
+	// The oldest category keeps its name
+	for _, duplicate := range duplicates {
+		for i := 1; i < len(duplicate.IDs); i++ {
+			name := fmt.Sprintf("%s (%d)", duplicate.Names[i], i+1)
+			if _, err := r.collection.UpdateByID(ctx, duplicate.IDs[i], bson.M{"$set": bson.M{"name": name}}); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Create keeps the ID of category when it is set, its Path is made of it
 func (r *categoryRepository) Create(ctx context.Context, category *model.Category) (*model.Category, error) {
-	category.ID = primitive.NewObjectID()
+	if category.ID.IsZero() {
+		category.ID = primitive.NewObjectID()
+	}
 	category.CreatedAt = time.Now()
 	category.UpdatedAt = time.Now()
 
@@ -41,15 +134,15 @@ func (r *categoryRepository) Create(ctx context.Context, category *model.Categor
 
 func (r *categoryRepository) Get(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
 	var category model.Category
-	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&category)
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"_id": id})).Decode(&category)
 	if err != nil {
 		return nil, err
 	}
 	return &category, nil
 }
 
-func (r *categoryRepository) List(ctx context.Context) ([]model.Category, error) {
-	cursor, err := r.collection.Find(ctx, bson.M{})
+func (r *categoryRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Category, error) {
+	cursor, err := r.collection.Find(ctx, notDeleted(query), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +155,133 @@ func (r *categoryRepository) List(ctx context.Context) ([]model.Category, error)
 	return categories, nil
 }
 
-func (r *categoryRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+func (r *categoryRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, notDeleted(query))
+}
+
+func (r *categoryRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.Category, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedCategory model.Category
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		notDeleted(bson.M{"_id": id}),
+		bson.M{
+			"$set": set,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&updatedCategory)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedCategory, nil
+}
+
+// Delete moves the categories matching query to the trash, all with the same deleted_at
+func (r *categoryRepository) Delete(ctx context.Context, query bson.M, deletedBy primitive.ObjectID) (int64, error) {
+	result, err := r.collection.UpdateMany(ctx, notDeleted(query), trashUpdate(deletedBy, time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// Restore takes the trashed categories matching query out of the trash in one
+// transaction, so a name taken in the meantime restores none of them
+func (r *categoryRepository) Restore(ctx context.Context, query bson.M) (int64, error) {
+	trashed := bson.M{"deleted_at": InTrash}
+	for key, value := range query {
+		trashed[key] = value
+	}
+	restored, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		result, err := r.collection.UpdateMany(sessCtx, trashed, restoreUpdate)
+		if err != nil {
+			return nil, err
+		}
+		return result.ModifiedCount, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return restored.(int64), nil
+}
+
+// NextPosition is the position after the last child of parentID, or after the last root when it is nil
+func (r *categoryRepository) NextPosition(ctx context.Context, shopID primitive.ObjectID, parentID *primitive.ObjectID) (int, error) {
+	var last model.Category
+	opts := options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}})
+	err := r.collection.FindOne(ctx, notDeleted(bson.M{"shop_id": shopID, "parent_id": parentID}), opts).Decode(&last)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Position + 1, nil
+}
+
+// Move puts category at position below parentID in one transaction: the new
+// siblings from position on make room and the paths of the whole subtree are
+// rewritten from the old path of category to path
+func (r *categoryRepository) Move(ctx context.Context, category *model.Category, parentID *primitive.ObjectID, path string, position int) error {
+	_, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		_, err := r.collection.UpdateMany(sessCtx, bson.M{
+			"shop_id":   category.ShopID,
+			"parent_id": parentID,
+			"position":  bson.M{"$gte": position},
+			"_id":       bson.M{"$ne": category.ID},
+		}, bson.M{"$inc": bson.M{"position": 1}})
+		if err != nil {
+			return nil, err
+		}
+
+		oldLength := len(category.Path)
+		_, err = r.collection.UpdateMany(sessCtx, bson.M{
+			"shop_id": category.ShopID,
+			"path":    primitive.Regex{Pattern: "^" + regexp.QuoteMeta(category.Path)},
+		}, mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"path": bson.M{"$concat": bson.A{
+					path,
+					bson.M{"$substrCP": bson.A{"$path", oldLength, bson.M{"$subtract": bson.A{bson.M{"$strLenCP": "$path"}, oldLength}}}},
+				}},
+				"updated_at": "$$NOW",
+			}}},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = r.collection.UpdateByID(sessCtx, category.ID, bson.M{
+			"$set": bson.M{"parent_id": parentID, "position": position},
+		})
+		return nil, err
+	})
+	return err
+}
+
+// Reorder sets the position of each category to its index in ids
+func (r *categoryRepository) Reorder(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "shop_id": shopID}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"position": i},
+				"$currentDate": bson.M{"updated_at": true},
+			})
+	}
+	_, err := r.collection.BulkWrite(ctx, models)
 	return err
 }
+
+// isIndexNotFound tells whether dropping an index failed only because it, or its collection, does not exist
+func isIndexNotFound(err error) bool {
+	var commandErr mongo.CommandError
+	return errors.As(err, &commandErr) && (commandErr.Code == 26 || commandErr.Code == 27)
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
+	"go-fiber-api/pkg/utils"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeletionRepository counts and removes the documents related to a shop or a
+// user across collections. Every method runs in the transaction of ctx when
+// called from within WithTransaction.
+type DeletionRepository interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	Count(ctx context.Context, collection string, query bson.M) (int64, error)
+	FindIDs(ctx context.Context, collection string, query bson.M) ([]primitive.ObjectID, error)
+	FindFiles(ctx context.Context, query bson.M) ([]model.FileStore, error)
+	DeleteMany(ctx context.Context, collection string, query bson.M) (int64, error)
+	UpdateMany(ctx context.Context, collection string, query bson.M, set bson.M) (int64, error)
+	UpsertOwner(ctx context.Context, shopID primitive.ObjectID, owner *model.User, invitedBy primitive.ObjectID) error
+}
+
+type deletionRepository struct {
+	db *mongo.Database
+}
+
+func NewDeletionRepository(db *mongo.Database) DeletionRepository {
+	return &deletionRepository{db: db}
+}
+
+func (r *deletionRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := database.WithTransaction(ctx, r.db.Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
+func (r *deletionRepository) Count(ctx context.Context, collection string, query bson.M) (int64, error) {
+	return r.db.Collection(collection).CountDocuments(ctx, query)
+}
+
+func (r *deletionRepository) FindIDs(ctx context.Context, collection string, query bson.M) ([]primitive.ObjectID, error) {
+	cursor, err := r.db.Collection(collection).Find(ctx, query, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var documents []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(documents))
+	for i, document := range documents {
+		ids[i] = document.ID
+	}
+	return ids, nil
+}
+
+func (r *deletionRepository) FindFiles(ctx context.Context, query bson.M) ([]model.FileStore, error) {
+	cursor, err := r.db.Collection("file_stores").Find(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var files []model.FileStore
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func (r *deletionRepository) DeleteMany(ctx context.Context, collection string, query bson.M) (int64, error) {
+	result, err := r.db.Collection(collection).DeleteMany(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return result.DeletedCount, nil
+}
+
+func (r *deletionRepository) UpdateMany(ctx context.Context, collection string, query bson.M, set bson.M) (int64, error) {
+	result, err := r.db.Collection(collection).UpdateMany(ctx, query, bson.M{
+		"$set":         set,
+		"$currentDate": bson.M{"updated_at": true},
+	})
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+// UpsertOwner makes owner an accepted owner of the shop, keeping an existing membership of the same email
+func (r *deletionRepository) UpsertOwner(ctx context.Context, shopID primitive.ObjectID, owner *model.User, invitedBy primitive.ObjectID) error {
+	_, err := r.db.Collection("shop_members").UpdateOne(
+		ctx,
+		bson.M{"shop_id": shopID, "email": strings.ToLower(owner.Email)},
+		bson.M{
+			"$set": bson.M{
+				"user_id": owner.ID,
+				"role":    string(utils.ShopOwner),
+				"status":  model.MemberAccepted,
+			},
+			"$setOnInsert": bson.M{"invited_by": invitedBy, "created_at": time.Now()},
+			"$currentDate": bson.M{"updated_at": true},
+		},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
@@ -3,13 +3,12 @@ package repository
 import (
 	"context"
 	"go-fiber-api/internal/model"
-	"os"
-	"path/filepath"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type FileStoreRepository interface {
@@ -17,7 +16,17 @@ type FileStoreRepository interface {
 	FindById(ctx context.Context, id primitive.ObjectID) (*model.FileStore, error)
 	FindOne(ctx context.Context, query bson.M) (*model.FileStore, error)
 	Create(ctx context.Context, fileStore []*model.FileStore) ([]*model.FileStore, error)
-	Delete(ctx context.Context, fileStore *model.FileStore, id primitive.ObjectID) error
+	Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*model.FileStore, error)
+	UpdateWhere(ctx context.Context, query bson.M, update bson.M) (*model.FileStore, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	DeleteMany(ctx context.Context, query bson.M) error
+	NextPosition(ctx context.Context, shopID primitive.ObjectID) (int, error)
+	Reorder(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID) error
+	MigrateStorage(ctx context.Context) error
+	FindWithoutShop(ctx context.Context) ([]model.FileStore, error)
+	Usage(ctx context.Context, shopID primitive.ObjectID) ([]model.ContentTypeUsage, error)
+	FindPage(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.FileStore, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
 }
 
 type fileStoreRepository struct {
@@ -32,7 +41,8 @@ func NewFileStoreRepository(db *mongo.Database) FileStoreRepository {
 
 func (r *fileStoreRepository) FindAll(ctx context.Context, query bson.M) ([]model.FileStore, error) {
 	var fileStores []model.FileStore
-	cursor, err := r.collection.Find(ctx, query)
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, query, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -42,6 +52,22 @@ func (r *fileStoreRepository) FindAll(ctx context.Context, query bson.M) ([]mode
 	return fileStores, nil
 }
 
+func (r *fileStoreRepository) FindPage(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.FileStore, error) {
+	var fileStores []model.FileStore
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err = cursor.All(ctx, &fileStores); err != nil {
+		return nil, err
+	}
+	return fileStores, nil
+}
+
+func (r *fileStoreRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
+
 func (r *fileStoreRepository) FindOne(ctx context.Context, query bson.M) (*model.FileStore, error) {
 	var result model.FileStore
 	err := r.collection.FindOne(ctx, query).Decode(&result)
@@ -83,11 +109,139 @@ func (r *fileStoreRepository) Create(ctx context.Context, fileStore []*model.Fil
 	return fileStore, nil
 }
 
-func (r *fileStoreRepository) Delete(ctx context.Context, fileStore *model.FileStore, id primitive.ObjectID) error {
-	filePath := filepath.Join(fileStore.BasePath, fileStore.Name)
-	if err := os.Remove(filePath); err != nil {
-		return err
+func (r *fileStoreRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*model.FileStore, error) {
+	return r.UpdateWhere(ctx, bson.M{"_id": id}, update)
+}
+
+// UpdateWhere updates the file matching query, it returns mongo.ErrNoDocuments
+// when the file changed and no longer matches
+func (r *fileStoreRepository) UpdateWhere(ctx context.Context, query bson.M, update bson.M) (*model.FileStore, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated model.FileStore
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		query,
+		bson.M{
+			"$set": update,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&updated)
+	if err != nil {
+		return nil, err
 	}
+	return &updated, nil
+}
+
+func (r *fileStoreRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
 	return err
 }
+
+func (r *fileStoreRepository) DeleteMany(ctx context.Context, query bson.M) error {
+	_, err := r.collection.DeleteMany(ctx, query)
+	return err
+}
+
+// NextPosition returns the position after the last file of the shop
+func (r *fileStoreRepository) NextPosition(ctx context.Context, shopID primitive.ObjectID) (int, error) {
+	var last model.FileStore
+	opts := options.FindOne().SetSort(bson.D{{Key: "position", Value: -1}})
+	err := r.collection.FindOne(ctx, bson.M{"shop_id": shopID, "parent_id": bson.M{"$exists": false}}, opts).Decode(&last)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return last.Position + 1, nil
+}
+
+// Reorder sets the position of each file to its index in ids
+func (r *fileStoreRepository) Reorder(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	models := make([]mongo.WriteModel, len(ids))
+	for i, id := range ids {
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "shop_id": shopID}).
+			SetUpdate(bson.M{
+				"$set":         bson.M{"position": i},
+				"$currentDate": bson.M{"updated_at": true},
+			})
+	}
+	_, err := r.collection.BulkWrite(ctx, models)
+	return err
+}
+
+// MigrateStorage points files stored before storage backends at the local
+// driver, their name is the key below the upload directory. Files uploaded
+// before malware scanning are queued for a scan.
+func (r *fileStoreRepository) MigrateStorage(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"scan_status": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"scan_status": model.ScanPending}},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.collection.UpdateMany(
+		ctx,
+		bson.M{"backend": bson.M{"$exists": false}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"backend": "local",
+				"key":     "$name",
+			}}},
+		},
+	)
+	return err
+}
+
+// FindWithoutShop returns the files whose shop no longer exists
+func (r *fileStoreRepository) FindWithoutShop(ctx context.Context) ([]model.FileStore, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "shops",
+			"localField":   "shop_id",
+			"foreignField": "_id",
+			"as":           "shop",
+		}}},
+		{{Key: "$match", Value: bson.M{"shop": bson.M{"$size": 0}}}},
+		{{Key: "$project", Value: bson.M{"shop": 0}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var fileStores []model.FileStore
+	if err = cursor.All(ctx, &fileStores); err != nil {
+		return nil, err
+	}
+	return fileStores, nil
+}
+
+// Usage counts the files of a shop and their bytes by content type, without image derivatives
+func (r *fileStoreRepository) Usage(ctx context.Context, shopID primitive.ObjectID) ([]model.ContentTypeUsage, error) {
+	cursor, err := r.collection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"shop_id": shopID, "parent_id": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$content_type",
+			"files": bson.M{"$sum": 1},
+			"bytes": bson.M{"$sum": "$size"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "bytes", Value: -1}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var usage []model.ContentTypeUsage
+	if err = cursor.All(ctx, &usage); err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/dto"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProductRepository interface {
+	Create(ctx context.Context, product *model.Product) (*model.Product, error)
+	FindOne(ctx context.Context, query bson.M) (*model.Product, error)
+	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Product, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateProductRequest) (*model.Product, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+}
+
+type productRepository struct {
+	collection *mongo.Collection
+}
+
+func NewProductRepository(db *mongo.Database) ProductRepository {
+	return &productRepository{
+		collection: db.Collection("products"),
+	}
+}
+
+func (r *productRepository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
+	product.ID = primitive.NewObjectID()
+	product.CreatedAt = time.Now()
+	product.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, product)
+	if err != nil {
+		return nil, err
+	}
+	return product, nil
+}
+
+func (r *productRepository) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	var product model.Product
+	err := r.collection.FindOne(ctx, query).Decode(&product)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (r *productRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Product, error) {
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	products := []model.Product{}
+	if err := cursor.All(ctx, &products); err != nil {
+		return nil, err
+	}
+	return products, nil
+}
+
+func (r *productRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
+
+func (r *productRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateProductRequest) (*model.Product, error) {
+	set := bson.M{
+		"name":        payload.Name,
+		"description": payload.Description,
+		"price":       payload.Price,
+	}
+	unset := bson.M{}
+	if payload.ImageID.IsZero() {
+		unset["image_id"] = ""
+	} else {
+		set["image_id"] = payload.ImageID
+	}
+	if payload.CategoryID.IsZero() {
+		unset["category_id"] = ""
+	} else {
+		set["category_id"] = payload.CategoryID
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$currentDate": bson.M{
+			"updated_at": true,
+		},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedProduct model.Product
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&updatedProduct)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedProduct, nil
+}
+
+func (r *productRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
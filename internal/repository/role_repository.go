@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type RoleRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	FindAll(ctx context.Context) ([]model.Role, error)
+	FindByNames(ctx context.Context, names []string) ([]model.Role, error)
+	Seed(ctx context.Context, name string, defaults []string) error
+	Upsert(ctx context.Context, name string, permissions []string) (*model.Role, error)
+}
+
+type roleRepository struct {
+	collection *mongo.Collection
+}
+
+func NewRoleRepository(db *mongo.Database) RoleRepository {
+	return &roleRepository{
+		collection: db.Collection("roles"),
+	}
+}
+
+func (r *roleRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "name", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+func (r *roleRepository) FindAll(ctx context.Context) ([]model.Role, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	roles := []model.Role{}
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindByNames(ctx context.Context, names []string) ([]model.Role, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"name": bson.M{"$in": names}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var roles []model.Role
+	if err := cursor.All(ctx, &roles); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Seed creates the role with its default permissions. For an existing role only
+// defaults that were never applied before are added, so permissions removed by an
+// admin are not granted again.
+func (r *roleRepository) Seed(ctx context.Context, name string, defaults []string) error {
+	var role model.Role
+	err := r.collection.FindOne(ctx, bson.M{"name": name}).Decode(&role)
+	if err == mongo.ErrNoDocuments {
+		now := time.Now()
+		_, err = r.collection.InsertOne(ctx, &model.Role{
+			ID:                primitive.NewObjectID(),
+			Name:              name,
+			Permissions:       defaults,
+			SeededPermissions: defaults,
+			CreatedAt:         now,
+			UpdatedAt:         now,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	seeded := make(map[string]bool)
+	for _, p := range role.SeededPermissions {
+		seeded[p] = true
+	}
+	var added []string
+	for _, p := range defaults {
+		if !seeded[p] {
+			added = append(added, p)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+
+	_, err = r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": role.ID},
+		bson.M{
+			"$addToSet": bson.M{"permissions": bson.M{"$each": added}},
+			"$set":      bson.M{"seeded_permissions": defaults},
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+	)
+	return err
+}
+
+func (r *roleRepository) Upsert(ctx context.Context, name string, permissions []string) (*model.Role, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetUpsert(true)
+	var role model.Role
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"name": name},
+		bson.M{
+			"$set":         bson.M{"permissions": permissions},
+			"$setOnInsert": bson.M{"created_at": time.Now()},
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&role)
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
+	"go-fiber-api/pkg/utils"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrLastOwner = errors.New("a shop needs at least one owner")
+
+type ShopMemberRepository interface {
+	EnsureIndexes(ctx context.Context) error
+	Create(ctx context.Context, member *model.ShopMember) (*model.ShopMember, error)
+	FindOne(ctx context.Context, query bson.M) (*model.ShopMember, error)
+	FindAll(ctx context.Context, query bson.M) ([]model.ShopMember, error)
+	Count(ctx context.Context, query bson.M) (int64, error)
+	UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.ShopMember, error)
+	UpdateRole(ctx context.Context, id primitive.ObjectID, role string) (*model.ShopMember, error)
+	Delete(ctx context.Context, id primitive.ObjectID) error
+	MigrateCreators(ctx context.Context) error
+}
+
+type shopMemberRepository struct {
+	collection *mongo.Collection
+	shops      *mongo.Collection
+}
+
+func NewShopMemberRepository(db *mongo.Database) ShopMemberRepository {
+	return &shopMemberRepository{
+		collection: db.Collection("shop_members"),
+		shops:      db.Collection("shops"),
+	}
+}
+
+func (r *shopMemberRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "shop_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}, {Key: "status", Value: 1}},
+		},
+	})
+	return err
+}
+
+func (r *shopMemberRepository) Create(ctx context.Context, member *model.ShopMember) (*model.ShopMember, error) {
+	member.ID = primitive.NewObjectID()
+	member.CreatedAt = time.Now()
+	member.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, member)
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *shopMemberRepository) FindOne(ctx context.Context, query bson.M) (*model.ShopMember, error) {
+	var member model.ShopMember
+	err := r.collection.FindOne(ctx, query).Decode(&member)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *shopMemberRepository) FindAll(ctx context.Context, query bson.M) ([]model.ShopMember, error) {
+	cursor, err := r.collection.Find(ctx, query, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	members := []model.ShopMember{}
+	if err := cursor.All(ctx, &members); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r *shopMemberRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return r.collection.CountDocuments(ctx, query)
+}
+
+func (r *shopMemberRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.ShopMember, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedMember model.ShopMember
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": set,
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+		opts,
+	).Decode(&updatedMember)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedMember, nil
+}
+
+// UpdateRole changes the role of the member. Demoting the last accepted owner
+// of a shop fails with ErrLastOwner, see keepOwner.
+func (r *shopMemberRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, role string) (*model.ShopMember, error) {
+	result, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		var previous model.ShopMember
+		if err := r.collection.FindOne(sessCtx, bson.M{"_id": id}).Decode(&previous); err != nil {
+			return nil, err
+		}
+
+		var updatedMember model.ShopMember
+		err := r.collection.FindOneAndUpdate(
+			sessCtx,
+			bson.M{"_id": id},
+			bson.M{
+				"$set": bson.M{"role": role},
+				"$currentDate": bson.M{
+					"updated_at": true,
+				},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updatedMember)
+		if err != nil {
+			return nil, err
+		}
+
+		if role != string(utils.ShopOwner) {
+			if err := r.keepOwner(sessCtx, &previous); err != nil {
+				return nil, err
+			}
+		}
+		return &updatedMember, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.ShopMember), nil
+}
+
+// Delete removes the membership. Removing the last accepted owner of a shop
+// fails with ErrLastOwner, see keepOwner.
+func (r *shopMemberRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		var removed model.ShopMember
+		if err := r.collection.FindOneAndDelete(sessCtx, bson.M{"_id": id}).Decode(&removed); err != nil {
+			return nil, err
+		}
+		return nil, r.keepOwner(sessCtx, &removed)
+	})
+	return err
+}
+
+// keepOwner runs in the transaction that demotes or removes previous. When it
+// was an accepted owner, it writes the shop so that two owners changed at the
+// same time conflict instead of both seeing the other one, then fails with
+// ErrLastOwner when the shop has no accepted owner left.
+func (r *shopMemberRepository) keepOwner(sessCtx mongo.SessionContext, previous *model.ShopMember) error {
+	if previous.Role != string(utils.ShopOwner) || previous.Status != model.MemberAccepted {
+		return nil
+	}
+	if _, err := r.shops.UpdateOne(sessCtx, bson.M{"_id": previous.ShopID}, bson.M{
+		"$currentDate": bson.M{"updated_at": true},
+	}); err != nil {
+		return err
+	}
+
+	owners, err := r.collection.CountDocuments(sessCtx, bson.M{
+		"shop_id": previous.ShopID,
+		"role":    string(utils.ShopOwner),
+		"status":  model.MemberAccepted,
+	})
+	if err != nil {
+		return err
+	}
+	if owners == 0 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// MigrateCreators adds their creator as accepted owner to the shops without
+// any membership, shops created before memberships existed
+func (r *shopMemberRepository) MigrateCreators(ctx context.Context) error {
+	cursor, err := r.collection.Database().Collection("shops").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "shop_members",
+			"localField":   "_id",
+			"foreignField": "shop_id",
+			"as":           "memberships",
+		}}},
+		{{Key: "$match", Value: bson.M{"memberships": bson.M{"$size": 0}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "users",
+			"localField":   "created_by",
+			"foreignField": "_id",
+			"as":           "creator",
+		}}},
+		// Shops whose creator is gone stay without owner, admins manage them
+		{{Key: "$unwind", Value: "$creator"}},
+		{{Key: "$project", Value: bson.M{
+			"_id":        0,
+			"shop_id":    "$_id",
+			"user_id":    "$creator._id",
+			"email":      bson.M{"$toLower": "$creator.email"},
+			"role":       string(utils.ShopOwner),
+			"status":     model.MemberAccepted,
+			"invited_by": "$creator._id",
+			"created_at": "$$NOW",
+			"updated_at": "$$NOW",
+		}}},
+		{{Key: "$merge", Value: bson.M{
+			"into":           "shop_members",
+			"whenMatched":    "keepExisting",
+			"whenNotMatched": "insert",
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+	return cursor.Close(ctx)
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
 	"go-fiber-api/pkg/dto"
 	"time"
 
@@ -13,24 +14,41 @@ import (
 )
 
 type ShopRepository interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
 	Create(ctx context.Context, shop *model.Shop) (*model.Shop, error)
 	FindOne(ctx context.Context, query bson.M) (*model.Shop, error)
 	FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Shop, error)
 	Count(ctx context.Context, query bson.D) (int64, error)
 	UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateShopRequest) (*model.Shop, error)
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
+	MigrateBudgets(ctx context.Context) error
+	AddStorageUsed(ctx context.Context, id primitive.ObjectID, delta, limit int64) (bool, error)
+	UpdateStoragePlan(ctx context.Context, id primitive.ObjectID, plan string, quota *int64) (*model.Shop, error)
+	MigrateStorageUsed(ctx context.Context) error
 }
 
 type shopRepository struct {
 	collection *mongo.Collection
+	files      *mongo.Collection
 }
 
 func NewShopRepository(db *mongo.Database) ShopRepository {
 	return &shopRepository{
 		collection: db.Collection("shops"),
+		files:      db.Collection("file_stores"),
 	}
 }
 
+// WithTransaction runs fn in one transaction, the repositories called with its
+// ctx take part in it
+func (r *shopRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	_, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessCtx)
+	})
+	return err
+}
+
 func (r *shopRepository) Create(ctx context.Context, shop *model.Shop) (*model.Shop, error) {
 	shop.ID = primitive.NewObjectID()
 	shop.CreatedAt = time.Now()
@@ -46,7 +64,7 @@ func (r *shopRepository) Create(ctx context.Context, shop *model.Shop) (*model.S
 func (r *shopRepository) FindOne(ctx context.Context, query bson.M) (*model.Shop, error) {
 	var shop model.Shop
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: query}},
+		{{Key: "$match", Value: notDeleted(query)}},
 		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "users",
@@ -54,19 +72,55 @@ func (r *shopRepository) FindOne(ctx context.Context, query bson.M) (*model.Shop
 			"foreignField": "_id",
 			"as":           "user",
 		}}},
-		{{Key: "$unwind", Value: "$user"}},
+		// Shops whose creator is gone are still listed, without a user
+		{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "categories",
+			"let":  bson.M{"shop_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"$expr":      bson.M{"$eq": bson.A{"$shop_id", "$$shop_id"}},
+					"deleted_at": nil,
+				}}},
+			},
+			"as": "categories",
+		}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": "file_stores",
+			"let":  bson.M{"shop_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"$expr":     bson.M{"$eq": bson.A{"$shop_id", "$$shop_id"}},
+					"parent_id": bson.M{"$exists": false},
+				}}},
+				{{Key: "$sort", Value: bson.D{{Key: "position", Value: 1}, {Key: "created_at", Value: 1}}}},
+			},
+			"as": "files",
+		}}},
 		{{Key: "$lookup", Value: bson.M{
-			"from":         "categories",
-			"localField":   "_id",
-			"foreignField": "shop_id",
-			"as":           "categories",
+			"from": "shop_members",
+			"let":  bson.M{"shop_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{
+					"$expr":  bson.M{"$eq": bson.A{"$shop_id", "$$shop_id"}},
+					"status": model.MemberAccepted,
+				}}},
+			},
+			"as": "members",
 		}}},
+		// Shops created before memberships existed have none at all
 		{{Key: "$lookup", Value: bson.M{
-			"from":         "file_stores",
-			"localField":   "_id",
-			"foreignField": "shop_id",
-			"as":           "files",
+			"from": "shop_members",
+			"let":  bson.M{"shop_id": "$_id"},
+			"pipeline": mongo.Pipeline{
+				{{Key: "$match", Value: bson.M{"$expr": bson.M{"$eq": bson.A{"$shop_id", "$$shop_id"}}}}},
+				{{Key: "$limit", Value: 1}},
+				{{Key: "$project", Value: bson.M{"_id": 1}}},
+			},
+			"as": "any_member",
 		}}},
+		{{Key: "$set", Value: bson.M{"has_members": bson.M{"$gt": bson.A{bson.M{"$size": "$any_member"}, 0}}}}},
+		{{Key: "$unset", Value: "any_member"}},
 	}
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -86,20 +140,33 @@ func (r *shopRepository) FindOne(ctx context.Context, query bson.M) (*model.Shop
 	return &shop, nil
 }
 
+// FindAll sorts the shops by opts.Sort, newest first without one, and pages
+// them by opts.Skip and opts.Limit when set
 func (r *shopRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Shop, error) {
+	var sort interface{} = bson.D{{Key: "created_at", Value: -1}}
+	if opts != nil && opts.Sort != nil {
+		sort = opts.Sort
+	}
 	pipeline := mongo.Pipeline{
-		{{Key: "$match", Value: query}},
-		{{Key: "$sort", Value: bson.D{{Key: "created_at", Value: -1}}}},
-		{{Key: "$skip", Value: opts.Skip}},
-		{{Key: "$limit", Value: opts.Limit}},
+		{{Key: "$match", Value: notDeleted(query)}},
+		{{Key: "$sort", Value: sort}},
+	}
+	if opts != nil && opts.Skip != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: *opts.Skip}})
+	}
+	if opts != nil && opts.Limit != nil {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: *opts.Limit}})
+	}
+	pipeline = append(pipeline, mongo.Pipeline{
 		{{Key: "$lookup", Value: bson.M{
 			"from":         "users",
 			"localField":   "created_by",
 			"foreignField": "_id",
 			"as":           "user",
 		}}},
-		{{Key: "$unwind", Value: "$user"}},
-	}
+		// Shops whose creator is gone are still listed, without a user
+		{{Key: "$unwind", Value: bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}}},
+	}...)
 
 	cursor, err := r.collection.Aggregate(ctx, pipeline)
 	if err != nil {
@@ -115,7 +182,7 @@ func (r *shopRepository) FindAll(ctx context.Context, query bson.M, opts *option
 }
 
 func (r *shopRepository) Count(ctx context.Context, query bson.D) (int64, error) {
-	return r.collection.CountDocuments(ctx, query)
+	return r.collection.CountDocuments(ctx, notDeletedD(query))
 }
 
 func (r *shopRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateShopRequest) (*model.Shop, error) {
@@ -138,7 +205,104 @@ func (r *shopRepository) UpdateByID(ctx context.Context, id primitive.ObjectID,
 	return &updatedShop, nil
 }
 
-func (r *shopRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+// Delete moves the shop to the trash, its files, products and categories stay until it is purged
+func (r *shopRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), trashUpdate(deletedBy, time.Now()))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore takes the shop out of the trash
+func (r *shopRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": InTrash}, restoreUpdate)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+func (r *shopRepository) MigrateBudgets(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"budget": bson.M{"$type": "double"}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"budget": bson.M{"$toLong": bson.M{"$round": bson.A{bson.M{"$multiply": bson.A{"$budget", 100}}, 0}}},
+			}}},
+		},
+	)
+	return err
+}
+
+// AddStorageUsed moves the storage usage of the shop by delta. A positive delta
+// that would take the usage past a limit above zero is refused with false.
+func (r *shopRepository) AddStorageUsed(ctx context.Context, id primitive.ObjectID, delta, limit int64) (bool, error) {
+	filter := bson.M{"_id": id}
+	if limit > 0 && delta > 0 {
+		filter["storage_used"] = bson.M{"$lte": limit - delta}
+	}
+	result, err := r.collection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"storage_used": delta}})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// UpdateStoragePlan sets the plan of the shop, a nil quota falls back to the plan quota
+func (r *shopRepository) UpdateStoragePlan(ctx context.Context, id primitive.ObjectID, plan string, quota *int64) (*model.Shop, error) {
+	update := bson.M{
+		"$set":         bson.M{"plan": plan},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+	if quota != nil {
+		update["$set"].(bson.M)["storage_quota"] = *quota
+	} else {
+		update["$unset"] = bson.M{"storage_quota": ""}
+	}
+
+	var shop model.Shop
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&shop)
+	if err != nil {
+		return nil, err
+	}
+	return &shop, nil
+}
+
+// MigrateStorageUsed sets the storage usage of shops created before usage was
+// tracked to the size of their files
+func (r *shopRepository) MigrateStorageUsed(ctx context.Context) error {
+	cursor, err := r.files.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"parent_id": bson.M{"$exists": false}}}},
+		{{Key: "$group", Value: bson.M{"_id": "$shop_id", "bytes": bson.M{"$sum": "$size"}}}},
+	})
+	if err != nil {
+		return err
+	}
+	var usage []struct {
+		ShopID primitive.ObjectID `bson:"_id"`
+		Bytes  int64              `bson:"bytes"`
+	}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return err
+	}
+
+	models := []mongo.WriteModel{}
+	for _, u := range usage {
+		models = append(models, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": u.ShopID, "storage_used": bson.M{"$exists": false}}).
+			SetUpdate(bson.M{"$set": bson.M{"storage_used": u.Bytes}}))
+	}
+	models = append(models, mongo.NewUpdateManyModel().
+		SetFilter(bson.M{"storage_used": bson.M{"$exists": false}}).
+		SetUpdate(bson.M{"$set": bson.M{"storage_used": int64(0)}}))
+	_, err = r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(true))
 	return err
 }
@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Users, shops and categories are soft deleted: they get a deleted_at and stay
+// in the trash until restored or purged. Queries leave them out unless they
+// filter on deleted_at themselves.
+
+// InTrash matches the soft deleted documents
+var InTrash = bson.M{"$ne": nil}
+
+// notDeleted adds the exclusion of soft deleted documents to query
+func notDeleted(query bson.M) bson.M {
+	if _, ok := query["deleted_at"]; ok {
+		return query
+	}
+	filtered := make(bson.M, len(query)+1)
+	for key, value := range query {
+		filtered[key] = value
+	}
+	// nil matches documents without the field too
+	filtered["deleted_at"] = nil
+	return filtered
+}
+
+// notDeletedD is notDeleted for ordered queries
+func notDeletedD(query bson.D) bson.D {
+	for _, element := range query {
+		if element.Key == "deleted_at" {
+			return query
+		}
+	}
+	filtered := make(bson.D, 0, len(query)+1)
+	filtered = append(filtered, query...)
+	return append(filtered, bson.E{Key: "deleted_at", Value: nil})
+}
+
+// trashUpdate soft deletes the documents it is applied to
+func trashUpdate(deletedBy primitive.ObjectID, deletedAt time.Time) bson.M {
+	return bson.M{
+		"$set":         bson.M{"deleted_at": deletedAt, "deleted_by": deletedBy},
+		"$currentDate": bson.M{"updated_at": true},
+	}
+}
+
+// restoreUpdate takes the documents it is applied to out of the trash
+var restoreUpdate = bson.M{
+	"$unset":       bson.M{"deleted_at": "", "deleted_by": ""},
+	"$currentDate": bson.M{"updated_at": true},
+}
@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/database"
 	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/utils"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,22 +15,31 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrLastAdmin = errors.New("cannot remove the last admin")
+
 type UserRepository interface {
 	Create(ctx context.Context, user *model.User) error
 	UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateUserRequest) (*model.User, error)
-	Delete(ctx context.Context, id primitive.ObjectID) error
+	UpdatePassword(ctx context.Context, id primitive.ObjectID, hashedPassword string) error
+	UpdateEmailVerified(ctx context.Context, id primitive.ObjectID, verified bool) (*model.User, error)
+	UpdateRoles(ctx context.Context, id primitive.ObjectID, roles []string) (*model.User, error)
+	Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error
+	Restore(ctx context.Context, id primitive.ObjectID) error
 	FindOne(ctx context.Context, query bson.M) (*model.User, error)
 	FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]model.User, error)
 	Count(ctx context.Context, query bson.D) (int64, error)
+	MigrateEmailVerified(ctx context.Context) error
 }
 
 type userRepository struct {
 	collection *mongo.Collection
+	roles      *mongo.Collection
 }
 
 func NewUserRepository(db *mongo.Database) UserRepository {
 	return &userRepository{
 		collection: db.Collection("users"),
+		roles:      db.Collection("roles"),
 	}
 }
 
@@ -42,7 +54,7 @@ func (r *userRepository) Create(ctx context.Context, user *model.User) error {
 
 func (r *userRepository) FindOne(ctx context.Context, query bson.M) (*model.User, error) {
 	var user model.User
-	err := r.collection.FindOne(ctx, query).Decode(&user)
+	err := r.collection.FindOne(ctx, notDeleted(query)).Decode(&user)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
@@ -72,13 +84,119 @@ func (r *userRepository) UpdateByID(ctx context.Context, id primitive.ObjectID,
 	return &updatedUser, nil
 }
 
-func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
-	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+func (r *userRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, hashedPassword string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$set": bson.M{"password": hashedPassword},
+			"$currentDate": bson.M{
+				"updated_at": true,
+			},
+		},
+	)
 	return err
 }
 
+func (r *userRepository) UpdateEmailVerified(ctx context.Context, id primitive.ObjectID, verified bool) (*model.User, error) {
+	update := bson.M{
+		"$set": bson.M{"email_verified": verified},
+		"$currentDate": bson.M{
+			"updated_at": true,
+		},
+	}
+	if verified {
+		update["$set"] = bson.M{"email_verified": true, "verified_at": time.Now()}
+	} else {
+		update["$unset"] = bson.M{"verified_at": ""}
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updatedUser model.User
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&updatedUser)
+	if err != nil {
+		return nil, err
+	}
+	return &updatedUser, nil
+}
+
+// UpdateRoles replaces the roles of the user. Roles without admin fail with
+// ErrLastAdmin when no other admin would be left: the update and the count run
+// in one transaction that also writes the admin role, so two admins removed at
+// the same time conflict instead of both seeing the other one.
+func (r *userRepository) UpdateRoles(ctx context.Context, id primitive.ObjectID, roles []string) (*model.User, error) {
+	result, err := database.WithTransaction(ctx, r.collection.Database().Client(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+		keepsAdmin := false
+		for _, role := range roles {
+			keepsAdmin = keepsAdmin || role == string(utils.AdminRole)
+		}
+		if !keepsAdmin {
+			if _, err := r.roles.UpdateOne(sessCtx, bson.M{"name": string(utils.AdminRole)}, bson.M{
+				"$currentDate": bson.M{"updated_at": true},
+			}); err != nil {
+				return nil, err
+			}
+		}
+
+		var updatedUser model.User
+		err := r.collection.FindOneAndUpdate(
+			sessCtx,
+			bson.M{"_id": id},
+			bson.M{
+				"$set": bson.M{"roles": roles},
+				"$currentDate": bson.M{
+					"updated_at": true,
+				},
+			},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updatedUser)
+		if err != nil {
+			return nil, err
+		}
+
+		if !keepsAdmin {
+			admins, err := r.collection.CountDocuments(sessCtx, notDeleted(bson.M{"roles": string(utils.AdminRole)}))
+			if err != nil {
+				return nil, err
+			}
+			if admins == 0 {
+				return nil, ErrLastAdmin
+			}
+		}
+		return &updatedUser, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.User), nil
+}
+
+// Delete moves the user to the trash
+func (r *userRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, notDeleted(bson.M{"_id": id}), trashUpdate(deletedBy, time.Now()))
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// Restore takes the user out of the trash
+func (r *userRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": InTrash}, restoreUpdate)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 func (r *userRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]model.User, error) {
-	cursor, err := r.collection.Find(ctx, query, opts)
+	cursor, err := r.collection.Find(ctx, notDeletedD(query), opts)
 	if err != nil {
 		return nil, err
 	}
@@ -92,5 +210,16 @@ func (r *userRepository) FindAll(ctx context.Context, query bson.D, opts *option
 }
 
 func (r *userRepository) Count(ctx context.Context, query bson.D) (int64, error) {
-	return r.collection.CountDocuments(ctx, query)
+	return r.collection.CountDocuments(ctx, notDeletedD(query))
+}
+
+// MigrateEmailVerified marks the users registered before email verification
+// existed as verified, so turning verification on does not lock them out
+func (r *userRepository) MigrateEmailVerified(ctx context.Context) error {
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"email_verified": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"email_verified": true}},
+	)
+	return err
 }
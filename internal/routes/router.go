@@ -15,14 +15,23 @@ import (
 )
 
 type Application struct {
-	App              *fiber.App
-	UserHandler      *handlers.UserHandler
-	ShopHandler      *handlers.ShopHandler
-	CategoryHandler  *handlers.CategoryHandler
-	FileStoreHandler *handlers.FileStoreHandler
-	OtherHandler     *handlers.OtherHandler
-	AuthMiddleware   *middleware.AuthMiddleware
-	Config           *config.Config
+	App               *fiber.App
+	UserHandler       *handlers.UserHandler
+	ShopHandler       *handlers.ShopHandler
+	CategoryHandler   *handlers.CategoryHandler
+	FileStoreHandler  *handlers.FileStoreHandler
+	ProductHandler    *handlers.ProductHandler
+	OtherHandler      *handlers.OtherHandler
+	RoleHandler       *handlers.RoleHandler
+	ShopMemberHandler *handlers.ShopMemberHandler
+	BudgetHandler     *handlers.BudgetHandler
+	UploadHandler     *handlers.UploadHandler
+	TrashHandler      *handlers.TrashHandler
+	AuditHandler      *handlers.AuditHandler
+	AuthMiddleware    *middleware.AuthMiddleware
+	AuditMiddleware   *middleware.AuditMiddleware
+	RateLimiter       *middleware.RateLimiter
+	Config            *config.Config
 }
 
 func (app *Application) SetupRoutes() {
@@ -32,7 +41,9 @@ func (app *Application) SetupRoutes() {
 	// API routes
 	v1 := app.App.Group("/api/v1")
 	// Rate limit (You can use route by route)
-	v1.Use(middleware.RateLimit(100, time.Minute))
+	v1.Use(app.RateLimiter.Limit("api", 100, time.Minute))
+	// Writes are recorded in the audit log
+	v1.Use(app.AuditMiddleware.Record())
 
 	// Public routes
 	public := v1.Group("/")
@@ -41,12 +52,19 @@ func (app *Application) SetupRoutes() {
 	auth := v1.Group("/auth")
 	auth.Post("/register", app.UserHandler.Register)
 	auth.Post("/login", app.UserHandler.Login)
+	auth.Post("/password/forgot", app.RateLimiter.Limit("password_forgot", 5, time.Minute), app.UserHandler.ForgotPassword)
+	auth.Post("/password/reset", app.RateLimiter.Limit("password_reset", 10, time.Minute), app.UserHandler.ResetPassword)
+	auth.Post("/email/verify", app.UserHandler.VerifyEmail)
+	auth.Post("/email/verification/resend", app.RateLimiter.Limit("verification_resend", 5, time.Minute), app.UserHandler.ResendVerification)
 
 	// Other routes
 	other := public.Group("/other")
-	other.Use(middleware.RateLimit(20, time.Minute))
+	other.Use(app.RateLimiter.Limit("other", 20, time.Minute))
 	other.Get("/example/gallery", app.OtherHandler.GetListImages)
 
+	// Signed file links carry their own authorization
+	public.Get("/file/public/:file_id", app.FileStoreHandler.PublicDownload)
+
 	// Protected routes
 	private := v1.Group("/")
 	private.Use(app.AuthMiddleware.Protected())
@@ -54,31 +72,94 @@ func (app *Application) SetupRoutes() {
 	// User routes
 	users := private.Group("/user")
 	users.Get("/profile", app.UserHandler.GetProfile)
+	users.Get("/invitations", app.ShopMemberHandler.InvitationList)
+	users.Post("/invitations/:member_id/accept", app.ShopMemberHandler.AcceptInvitation)
+	users.Post("/invitations/:member_id/decline", app.ShopMemberHandler.DeclineInvitation)
 
 	// Auth routes
 	user := private.Group("/auth")
 	user.Get("/logout", app.UserHandler.Logout)
 	user.Post("/refresh", app.UserHandler.RefreshToken)
+	user.Post("/email/verification", app.RateLimiter.Limit("verification_request", 5, time.Minute), app.UserHandler.RequestVerification)
 
 	// Admin only routes
 	adminGroup := private.Group("/admin")
-	adminGroup.Use(app.AuthMiddleware.RequireRoles(utils.Role("admin")))
-	adminGroup.Get("/users", app.UserHandler.UserList)
-	adminGroup.Put("/user/:id", app.UserHandler.UpdateUser)
-	adminGroup.Delete("/user/:id", app.UserHandler.DeleteUser)
+	adminGroup.Get("/users", app.AuthMiddleware.RequirePermission(utils.UserRead), app.UserHandler.UserList)
+	adminGroup.Put("/user/:id", app.AuthMiddleware.RequirePermission(utils.UserUpdate), app.UserHandler.UpdateUser)
+	adminGroup.Put("/user/:id/verification", app.AuthMiddleware.RequirePermission(utils.UserUpdate), app.UserHandler.UpdateEmailVerified)
+	adminGroup.Put("/user/:id/roles", app.AuthMiddleware.RequirePermission(utils.UserAssignRoles), app.UserHandler.UpdateUserRoles)
+	adminGroup.Delete("/user/:id", app.AuthMiddleware.RequirePermission(utils.UserDelete), app.UserHandler.DeleteUser)
+	adminGroup.Get("/roles", app.AuthMiddleware.RequirePermission(utils.RoleManage), app.RoleHandler.RoleList)
+	adminGroup.Put("/roles/:name", app.AuthMiddleware.RequirePermission(utils.RoleManage), app.RoleHandler.SaveRole)
+	adminGroup.Put("/shop/:id/storage", app.AuthMiddleware.RequirePermission(utils.StorageManage), app.FileStoreHandler.UpdateStoragePlan)
+	adminGroup.Get("/storage/dedup", app.AuthMiddleware.RequirePermission(utils.StorageManage), app.FileStoreHandler.DedupReport)
+	adminGroup.Get("/files/quarantine", app.AuthMiddleware.RequirePermission(utils.StorageManage), app.FileStoreHandler.QuarantineList)
+	adminGroup.Get("/audit", app.AuthMiddleware.RequirePermission(utils.AuditRead), app.AuditHandler.AuditList)
+
+	// Trash routes
+	trash := adminGroup.Group("/trash", app.AuthMiddleware.RequirePermission(utils.TrashManage))
+	trash.Get("/", app.TrashHandler.TrashList)
+	trash.Post("/:type/:id/restore", app.TrashHandler.Restore)
+	trash.Delete("/:type/:id", app.TrashHandler.Purge)
 
 	// Shop routes
 	shops := private.Group("/shop")
 	shops.Get("/list", app.ShopHandler.ShopList)
-	shops.Post("/", app.ShopHandler.CreateShop)
+	shops.Post("/", app.AuthMiddleware.RequirePermission(utils.ShopCreate), app.ShopHandler.CreateShop)
 	shops.Get("/:id", app.ShopHandler.GetShop)
 	shops.Put("/:id", app.ShopHandler.UpdateShop)
 	shops.Delete("/:id", app.ShopHandler.DeleteShop)
 
+	// Shop member routes
+	members := shops.Group("/:id/members")
+	members.Get("/", app.ShopMemberHandler.MemberList)
+	members.Post("/", app.ShopMemberHandler.InviteMember)
+	members.Put("/:member_id", app.ShopMemberHandler.UpdateMember)
+	members.Delete("/:member_id", app.ShopMemberHandler.RemoveMember)
+
+	// Budget routes
+	budget := shops.Group("/:id/budget")
+	budget.Get("/transactions", app.BudgetHandler.TransactionList)
+	budget.Post("/credit", app.BudgetHandler.Credit)
+	budget.Post("/debit", app.BudgetHandler.Debit)
+
+	// Shop file routes
+	shopFiles := shops.Group("/:id/files")
+	shopFiles.Get("/", app.FileStoreHandler.FileList)
+	shopFiles.Get("/archive", app.FileStoreHandler.Archive)
+	shopFiles.Post("/", app.FileStoreHandler.AddFiles)
+	shopFiles.Put("/order", app.FileStoreHandler.ReorderFiles)
+	shopFiles.Put("/:file_id", app.FileStoreHandler.ReplaceFile)
+	shopFiles.Delete("/:file_id", app.FileStoreHandler.DeleteFile)
+	shopFiles.Post("/:file_id/link", app.FileStoreHandler.CreateLink)
+
+	shops.Get("/:id/usage", app.FileStoreHandler.StorageUsage)
+
+	// Resumable (tus) upload routes
+	uploads := shops.Group("/:id/uploads")
+	uploads.Options("/", app.UploadHandler.Options)
+	uploads.Post("/", app.UploadHandler.Create)
+	uploads.Head("/:upload_id", app.UploadHandler.Status)
+	uploads.Patch("/:upload_id", app.UploadHandler.Append)
+	uploads.Delete("/:upload_id", app.UploadHandler.Terminate)
+
+	shops.Get("/:id/categories", app.CategoryHandler.ShopCategories)
+	shops.Get("/:id/categories/tree", app.CategoryHandler.ShopCategoryTree)
+	shops.Put("/:id/categories/reorder", app.CategoryHandler.ReorderCategories)
+
+	// Product routes
+	products := shops.Group("/:id/products")
+	products.Get("/", app.ProductHandler.ProductList)
+	products.Post("/", app.ProductHandler.CreateProduct)
+	products.Get("/:product_id", app.ProductHandler.GetProduct)
+	products.Put("/:product_id", app.ProductHandler.UpdateProduct)
+	products.Delete("/:product_id", app.ProductHandler.DeleteProduct)
+
 	// Category routes
 	categories := private.Group("/category")
-	categories.Get("/list", app.CategoryHandler.GetAll)
 	categories.Post("/", app.CategoryHandler.Create)
+	categories.Put("/:id", app.CategoryHandler.UpdateCategory)
+	categories.Put("/:id/move", app.CategoryHandler.MoveCategory)
 	categories.Delete("/:id", app.CategoryHandler.DeleteCategory)
 
 	// file routes
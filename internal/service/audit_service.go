@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"log"
+	"reflect"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// auditIgnored are left out of the changes: bookkeeping fields and the
+// documents joined in by lookups
+var auditIgnored = map[string]bool{
+	"updated_at": true,
+	"user":       true,
+	"categories": true,
+	"files":      true,
+	"members":    true,
+}
+
+// auditQueueSize is how many entries wait for the background writer before
+// requests write their own
+const auditQueueSize = 1024
+
+type AuditService struct {
+	auditRepo repository.AuditRepository
+	mu        sync.RWMutex
+	queue     chan *model.AuditEntry // nil while the writer is not running
+	done      chan struct{}
+}
+
+func NewAuditService(auditRepo repository.AuditRepository) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+	}
+}
+
+func (s *AuditService) EnsureIndexes(ctx context.Context) error {
+	return s.auditRepo.EnsureIndexes(ctx)
+}
+
+// Start runs the background writer until Stop
+func (s *AuditService) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.queue != nil {
+		return
+	}
+	s.queue = make(chan *model.AuditEntry, auditQueueSize)
+	s.done = make(chan struct{})
+	go s.work(s.queue, s.done)
+}
+
+// Stop returns once the queued entries are stored, entries recorded
+// afterwards are written right away
+func (s *AuditService) Stop() {
+	s.mu.Lock()
+	queue, done := s.queue, s.done
+	s.queue = nil
+	s.mu.Unlock()
+
+	if queue != nil {
+		close(queue)
+		<-done
+	}
+}
+
+// Record queues entry for the background writer, requests do not wait for the
+// insert. Without a running writer, or with a full queue, the entry is written
+// right away rather than dropped. A failure is logged, it does not fail the
+// request the entry describes.
+func (s *AuditService) Record(entry *model.AuditEntry) {
+	s.mu.RLock()
+	if s.queue != nil {
+		select {
+		case s.queue <- entry:
+			s.mu.RUnlock()
+			return
+		default:
+		}
+	}
+	s.mu.RUnlock()
+
+	s.write(entry)
+}
+
+func (s *AuditService) work(queue <-chan *model.AuditEntry, done chan<- struct{}) {
+	defer close(done)
+	for entry := range queue {
+		s.write(entry)
+	}
+}
+
+func (s *AuditService) write(entry *model.AuditEntry) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.auditRepo.Create(ctx, entry); err != nil {
+		log.Printf("audit: failed to record %s %s: %v", entry.Method, entry.Path, err)
+	}
+}
+
+func (s *AuditService) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.AuditEntry, error) {
+	return s.auditRepo.FindAll(ctx, query, opts)
+}
+
+func (s *AuditService) Count(ctx context.Context, query bson.M) (int64, error) {
+	return s.auditRepo.Count(ctx, query)
+}
+
+// AuditChanges compares the JSON form of before and after field by field and
+// returns the fields that differ. Either side may be nil for a creation or a
+// deletion, fields hidden from JSON such as passwords never show up.
+func AuditChanges(before, after interface{}) map[string]model.AuditChange {
+	beforeFields, afterFields := auditFields(before), auditFields(after)
+	changes := map[string]model.AuditChange{}
+	for key, value := range beforeFields {
+		if auditIgnored[key] || reflect.DeepEqual(value, afterFields[key]) {
+			continue
+		}
+		changes[key] = model.AuditChange{Before: value, After: afterFields[key]}
+	}
+	for key, value := range afterFields {
+		if _, ok := beforeFields[key]; ok || auditIgnored[key] || value == nil {
+			continue
+		}
+		changes[key] = model.AuditChange{After: value}
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// auditFields decodes the JSON form of value, values that are not objects
+// become the single field "value"
+func auditFields(value interface{}) map[string]interface{} {
+	if value == nil {
+		return nil
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(encoded, &decoded); err != nil || decoded == nil {
+		return nil
+	}
+	if fields, ok := decoded.(map[string]interface{}); ok {
+		return fields
+	}
+	return map[string]interface{}{"value": decoded}
+}
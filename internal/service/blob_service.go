@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/storage"
+	"go-fiber-api/pkg/utils"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+)
+
+// blobPrefix is the storage key prefix of content addressed blobs
+const blobPrefix = "blobs/"
+
+// blobRetryDelay is how long Store waits for the deletion of a blob to finish
+const blobRetryDelay = 50 * time.Millisecond
+
+// BlobService stores file content once per SHA-256 hash in the default backend
+// and counts the FileStores referencing it
+type BlobService struct {
+	blobRepo repository.BlobRepository
+	backends *storage.Backends
+}
+
+func NewBlobService(blobRepo repository.BlobRepository, backends *storage.Backends) *BlobService {
+	return &BlobService{
+		blobRepo: blobRepo,
+		backends: backends,
+	}
+}
+
+func (s *BlobService) EnsureIndexes(ctx context.Context) error {
+	return s.blobRepo.EnsureIndexes(ctx)
+}
+
+// Store references the blob with hash, writing src to the default backend
+// only when the content is not stored yet. A blob being deleted is waited
+// for and then stored again.
+func (s *BlobService) Store(ctx context.Context, hash string, src io.Reader, size int64, contentType string) (string, string, error) {
+	store := s.backends.Default
+	for {
+		blob, err := s.blobRepo.Acquire(ctx, &model.Blob{
+			Hash:        hash,
+			Backend:     store.Name(),
+			Key:         BlobKey(hash),
+			Size:        size,
+			ContentType: contentType,
+		})
+		if err != nil {
+			return "", "", err
+		}
+		if blob == nil {
+			select {
+			case <-ctx.Done():
+				return "", "", ctx.Err()
+			case <-time.After(blobRetryDelay):
+			}
+			continue
+		}
+		if !blob.Pending {
+			return blob.Backend, blob.Key, nil
+		}
+
+		// The content may still be on its way from a concurrent Store of the
+		// same hash, writing the same bytes again is harmless
+		if err := store.Put(ctx, blob.Key, src, size, contentType); err != nil {
+			if releaseErr := s.Release(ctx, blob.Backend, blob.Key); releaseErr != nil {
+				log.Printf("blobs: release %s/%s after failed write: %v", blob.Backend, blob.Key, releaseErr)
+			}
+			return "", "", err
+		}
+		if err := s.blobRepo.MarkStored(ctx, blob.ID); err != nil {
+			return "", "", err
+		}
+		return blob.Backend, blob.Key, nil
+	}
+}
+
+// StoreBytes is Store for content already in memory
+func (s *BlobService) StoreBytes(ctx context.Context, data []byte, contentType string) (string, string, error) {
+	return s.Store(ctx, utils.HashBytes(data), bytes.NewReader(data), int64(len(data)), contentType)
+}
+
+// Release drops a reference to the content at key and deletes it with the
+// last one. Keys written before deduplication are not counted, they are
+// deleted right away.
+func (s *BlobService) Release(ctx context.Context, backend, key string) error {
+	store, err := s.backends.Get(backend)
+	if err != nil {
+		return err
+	}
+
+	blob, err := s.blobRepo.Release(ctx, store.Name(), key)
+	if err != nil {
+		return err
+	}
+	if blob == nil {
+		if strings.HasPrefix(key, blobPrefix) {
+			log.Printf("blobs: %s/%s released without a reference", store.Name(), key)
+			return nil
+		}
+		return store.Delete(ctx, key)
+	}
+	if blob.RefCount > 0 {
+		return nil
+	}
+
+	// The tombstone keeps a concurrent Store from referencing the content
+	// while it is deleted, it waits and writes the content again
+	marked, err := s.blobRepo.MarkDeleting(ctx, blob.ID)
+	if err != nil || !marked {
+		return err
+	}
+	if err := store.Delete(ctx, key); err != nil {
+		if unmarkErr := s.blobRepo.UnmarkDeleting(ctx, blob.ID); unmarkErr != nil {
+			log.Printf("blobs: unmark %s/%s after failed delete: %v", store.Name(), key, unmarkErr)
+		}
+		return err
+	}
+	return s.blobRepo.Remove(ctx, blob.ID)
+}
+
+// Stats reports how much space deduplication saves
+func (s *BlobService) Stats(ctx context.Context) (*model.BlobStats, error) {
+	return s.blobRepo.Stats(ctx)
+}
+
+// BlobKey is the storage key of the content with hash
+func BlobKey(hash string) string {
+	return path.Join(blobPrefix, hash[:2], hash)
+}
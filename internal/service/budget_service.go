@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type BudgetService struct {
+	budgetRepo repository.BudgetRepository
+}
+
+func NewBudgetService(budgetRepo repository.BudgetRepository) *BudgetService {
+	return &BudgetService{
+		budgetRepo: budgetRepo,
+	}
+}
+
+func (s *BudgetService) EnsureIndexes(ctx context.Context) error {
+	return s.budgetRepo.EnsureIndexes(ctx)
+}
+
+func (s *BudgetService) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.BudgetTransaction, error) {
+	return s.budgetRepo.FindAll(ctx, query, opts)
+}
+
+func (s *BudgetService) Count(ctx context.Context, query bson.M) (int64, error) {
+	return s.budgetRepo.Count(ctx, query)
+}
+
+func (s *BudgetService) Credit(ctx context.Context, shop *model.Shop, actor *model.User, amount int64, reason, idempotencyKey string) (*model.BudgetTransaction, error) {
+	return s.apply(ctx, shop, actor, model.BudgetCredit, amount, reason, idempotencyKey)
+}
+
+func (s *BudgetService) Debit(ctx context.Context, shop *model.Shop, actor *model.User, amount int64, reason, idempotencyKey string) (*model.BudgetTransaction, error) {
+	return s.apply(ctx, shop, actor, model.BudgetDebit, amount, reason, idempotencyKey)
+}
+
+// RecordOpening writes the balance a shop was created with as its first ledger entry
+func (s *BudgetService) RecordOpening(ctx context.Context, shop *model.Shop, actor *model.User) (*model.BudgetTransaction, error) {
+	return s.budgetRepo.Record(ctx, &model.BudgetTransaction{
+		ShopID:       shop.ID,
+		Type:         model.BudgetCredit,
+		Amount:       shop.Budget,
+		BalanceAfter: shop.Budget,
+		Reason:       "Opening balance",
+		ActorID:      actor.ID,
+	})
+}
+
+func (s *BudgetService) apply(ctx context.Context, shop *model.Shop, actor *model.User, kind string, amount int64, reason, idempotencyKey string) (*model.BudgetTransaction, error) {
+	if amount <= 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Amount must be greater than zero")
+	}
+
+	entry, err := s.budgetRepo.Apply(ctx, &model.BudgetTransaction{
+		ShopID:         shop.ID,
+		Type:           kind,
+		Amount:         amount,
+		Reason:         reason,
+		ActorID:        actor.ID,
+		IdempotencyKey: idempotencyKey,
+	})
+	switch {
+	case errors.Is(err, repository.ErrInsufficientBudget):
+		return nil, fiber.NewError(fiber.StatusConflict, "Insufficient budget")
+	case errors.Is(err, repository.ErrIdempotencyReused):
+		return nil, fiber.NewError(fiber.StatusConflict, err.Error())
+	case mongo.IsDuplicateKeyError(err):
+		return nil, fiber.NewError(fiber.StatusConflict, "A transaction with this idempotency key is already being processed")
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return nil, fiber.NewError(fiber.StatusNotFound, "Failed to find shop")
+	}
+	return entry, err
+}
@@ -2,11 +2,18 @@ package service
 
 import (
 	"context"
+	"errors"
 	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/repository"
 	"go-fiber-api/pkg/dto"
+	"regexp"
+	"strings"
 
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type CategoryService struct {
@@ -19,23 +26,221 @@ func NewCategoryService(categoryRepo repository.CategoryRepository) *CategorySer
 	}
 }
 
+// EnsureIndexes creates the category indexes and turns categories from before
+// nesting into roots
+func (s *CategoryService) EnsureIndexes(ctx context.Context) error {
+	if err := s.categoryRepo.MigrateTree(ctx); err != nil {
+		return err
+	}
+	return s.categoryRepo.EnsureIndexes(ctx)
+}
+
+// Create adds a category to shop, as the last child of its parent when one is given
 func (s *CategoryService) Create(ctx context.Context, payload *dto.CategoryRequest, shop *model.Shop) (*model.Category, error) {
+	parent, err := s.findParent(ctx, shop.ID, payload.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
 	category := &model.Category{
-		Name:   payload.Name,
+		ID:     primitive.NewObjectID(),
+		Name:   strings.TrimSpace(payload.Name),
 		ShopID: shop.ID,
 	}
+	var parentPath string
+	if parent != nil {
+		category.ParentID, parentPath = &parent.ID, parent.Path
+	}
+	category.Path = model.CategoryPath(parentPath, category.ID)
+	if category.Position, err = s.categoryRepo.NextPosition(ctx, shop.ID, category.ParentID); err != nil {
+		return nil, err
+	}
 
 	createdCategory, err := s.categoryRepo.Create(ctx, category)
 	if err != nil {
-		return nil, err
+		return nil, categoryError(err)
 	}
 	return createdCategory, nil
 }
 
-func (s *CategoryService) FindAll(ctx context.Context) ([]model.Category, error) {
-	return s.categoryRepo.List(ctx)
+// Update renames a category within its shop, categories never move to another shop
+func (s *CategoryService) Update(ctx context.Context, category *model.Category, payload *dto.UpdateCategoryRequest) (*model.Category, error) {
+	if payload.ShopId != "" && payload.ShopId != category.ShopID.Hex() {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "A category cannot be moved to another shop")
+	}
+
+	set := bson.M{}
+	if name := strings.TrimSpace(payload.Name); name != "" {
+		set["name"] = name
+	}
+	if len(set) == 0 {
+		return category, nil
+	}
+
+	updated, err := s.categoryRepo.UpdateByID(ctx, category.ID, set)
+	if err != nil {
+		return nil, categoryError(err)
+	}
+	return updated, nil
+}
+
+func (s *CategoryService) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Category, error) {
+	return s.categoryRepo.FindAll(ctx, query, opts)
+}
+
+func (s *CategoryService) Count(ctx context.Context, query bson.M) (int64, error) {
+	return s.categoryRepo.Count(ctx, query)
+}
+
+func (s *CategoryService) FindByID(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	return s.categoryRepo.Get(ctx, id)
+}
+
+// Tree returns the categories of a shop as nested nodes, children in order
+func (s *CategoryService) Tree(ctx context.Context, shopID primitive.ObjectID) ([]*dto.CategoryNode, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+	categories, err := s.categoryRepo.FindAll(ctx, bson.M{"shop_id": shopID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[primitive.ObjectID]*dto.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &dto.CategoryNode{Category: category, Children: []*dto.CategoryNode{}}
+	}
+	roots := []*dto.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		// A category whose parent is gone is shown as a root rather than lost
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	return roots, nil
+}
+
+// Move puts category with its subtree below another parent of the same shop,
+// or makes it a root. A category cannot move below itself or its descendants.
+func (s *CategoryService) Move(ctx context.Context, category *model.Category, payload *dto.MoveCategoryRequest) (*model.Category, error) {
+	parent, err := s.findParent(ctx, category.ShopID, payload.ParentId)
+	if err != nil {
+		return nil, err
+	}
+
+	var parentID *primitive.ObjectID
+	var parentPath string
+	if parent != nil {
+		if strings.HasPrefix(parent.Path, category.Path) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "A category cannot be moved below itself or one of its subcategories")
+		}
+		parentID, parentPath = &parent.ID, parent.Path
+	}
+
+	position := 0
+	if payload.Position != nil {
+		position = *payload.Position
+	} else if position, err = s.categoryRepo.NextPosition(ctx, category.ShopID, parentID); err != nil {
+		return nil, err
+	}
+
+	if err := s.categoryRepo.Move(ctx, category, parentID, model.CategoryPath(parentPath, category.ID), position); err != nil {
+		return nil, err
+	}
+	return s.categoryRepo.Get(ctx, category.ID)
+}
+
+// Reorder sets the order of the children of a parent, or of the roots, ids must
+// list every one of them exactly once
+func (s *CategoryService) Reorder(ctx context.Context, shop *model.Shop, payload *dto.ReorderCategoriesRequest) ([]model.Category, error) {
+	parent, err := s.findParent(ctx, shop.ID, payload.ParentId)
+	if err != nil {
+		return nil, err
+	}
+	var parentID *primitive.ObjectID
+	if parent != nil {
+		parentID = &parent.ID
+	}
+
+	siblings, err := s.children(ctx, shop.ID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if len(payload.CategoryIDs) != len(siblings) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Category IDs must list every category of the parent")
+	}
+	known := make(map[primitive.ObjectID]bool, len(siblings))
+	for _, sibling := range siblings {
+		known[sibling.ID] = true
+	}
+	for _, id := range payload.CategoryIDs {
+		if !known[id] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Category IDs must list every category of the parent exactly once")
+		}
+		delete(known, id)
+	}
+
+	if err := s.categoryRepo.Reorder(ctx, shop.ID, payload.CategoryIDs); err != nil {
+		return nil, err
+	}
+	return s.children(ctx, shop.ID, parentID)
+}
+
+// Delete moves a category to the trash. With subcategories it is refused unless
+// cascade is set, then the whole subtree goes and is restored together. It
+// returns how many categories were deleted.
+func (s *CategoryService) Delete(ctx context.Context, category *model.Category, cascade bool, deletedBy primitive.ObjectID) (int64, error) {
+	if !cascade {
+		children, err := s.categoryRepo.Count(ctx, bson.M{"shop_id": category.ShopID, "parent_id": category.ID})
+		if err != nil {
+			return 0, err
+		}
+		if children > 0 {
+			return 0, fiber.NewError(fiber.StatusConflict, "Category has subcategories, delete them first or use cascade=true")
+		}
+		return s.categoryRepo.Delete(ctx, bson.M{"_id": category.ID}, deletedBy)
+	}
+	return s.categoryRepo.Delete(ctx, SubtreeQuery(category), deletedBy)
+}
+
+// SubtreeQuery matches category and all its descendants
+func SubtreeQuery(category *model.Category) bson.M {
+	return bson.M{
+		"shop_id": category.ShopID,
+		"path":    primitive.Regex{Pattern: "^" + regexp.QuoteMeta(category.Path)},
+	}
 }
 
-func (s *CategoryService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.categoryRepo.Delete(ctx, id)
+// findParent loads the category parentId of the shop, nil when parentId is empty
+func (s *CategoryService) findParent(ctx context.Context, shopID primitive.ObjectID, parentId string) (*model.Category, error) {
+	if parentId == "" {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(parentId)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid parent ID format")
+	}
+	parent, err := s.categoryRepo.Get(ctx, id)
+	if err != nil || parent == nil || parent.ShopID != shopID {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Parent category must belong to the same shop")
+	}
+	return parent, nil
+}
+
+func (s *CategoryService) children(ctx context.Context, shopID primitive.ObjectID, parentID *primitive.ObjectID) ([]model.Category, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "name", Value: 1}})
+	return s.categoryRepo.FindAll(ctx, bson.M{"shop_id": shopID, "parent_id": parentID}, opts)
+}
+
+func categoryError(err error) error {
+	switch {
+	case mongo.IsDuplicateKeyError(err):
+		return fiber.NewError(fiber.StatusConflict, "The shop already has a category with this name")
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return fiber.NewError(fiber.StatusNotFound, "Failed to find category")
+	}
+	return err
 }
@@ -0,0 +1,295 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/dto"
+	"log"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// PolicyCascade deletes the related documents along
+	PolicyCascade = "cascade"
+	// PolicyReassign hands the related documents over to another user
+	PolicyReassign = "reassign"
+	// PolicyBlock refuses the deletion while related documents exist
+	PolicyBlock = "block"
+)
+
+const (
+	RelationShopCategories   = "shop.categories"
+	RelationShopProducts     = "shop.products"
+	RelationShopFiles        = "shop.files"
+	RelationShopMembers      = "shop.members"
+	RelationShopBudget       = "shop.budget_transactions"
+	RelationUserShops        = "user.shops"
+	RelationUserMemberships  = "user.memberships"
+	RelationCategoryChildren = "category.children"
+)
+
+// deletionRelation is a collection referencing a shop or a user, with the
+// policies it supports, the first one is the default
+type deletionRelation struct {
+	collection string
+	policies   []string
+}
+
+var deletionRelations = map[string]deletionRelation{
+	RelationShopCategories:   {"categories", []string{PolicyCascade, PolicyBlock}},
+	RelationShopProducts:     {"products", []string{PolicyCascade, PolicyBlock}},
+	RelationShopFiles:        {"file_stores", []string{PolicyCascade, PolicyBlock}},
+	RelationShopMembers:      {"shop_members", []string{PolicyCascade, PolicyBlock}},
+	RelationShopBudget:       {"budget_transactions", []string{PolicyCascade, PolicyBlock}},
+	RelationUserShops:        {"shops", []string{PolicyBlock, PolicyCascade, PolicyReassign}},
+	RelationUserMemberships:  {"shop_members", []string{PolicyCascade, PolicyBlock}},
+	RelationCategoryChildren: {"categories", []string{PolicyCascade, PolicyBlock}},
+}
+
+// shopRelations are applied in order, before the shops themselves
+var shopRelations = []string{RelationShopCategories, RelationShopProducts, RelationShopFiles, RelationShopMembers, RelationShopBudget}
+
+// DeletionPolicies maps each relation to its policy
+type DeletionPolicies map[string]string
+
+// ParseDeletionPolicies reads relation:policy items over the defaults
+func ParseDeletionPolicies(items []string) (DeletionPolicies, error) {
+	policies := DeletionPolicies{}
+	for name, relation := range deletionRelations {
+		policies[name] = relation.policies[0]
+	}
+	return policies.With(items)
+}
+
+// With returns a copy of the policies with relation:policy items applied
+func (p DeletionPolicies) With(items []string) (DeletionPolicies, error) {
+	policies := make(DeletionPolicies, len(p))
+	for name, policy := range p {
+		policies[name] = policy
+	}
+	for _, item := range items {
+		name, policy, _ := strings.Cut(strings.TrimSpace(item), ":")
+		relation, ok := deletionRelations[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown deletion relation %q", name)
+		}
+		if !slices.Contains(relation.policies, policy) {
+			return nil, fmt.Errorf("relation %s supports %s, not %q", name, strings.Join(relation.policies, ", "), policy)
+		}
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// DeletionService deletes shops, users and categories with their related documents in one
+// transaction, following the policy configured for each relation. File content
+// is released once the transaction is committed.
+type DeletionService struct {
+	deletionRepo repository.DeletionRepository
+	blobs        *BlobService
+	policies     DeletionPolicies
+}
+
+func NewDeletionService(deletionRepo repository.DeletionRepository, blobs *BlobService, cfg *config.Config) (*DeletionService, error) {
+	policies, err := ParseDeletionPolicies(cfg.DeletionPolicies)
+	if err != nil {
+		return nil, err
+	}
+	return &DeletionService{
+		deletionRepo: deletionRepo,
+		blobs:        blobs,
+		policies:     policies,
+	}, nil
+}
+
+// DeleteShop deletes the shop with its related documents. A dry run only reports them.
+func (s *DeletionService) DeleteShop(ctx context.Context, shop *model.Shop, dryRun bool) (*dto.DeletionReport, error) {
+	report := &dto.DeletionReport{Resource: "shop", ID: shop.ID, DryRun: dryRun}
+	shopIDs := []primitive.ObjectID{shop.ID}
+
+	var files []model.FileStore
+	err := s.run(ctx, report, func(ctx context.Context) error {
+		if err := s.planShops(ctx, report, s.policies, shopIDs); err != nil {
+			return err
+		}
+		if dryRun || len(report.Blocked) > 0 {
+			return nil
+		}
+		var err error
+		files, err = s.deleteShops(ctx, shopIDs)
+		return err
+	})
+	if err != nil {
+		return report, err
+	}
+	s.releaseFiles(files)
+	return report, nil
+}
+
+// DeleteCategory deletes the category with its subcategories. A dry run only reports them.
+func (s *DeletionService) DeleteCategory(ctx context.Context, category *model.Category, dryRun bool) (*dto.DeletionReport, error) {
+	report := &dto.DeletionReport{Resource: "category", ID: category.ID, DryRun: dryRun}
+	subtree := SubtreeQuery(category)
+
+	err := s.run(ctx, report, func(ctx context.Context) error {
+		count, err := s.deletionRepo.Count(ctx, "categories", subtree)
+		if err != nil {
+			return err
+		}
+		// The subtree includes the category itself
+		s.plan(report, s.policies, RelationCategoryChildren, max(count-1, 0))
+		if dryRun || len(report.Blocked) > 0 {
+			return nil
+		}
+		_, err = s.deletionRepo.DeleteMany(ctx, "categories", subtree)
+		return err
+	})
+	return report, err
+}
+
+// DeleteUser deletes the user with its related documents, overrides adjusts
+// the configured policies for this deletion. Reassigned shops go to newOwner.
+func (s *DeletionService) DeleteUser(ctx context.Context, user *model.User, newOwner *model.User, overrides []string, dryRun bool) (*dto.DeletionReport, error) {
+	policies, err := s.policies.With(overrides)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, err.Error())
+	}
+	if policies[RelationUserShops] == PolicyReassign && (newOwner == nil || newOwner.ID == user.ID) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Reassigning shops needs another user to own them")
+	}
+
+	report := &dto.DeletionReport{Resource: "user", ID: user.ID, DryRun: dryRun}
+	email := strings.ToLower(user.Email)
+	membershipQuery := bson.M{"$or": bson.A{bson.M{"user_id": user.ID}, bson.M{"email": email}}}
+
+	var files []model.FileStore
+	err = s.run(ctx, report, func(ctx context.Context) error {
+		shopIDs, err := s.deletionRepo.FindIDs(ctx, "shops", bson.M{"created_by": user.ID})
+		if err != nil {
+			return err
+		}
+		s.plan(report, policies, RelationUserShops, int64(len(shopIDs)))
+		if policies[RelationUserShops] == PolicyCascade {
+			if err := s.planShops(ctx, report, policies, shopIDs); err != nil {
+				return err
+			}
+		}
+		if policies[RelationUserShops] == PolicyReassign && len(shopIDs) > 0 {
+			report.NewOwner = &newOwner.ID
+		}
+
+		memberships, err := s.deletionRepo.Count(ctx, "shop_members", membershipQuery)
+		if err != nil {
+			return err
+		}
+		s.plan(report, policies, RelationUserMemberships, memberships)
+
+		if dryRun || len(report.Blocked) > 0 {
+			return nil
+		}
+
+		if _, err := s.deletionRepo.DeleteMany(ctx, "shop_members", membershipQuery); err != nil {
+			return err
+		}
+		switch policies[RelationUserShops] {
+		case PolicyCascade:
+			if files, err = s.deleteShops(ctx, shopIDs); err != nil {
+				return err
+			}
+		case PolicyReassign:
+			if _, err := s.deletionRepo.UpdateMany(ctx, "shops", bson.M{"_id": bson.M{"$in": shopIDs}}, bson.M{"created_by": newOwner.ID}); err != nil {
+				return err
+			}
+			for _, shopID := range shopIDs {
+				if err := s.deletionRepo.UpsertOwner(ctx, shopID, newOwner, newOwner.ID); err != nil {
+					return err
+				}
+			}
+		}
+		_, err = s.deletionRepo.DeleteMany(ctx, "users", bson.M{"_id": user.ID})
+		return err
+	})
+	if err != nil {
+		return report, err
+	}
+	s.releaseFiles(files)
+	return report, nil
+}
+
+// run executes fn, inside a transaction unless it is a dry run, and refuses
+// blocked deletions
+func (s *DeletionService) run(ctx context.Context, report *dto.DeletionReport, fn func(ctx context.Context) error) error {
+	var err error
+	if report.DryRun {
+		err = fn(ctx)
+	} else {
+		err = s.deletionRepo.WithTransaction(ctx, fn)
+	}
+	if err != nil {
+		return err
+	}
+	if len(report.Blocked) > 0 && !report.DryRun {
+		return fiber.NewError(fiber.StatusConflict, "Deletion is blocked by "+strings.Join(report.Blocked, ", "))
+	}
+	report.Deleted = !report.DryRun
+	return nil
+}
+
+// plan records count documents of relation, blocking the deletion when its policy says so
+func (s *DeletionService) plan(report *dto.DeletionReport, policies DeletionPolicies, relation string, count int64) {
+	policy := policies[relation]
+	report.Relations = append(report.Relations, dto.DeletionRelation{Relation: relation, Policy: policy, Count: count})
+	if policy == PolicyBlock && count > 0 {
+		report.Blocked = append(report.Blocked, relation)
+	}
+}
+
+func (s *DeletionService) planShops(ctx context.Context, report *dto.DeletionReport, policies DeletionPolicies, shopIDs []primitive.ObjectID) error {
+	for _, relation := range shopRelations {
+		count, err := s.deletionRepo.Count(ctx, deletionRelations[relation].collection, bson.M{"shop_id": bson.M{"$in": shopIDs}})
+		if err != nil {
+			return err
+		}
+		s.plan(report, policies, relation, count)
+	}
+	return nil
+}
+
+// deleteShops removes the shops with everything referencing them and returns
+// the deleted files, their content is still to be released
+func (s *DeletionService) deleteShops(ctx context.Context, shopIDs []primitive.ObjectID) ([]model.FileStore, error) {
+	if len(shopIDs) == 0 {
+		return nil, nil
+	}
+	query := bson.M{"shop_id": bson.M{"$in": shopIDs}}
+	files, err := s.deletionRepo.FindFiles(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	for _, relation := range shopRelations {
+		if _, err := s.deletionRepo.DeleteMany(ctx, deletionRelations[relation].collection, query); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.deletionRepo.DeleteMany(ctx, "shops", bson.M{"_id": bson.M{"$in": shopIDs}}); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// releaseFiles gives back the content of deleted files, failures are left to the reconciliation
+func (s *DeletionService) releaseFiles(files []model.FileStore) {
+	for _, file := range files {
+		if err := s.blobs.Release(context.Background(), file.Backend, file.Key); err != nil {
+			log.Printf("deletion: failed to release %s of file %s: %v", file.Key, file.ID.Hex(), err)
+		}
+	}
+}
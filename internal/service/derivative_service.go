@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/imaging"
+	"go-fiber-api/pkg/storage"
+	"go-fiber-api/pkg/utils"
+	"io"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const derivativeJobTimeout = time.Minute
+
+// DerivativeService renders the configured variants of uploaded images in
+// background workers and records each one as a child FileStore
+type DerivativeService struct {
+	fileStoreRepo repository.FileStoreRepository
+	blobs         *BlobService
+	backends      *storage.Backends
+	quota         *QuotaService
+	variants      []imaging.Variant
+	workers       int
+	maxPixels     int64
+	jobs          chan primitive.ObjectID
+}
+
+func NewDerivativeService(fileStoreRepo repository.FileStoreRepository, blobs *BlobService, backends *storage.Backends, quota *QuotaService, cfg *config.Config) (*DerivativeService, error) {
+	variants, err := imaging.ParseVariants(cfg.ImageVariants)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := cfg.ImageWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	return &DerivativeService{
+		fileStoreRepo: fileStoreRepo,
+		blobs:         blobs,
+		backends:      backends,
+		quota:         quota,
+		variants:      variants,
+		workers:       workers,
+		maxPixels:     cfg.ImageMaxPixels,
+		jobs:          make(chan primitive.ObjectID, 256),
+	}, nil
+}
+
+// Start runs the workers until ctx is done and queues the images left pending
+// by a previous run
+func (s *DerivativeService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+
+	pending, err := s.fileStoreRepo.FindAll(ctx, bson.M{"derivatives": model.DerivativesPending})
+	if err != nil {
+		log.Printf("derivatives: failed to load pending images: %v", err)
+		return
+	}
+	for _, file := range pending {
+		s.queue(file.ID)
+	}
+}
+
+// Wants reports whether file is an original image the workers process. Its
+// metadata is stripped even without variants configured.
+func (s *DerivativeService) Wants(file *model.FileStore) bool {
+	return file.ParentID == nil && imaging.Supported(file.ContentType)
+}
+
+// Enqueue marks the images among files as pending and queues them. A full queue
+// leaves them pending until the next start.
+func (s *DerivativeService) Enqueue(ctx context.Context, files ...*model.FileStore) {
+	for _, file := range files {
+		if !s.Wants(file) {
+			continue
+		}
+		if file.Derivatives != model.DerivativesPending {
+			if _, err := s.fileStoreRepo.Update(ctx, file.ID, bson.M{"derivatives": model.DerivativesPending}); err != nil {
+				log.Printf("derivatives: failed to mark %s pending: %v", file.ID.Hex(), err)
+				continue
+			}
+			file.Derivatives = model.DerivativesPending
+		}
+		s.queue(file.ID)
+	}
+}
+
+func (s *DerivativeService) queue(id primitive.ObjectID) {
+	select {
+	case s.jobs <- id:
+	default:
+		log.Printf("derivatives: queue is full, %s stays pending", id.Hex())
+	}
+}
+
+func (s *DerivativeService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.jobs:
+			jobCtx, cancel := context.WithTimeout(ctx, derivativeJobTimeout)
+			if err := s.process(jobCtx, id); err != nil {
+				log.Printf("derivatives: %s failed: %v", id.Hex(), err)
+				_, _ = s.fileStoreRepo.Update(jobCtx, id, bson.M{"derivatives": model.DerivativesFailed})
+			}
+			cancel()
+		}
+	}
+}
+
+// process strips the metadata of the original and renders every variant
+func (s *DerivativeService) process(ctx context.Context, id primitive.ObjectID) error {
+	file, err := s.fileStoreRepo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	store, err := s.backends.Get(file.Backend)
+	if err != nil {
+		return err
+	}
+	reader, _, err := store.Get(ctx, file.Key)
+	if err != nil {
+		return err
+	}
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	// Downloads wait for the stripped original, so it is written even when the
+	// image cannot be decoded for the variants
+	img, decodeErr := imaging.Decode(data, s.maxPixels)
+
+	// Rotated photos are re-encoded upright, since stripping drops the orientation tag
+	update := bson.M{}
+	clean := imaging.StripMetadata(data)
+	if decodeErr == nil && imaging.Orientation(data) > 1 {
+		if clean, err = imaging.Render(img, imaging.Variant{Format: imaging.FormatJPEG}); err != nil {
+			return err
+		}
+	}
+	// The original may be shared with other files, the clean copy is a blob of its own.
+	// Every write is conditioned on the content read, a replace landing meanwhile
+	// queued the new content and wins.
+	hash := file.Hash
+	if !bytes.Equal(clean, data) {
+		backend, key, err := s.blobs.StoreBytes(ctx, clean, file.ContentType)
+		if err != nil {
+			return err
+		}
+		cleanHash := utils.HashBytes(clean)
+		if _, err := s.fileStoreRepo.UpdateWhere(ctx, sameContent(file.ID, hash), bson.M{
+			"backend": backend,
+			"key":     key,
+			"size":    int64(len(clean)),
+			"hash":    cleanHash,
+		}); err != nil {
+			_ = s.blobs.Release(ctx, backend, key)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return nil
+			}
+			return err
+		}
+		hash = cleanHash
+		_ = s.blobs.Release(ctx, file.Backend, file.Key)
+		_ = s.quota.Adjust(ctx, file.ShopID, int64(len(clean))-file.Size)
+	}
+
+	if decodeErr != nil {
+		return decodeErr
+	}
+	if err := DeleteDerivatives(ctx, s.fileStoreRepo, s.blobs, file); err != nil {
+		return err
+	}
+
+	var children []*model.FileStore
+	names := []string{}
+	for _, variant := range s.variants {
+		out, err := imaging.Render(img, variant)
+		if err != nil {
+			return err
+		}
+
+		backend, key, err := s.blobs.StoreBytes(ctx, out, variant.ContentType())
+		if err != nil {
+			return err
+		}
+
+		parentID := file.ID
+		name := strings.TrimSuffix(file.Name, path.Ext(file.Name))
+		originalName := strings.TrimSuffix(file.OriginalName, path.Ext(file.OriginalName))
+		children = append(children, &model.FileStore{
+			ID:           primitive.NewObjectID(),
+			Name:         name + "_" + variant.Name + variant.Extension(),
+			OriginalName: originalName + "_" + variant.Name + variant.Extension(),
+			Backend:      backend,
+			Key:          key,
+			Extension:    variant.Extension(),
+			Size:         int64(len(out)),
+			ContentType:  variant.ContentType(),
+			Hash:         utils.HashBytes(out),
+			ShopID:       file.ShopID,
+			UploadedBy:   file.UploadedBy,
+			ParentID:     &parentID,
+			Variant:      variant.Name,
+			ScanStatus:   file.ScanStatus,
+			ScannedBy:    file.ScannedBy,
+			ScannedAt:    file.ScannedAt,
+		})
+		names = append(names, variant.Name)
+	}
+
+	if len(children) > 0 {
+		if _, err := s.fileStoreRepo.Create(ctx, children); err != nil {
+			for _, child := range children {
+				_ = s.blobs.Release(ctx, child.Backend, child.Key)
+			}
+			return err
+		}
+	}
+
+	update["derivatives"] = model.DerivativesReady
+	update["variants"] = names
+	if _, err := s.fileStoreRepo.UpdateWhere(ctx, sameContent(file.ID, hash), update); err != nil {
+		// The variants are of the replaced content, the ones of the new content are left alone
+		ids := make([]primitive.ObjectID, len(children))
+		for i, child := range children {
+			ids[i] = child.ID
+			_ = s.blobs.Release(ctx, child.Backend, child.Key)
+		}
+		if cleanupErr := s.fileStoreRepo.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); cleanupErr != nil {
+			log.Printf("derivatives: failed to delete stale variants of %s: %v", file.ID.Hex(), cleanupErr)
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// sameContent matches the file while it still has the content with hash, files
+// stored before hashing have none
+func sameContent(id primitive.ObjectID, hash string) bson.M {
+	if hash == "" {
+		return bson.M{"_id": id, "hash": nil}
+	}
+	return bson.M{"_id": id, "hash": hash}
+}
+
+// DeleteDerivatives removes the variants of file and releases their content
+func DeleteDerivatives(ctx context.Context, fileStoreRepo repository.FileStoreRepository, blobs *BlobService, file *model.FileStore) error {
+	children, err := fileStoreRepo.FindAll(ctx, bson.M{"parent_id": file.ID})
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := blobs.Release(ctx, child.Backend, child.Key); err != nil {
+			return err
+		}
+	}
+	return fileStoreRepo.DeleteMany(ctx, bson.M{"parent_id": file.ID})
+}
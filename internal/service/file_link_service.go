@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/signer"
+	"go-fiber-api/pkg/utils"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	errLinkUsed      = fiber.NewError(fiber.StatusGone, "Link has already been used")
+	errLinksDisabled = fiber.NewError(fiber.StatusServiceUnavailable, "Signed links are disabled")
+)
+
+// FileLinkService issues and checks signed download links, so files can be
+// fetched without a bearer token (e.g. from an <img> tag). Without signing keys
+// it is disabled and refuses to issue or accept links.
+type FileLinkService struct {
+	signer      *signer.Signer
+	redisClient *redis.Client
+	maxExpiry   time.Duration
+}
+
+func NewFileLinkService(redisClient *redis.Client, cfg *config.Config) (*FileLinkService, error) {
+	maxExpiry, err := time.ParseDuration(cfg.FileLinkMaxExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := signer.ParseKeys(cfg.FileSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		log.Println("file links: FILE_SIGNING_KEYS is not set, signed download links are disabled")
+		return &FileLinkService{redisClient: redisClient, maxExpiry: maxExpiry}, nil
+	}
+
+	s, err := signer.New(keys)
+	if err != nil {
+		return nil, err
+	}
+	return &FileLinkService{signer: s, redisClient: redisClient, maxExpiry: maxExpiry}, nil
+}
+
+// Issue returns the signed query string granting access to file until the returned time
+func (s *FileLinkService) Issue(file *model.FileStore, variant string, expiresIn time.Duration, singleUse bool) (string, time.Time, error) {
+	if s.signer == nil {
+		return "", time.Time{}, errLinksDisabled
+	}
+	if expiresIn <= 0 || expiresIn > s.maxExpiry {
+		return "", time.Time{}, fiber.NewError(fiber.StatusBadRequest, "Link expiry must be between 1 second and "+s.maxExpiry.String())
+	}
+
+	claims := signer.Claims{
+		Resource:  file.ID.Hex(),
+		Variant:   variant,
+		ExpiresAt: time.Now().Add(expiresIn).Truncate(time.Second),
+		SingleUse: singleUse,
+	}
+	if singleUse {
+		nonce, err := utils.GenerateSecureToken(16)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		claims.Nonce = nonce
+	}
+	return s.signer.Sign(claims).Encode(), claims.ExpiresAt, nil
+}
+
+// Verify checks a signed link for fileID without using it up. A single-use
+// link fails once it has been used.
+func (s *FileLinkService) Verify(ctx context.Context, fileID string, values url.Values) (*signer.Claims, error) {
+	if s.signer == nil {
+		return nil, errLinksDisabled
+	}
+	claims, err := s.signer.Verify(fileID, values, time.Now())
+	if errors.Is(err, signer.ErrExpired) {
+		return nil, fiber.NewError(fiber.StatusGone, "Link has expired")
+	}
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Invalid link signature")
+	}
+
+	if claims.SingleUse {
+		used, err := s.redisClient.Exists(ctx, linkUsedKey(claims.Nonce)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if used > 0 {
+			return nil, errLinkUsed
+		}
+	}
+	return claims, nil
+}
+
+// Consume uses up a single-use link. It is called right before any content,
+// whole or ranged, is sent, so HEAD and conditional requests leave the link usable.
+func (s *FileLinkService) Consume(ctx context.Context, fileID string, claims *signer.Claims) error {
+	if !claims.SingleUse {
+		return nil
+	}
+	ttl := time.Until(claims.ExpiresAt) + time.Minute
+	first, err := s.redisClient.SetNX(ctx, linkUsedKey(claims.Nonce), fileID, ttl).Result()
+	if err != nil {
+		return err
+	}
+	if !first {
+		return errLinkUsed
+	}
+	return nil
+}
+
+func linkUsedKey(nonce string) string {
+	return "file_link_used:" + nonce
+}
@@ -1,60 +1,348 @@
 package service
 
 import (
+	"archive/zip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-fiber-api/internal/config"
 	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/repository"
 	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/storage"
 	"go-fiber-api/pkg/utils"
+	"io"
 	"mime/multipart"
+	"path"
+	"strings"
+	"time"
 
+	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// ArchiveManifestName is the name of the manifest inside shop file archives
+const ArchiveManifestName = "manifest.json"
+
 type FileStoreService struct {
 	fileStoreRepo repository.FileStoreRepository
+	blobs         *BlobService
+	backends      *storage.Backends
+	scans         *ScanService
+	quota         *QuotaService
+	limits        utils.UploadLimits
 }
 
-func NewFileStoreService(fileStoreRepo repository.FileStoreRepository) *FileStoreService {
+func NewFileStoreService(fileStoreRepo repository.FileStoreRepository, blobs *BlobService, backends *storage.Backends, scans *ScanService, quota *QuotaService, cfg *config.Config) *FileStoreService {
 	return &FileStoreService{
 		fileStoreRepo: fileStoreRepo,
+		blobs:         blobs,
+		backends:      backends,
+		scans:         scans,
+		quota:         quota,
+		limits: utils.UploadLimits{
+			MaxFileSize:    cfg.UploadMaxFileSize,
+			MaxRequestSize: cfg.UploadMaxRequestSize,
+			AllowedTypes:   cfg.UploadAllowedTypes,
+		},
+	}
+}
+
+func (s *FileStoreService) MigrateStorage(ctx context.Context) error {
+	if err := s.blobs.EnsureIndexes(ctx); err != nil {
+		return err
 	}
+	if err := s.quota.MigrateUsage(ctx); err != nil {
+		return err
+	}
+	return s.fileStoreRepo.MigrateStorage(ctx)
 }
+
+// DedupStats reports how much space sharing identical content saves
+func (s *FileStoreService) DedupStats(ctx context.Context) (*model.BlobStats, error) {
+	return s.blobs.Stats(ctx)
+}
+
 func (s *FileStoreService) Uploads(ctx context.Context, payload *dto.FileStoreRequest, shop *model.Shop) ([]*model.FileStore, error) {
 	var files []*multipart.FileHeader
+	var size int64
 	for i := range payload.Files {
 		files = append(files, &payload.Files[i])
+		size += payload.Files[i].Size
 	}
-	resUpload, err := utils.Upload(files)
+	position, err := s.fileStoreRepo.NextPosition(ctx, shop.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := s.quota.Reserve(ctx, shop, size); err != nil {
+		return nil, err
+	}
+	resUpload, err := utils.Upload(ctx, s.blobs, files, s.limits)
+	if err != nil {
+		_ = s.quota.Adjust(ctx, shop.ID, -size)
+		return nil, uploadError(err)
+	}
+
+	// The reservation used the declared sizes, the quota counts the stored ones
+	var stored int64
+	for _, res := range resUpload {
+		stored += res.Size
+	}
+	if err := s.quota.Settle(ctx, shop, size, stored); err != nil {
+		for _, res := range resUpload {
+			_ = s.blobs.Release(ctx, res.Backend, res.Key)
+		}
+		_ = s.quota.Adjust(ctx, shop.ID, -size)
+		return nil, err
+	}
+	size = stored
+
 	var fileStore []*model.FileStore
 	for i := range resUpload {
-		fileStore = append(fileStore, &model.FileStore{
-			ID:        primitive.NewObjectID(),
-			Name:      resUpload[i].Name,
-			BasePath:  resUpload[i].BasePath,
-			Extension: resUpload[i].Extension,
-			ShopID:    shop.ID,
-		})
+		fileStore = append(fileStore, newFileStore(resUpload[i], shop.ID, payload.UploadedBy, position+i))
 	}
 
 	createdFileStore, err := s.fileStoreRepo.Create(ctx, fileStore)
 	if err != nil {
+		for _, res := range resUpload {
+			_ = s.blobs.Release(ctx, res.Backend, res.Key)
+		}
+		_ = s.quota.Adjust(ctx, shop.ID, -size)
 		return nil, err
 	}
+	s.scans.Enqueue(ctx, createdFileStore...)
 	return createdFileStore, nil
 }
 
+// UploadReader adds the content of src to the shop as a file called filename
+func (s *FileStoreService) UploadReader(ctx context.Context, shop *model.Shop, uploadedBy primitive.ObjectID, src io.ReadSeeker, filename string) (*model.FileStore, error) {
+	position, err := s.fileStoreRepo.NextPosition(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	size, err := src.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := s.quota.Reserve(ctx, shop, size); err != nil {
+		return nil, err
+	}
+
+	resUpload, err := utils.UploadReader(ctx, s.blobs, src, filename, s.limits)
+	if err != nil {
+		_ = s.quota.Adjust(ctx, shop.ID, -size)
+		return nil, uploadError(err)
+	}
+
+	created, err := s.fileStoreRepo.Create(ctx, []*model.FileStore{newFileStore(resUpload, shop.ID, uploadedBy, position)})
+	if err != nil {
+		_ = s.blobs.Release(ctx, resUpload.Backend, resUpload.Key)
+		_ = s.quota.Adjust(ctx, shop.ID, -size)
+		return nil, err
+	}
+	s.scans.Enqueue(ctx, created...)
+	return created[0], nil
+}
+
+// MaxFileSize is the largest file accepted, zero means no limit
+func (s *FileStoreService) MaxFileSize() int64 {
+	return s.limits.MaxFileSize
+}
+
+// CheckQuota fails when size more bytes would not fit the storage quota of shop
+func (s *FileStoreService) CheckQuota(shop *model.Shop, size int64) error {
+	return s.quota.Check(shop, size)
+}
+
+// Usage reports the storage quota and usage of shop
+func (s *FileStoreService) Usage(ctx context.Context, shop *model.Shop) (*dto.StorageUsageResponse, error) {
+	return s.quota.Usage(ctx, shop)
+}
+
+// UpdateStoragePlan sets the storage plan and quota override of a shop
+func (s *FileStoreService) UpdateStoragePlan(ctx context.Context, shopID primitive.ObjectID, payload *dto.UpdateStoragePlanRequest) (*model.Shop, error) {
+	return s.quota.UpdatePlan(ctx, shopID, payload)
+}
+
+func newFileStore(res *dto.FileStoreUploadResponse, shopID, uploadedBy primitive.ObjectID, position int) *model.FileStore {
+	return &model.FileStore{
+		ID:           primitive.NewObjectID(),
+		Name:         res.Name,
+		OriginalName: res.OriginalName,
+		Backend:      res.Backend,
+		Key:          res.Key,
+		Extension:    res.Extension,
+		Size:         res.Size,
+		ContentType:  res.ContentType,
+		Hash:         res.Hash,
+		ShopID:       shopID,
+		UploadedBy:   uploadedBy,
+		Position:     position,
+		ScanStatus:   model.ScanPending,
+	}
+}
+
+// Replace uploads a new content for fileStore, keeping its ID and position
+func (s *FileStoreService) Replace(ctx context.Context, shop *model.Shop, fileStore *model.FileStore, file *multipart.FileHeader, uploadedBy primitive.ObjectID) (*model.FileStore, error) {
+	// Only growth needs room, the old content is given back once replaced
+	growth := max(file.Size-fileStore.Size, 0)
+	if err := s.quota.Reserve(ctx, shop, growth); err != nil {
+		return nil, err
+	}
+
+	resUpload, err := utils.Upload(ctx, s.blobs, []*multipart.FileHeader{file}, s.limits)
+	if err != nil {
+		_ = s.quota.Adjust(ctx, shop.ID, -growth)
+		return nil, uploadError(err)
+	}
+
+	// The growth reserved used the declared size, the quota counts the stored one
+	stored := max(resUpload[0].Size-fileStore.Size, 0)
+	if err := s.quota.Settle(ctx, shop, growth, stored); err != nil {
+		_ = s.blobs.Release(ctx, resUpload[0].Backend, resUpload[0].Key)
+		_ = s.quota.Adjust(ctx, shop.ID, -growth)
+		return nil, err
+	}
+	growth = stored
+
+	updated, err := s.fileStoreRepo.Update(ctx, fileStore.ID, bson.M{
+		"name":           resUpload[0].Name,
+		"original_name":  resUpload[0].OriginalName,
+		"backend":        resUpload[0].Backend,
+		"key":            resUpload[0].Key,
+		"extension":      resUpload[0].Extension,
+		"size":           resUpload[0].Size,
+		"content_type":   resUpload[0].ContentType,
+		"hash":           resUpload[0].Hash,
+		"uploaded_by":    uploadedBy,
+		"scan_status":    model.ScanPending,
+		"scan_signature": "",
+		"scanned_by":     "",
+		"scanned_at":     nil,
+	})
+	if err != nil {
+		_ = s.blobs.Release(ctx, resUpload[0].Backend, resUpload[0].Key)
+		_ = s.quota.Adjust(ctx, shop.ID, -growth)
+		return nil, err
+	}
+	_ = s.quota.Adjust(ctx, shop.ID, resUpload[0].Size-fileStore.Size-growth)
+
+	// The record already points at the new content, a leftover old blob is harmless
+	_ = s.blobs.Release(ctx, fileStore.Backend, fileStore.Key)
+	if err := DeleteDerivatives(ctx, s.fileStoreRepo, s.blobs, fileStore); err != nil {
+		return nil, err
+	}
+	if _, err := s.fileStoreRepo.Update(ctx, fileStore.ID, bson.M{"variants": []string{}, "derivatives": ""}); err != nil {
+		return nil, err
+	}
+	updated.Variants, updated.Derivatives = nil, ""
+	s.scans.Enqueue(ctx, updated)
+	return updated, nil
+}
+
+// Reorder sets the order of the shop files, ids must list every file of the shop exactly once
+func (s *FileStoreService) Reorder(ctx context.Context, shop *model.Shop, ids []primitive.ObjectID) ([]model.FileStore, error) {
+	files, err := s.FindShopFiles(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ids) != len(files) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "File IDs must list every file of the shop")
+	}
+	known := make(map[primitive.ObjectID]bool, len(files))
+	for _, file := range files {
+		known[file.ID] = true
+	}
+	for _, id := range ids {
+		if !known[id] {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "File IDs must list every file of the shop exactly once")
+		}
+		delete(known, id)
+	}
+
+	if err := s.fileStoreRepo.Reorder(ctx, shop.ID, ids); err != nil {
+		return nil, err
+	}
+	return s.FindShopFiles(ctx, shop.ID)
+}
+
 func (s *FileStoreService) Delete(ctx context.Context, id primitive.ObjectID) error {
 	fileStore, err := s.fileStoreRepo.FindById(ctx, id)
 	if err != nil {
 		return err
 	}
-	return s.fileStoreRepo.Delete(ctx, fileStore, id)
+
+	if err := DeleteDerivatives(ctx, s.fileStoreRepo, s.blobs, fileStore); err != nil {
+		return err
+	}
+	if err := s.blobs.Release(ctx, fileStore.Backend, fileStore.Key); err != nil {
+		return err
+	}
+	if err := s.fileStoreRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+	if fileStore.ParentID != nil {
+		return nil
+	}
+	return s.quota.Adjust(ctx, fileStore.ShopID, -fileStore.Size)
+}
+
+// OpenRange returns length bytes of the content of fileStore starting at offset
+func (s *FileStoreService) OpenRange(ctx context.Context, fileStore *model.FileStore, offset, length int64) (io.ReadCloser, error) {
+	store, err := s.backends.Get(fileStore.Backend)
+	if err != nil {
+		return nil, err
+	}
+	return store.GetRange(ctx, fileStore.Key, offset, length)
+}
+
+// EnsureHash computes the hash and size of files stored before uploads were hashed
+func (s *FileStoreService) EnsureHash(ctx context.Context, fileStore *model.FileStore) error {
+	if fileStore.Hash != "" {
+		return nil
+	}
+
+	reader, _, err := s.Open(ctx, fileStore)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, reader)
+	if err != nil {
+		return err
+	}
+
+	fileStore.Hash = hex.EncodeToString(hash.Sum(nil))
+	fileStore.Size = size
+	_, err = s.fileStoreRepo.Update(ctx, fileStore.ID, bson.M{"hash": fileStore.Hash, "size": size})
+	return err
+}
+
+// FindShopFiles returns the files uploaded to a shop in their order, without image derivatives
+func (s *FileStoreService) FindShopFiles(ctx context.Context, shopID primitive.ObjectID) ([]model.FileStore, error) {
+	return s.fileStoreRepo.FindAll(ctx, bson.M{"shop_id": shopID, "parent_id": bson.M{"$exists": false}})
+}
+
+// Open returns the content of fileStore from the backend it was stored in
+func (s *FileStoreService) Open(ctx context.Context, fileStore *model.FileStore) (io.ReadCloser, *storage.Object, error) {
+	store, err := s.backends.Get(fileStore.Backend)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store.Get(ctx, fileStore.Key)
 }
 
 func (s *FileStoreService) FindAll(ctx context.Context, query bson.M) ([]model.FileStore, error) {
@@ -64,3 +352,135 @@ func (s *FileStoreService) FindAll(ctx context.Context, query bson.M) ([]model.F
 func (s *FileStoreService) FindOne(ctx context.Context, query bson.M) (*model.FileStore, error) {
 	return s.fileStoreRepo.FindOne(ctx, query)
 }
+
+// FindPage returns one page of the files matching query
+func (s *FileStoreService) FindPage(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.FileStore, error) {
+	return s.fileStoreRepo.FindPage(ctx, query, opts)
+}
+
+func (s *FileStoreService) Count(ctx context.Context, query bson.M) (int64, error) {
+	return s.fileStoreRepo.Count(ctx, query)
+}
+
+// FindArchiveFiles returns the files of a shop in their order, limited to ids
+// and extensions when they are not empty
+func (s *FileStoreService) FindArchiveFiles(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID, extensions []string) ([]model.FileStore, error) {
+	query := bson.M{"shop_id": shopID, "parent_id": bson.M{"$exists": false}}
+	if len(ids) > 0 {
+		query["_id"] = bson.M{"$in": ids}
+	}
+	if len(extensions) > 0 {
+		normalized := make([]string, len(extensions))
+		for i, ext := range extensions {
+			normalized[i] = "." + strings.TrimPrefix(strings.ToLower(ext), ".")
+		}
+		query["extension"] = bson.M{"$in": normalized}
+	}
+	return s.fileStoreRepo.FindAll(ctx, query)
+}
+
+// WriteArchive streams files as a ZIP archive to w, one entry at a time, and
+// ends it with a manifest. Files whose content cannot be read are left out and
+// listed in the manifest with the error, as are files not scanned clean.
+func (s *FileStoreService) WriteArchive(ctx context.Context, w io.Writer, shop *model.Shop, files []model.FileStore) error {
+	archive := zip.NewWriter(w)
+	manifest := dto.ArchiveManifest{
+		ShopID:    shop.ID,
+		ShopName:  shop.Name,
+		CreatedAt: time.Now(),
+		Files:     []dto.ArchiveManifestFile{},
+	}
+
+	used := map[string]bool{ArchiveManifestName: true}
+	for i := range files {
+		file := &files[i]
+		entry := dto.ArchiveManifestFile{
+			ID:           file.ID,
+			OriginalName: file.OriginalName,
+			ContentType:  file.ContentType,
+			Size:         file.Size,
+			Hash:         file.Hash,
+			Position:     file.Position,
+			CreatedAt:    file.CreatedAt,
+		}
+
+		if err := CheckScanned(file); err != nil {
+			entry.Error = err.Error()
+			manifest.Files = append(manifest.Files, entry)
+			continue
+		}
+		reader, _, err := s.Open(ctx, file)
+		if err != nil {
+			entry.Error = err.Error()
+			manifest.Files = append(manifest.Files, entry)
+			continue
+		}
+
+		entry.Path = archivePath(file, used)
+		header := &zip.FileHeader{Name: entry.Path, Method: archiveMethod(file.ContentType), Modified: file.UpdatedAt}
+		part, err := archive.CreateHeader(header)
+		if err == nil {
+			_, err = io.Copy(part, reader)
+		}
+		reader.Close()
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, entry)
+	}
+
+	part, err := archive.CreateHeader(&zip.FileHeader{Name: ArchiveManifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(part)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// archivePath names the entry of file after its original name, numbering
+// names already used by another entry
+func archivePath(file *model.FileStore, used map[string]bool) string {
+	name := file.OriginalName
+	if name == "" {
+		name = file.Name
+	}
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == "/" || name == ".." {
+		name = file.ID.Hex() + file.Extension
+	}
+
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; used[name]; i++ {
+		name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+	}
+	used[name] = true
+	return name
+}
+
+// archiveMethod stores content that is already compressed as is
+func archiveMethod(contentType string) uint16 {
+	switch {
+	case strings.HasPrefix(contentType, "image/") && contentType != "image/svg+xml",
+		strings.HasPrefix(contentType, "video/"),
+		strings.HasPrefix(contentType, "audio/"),
+		contentType == "application/zip", contentType == "application/gzip":
+		return zip.Store
+	}
+	return zip.Deflate
+}
+
+// uploadError turns rejected uploads into client errors
+func uploadError(err error) error {
+	switch {
+	case errors.Is(err, utils.ErrFileTooLarge), errors.Is(err, utils.ErrRequestTooLarge):
+		return fiber.NewError(fiber.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, utils.ErrUnsupportedFileType):
+		return fiber.NewError(fiber.StatusUnsupportedMediaType, err.Error())
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PolicyService resolves the permissions granted by roles and checks them against resources
+type PolicyService struct {
+	roleRepo repository.RoleRepository
+}
+
+func NewPolicyService(roleRepo repository.RoleRepository) *PolicyService {
+	return &PolicyService{
+		roleRepo: roleRepo,
+	}
+}
+
+// SeedRoles creates the default roles that do not exist yet
+func (s *PolicyService) SeedRoles(ctx context.Context) error {
+	if err := s.roleRepo.EnsureIndexes(ctx); err != nil {
+		return err
+	}
+	for role, permissions := range utils.DefaultRolePermissions {
+		names := make([]string, len(permissions))
+		for i, p := range permissions {
+			names[i] = string(p)
+		}
+		if err := s.roleRepo.Seed(ctx, string(role), names); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PolicyService) ListRoles(ctx context.Context) ([]model.Role, error) {
+	return s.roleRepo.FindAll(ctx)
+}
+
+// SaveRole creates or replaces the permissions of a role
+func (s *PolicyService) SaveRole(ctx context.Context, name string, permissions []string) (*model.Role, error) {
+	for _, p := range permissions {
+		if !utils.IsKnownPermission(p) {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown permission: "+p)
+		}
+	}
+	if name == string(utils.AdminRole) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "The admin role always has every permission")
+	}
+	return s.roleRepo.Upsert(ctx, name, permissions)
+}
+
+// Permissions returns the union of the permissions granted by roles
+func (s *PolicyService) Permissions(ctx context.Context, roles []string) (map[utils.Permission]bool, error) {
+	granted := make(map[utils.Permission]bool)
+	if len(roles) == 0 {
+		return granted, nil
+	}
+
+	found, err := s.roleRepo.FindByNames(ctx, roles)
+	if err != nil {
+		return nil, err
+	}
+	for _, role := range found {
+		for _, p := range role.Permissions {
+			granted[utils.Permission(p)] = true
+		}
+	}
+	for _, role := range roles {
+		if role == string(utils.AdminRole) {
+			for _, p := range utils.AllPermissions {
+				granted[p] = true
+			}
+		}
+	}
+	return granted, nil
+}
+
+// HasPermission reports whether the user is granted any of the permissions
+func (s *PolicyService) HasPermission(ctx context.Context, user *model.User, permissions ...utils.Permission) (bool, error) {
+	granted, err := s.Permissions(ctx, user.Roles)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if granted[p] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AuthorizeShop checks that the user may perform the action on the shop, either
+// through the :any permission or through the :own permission combined with a
+// shop role that allows the action
+func (s *PolicyService) AuthorizeShop(ctx context.Context, user *model.User, action utils.Action, shop *model.Shop) error {
+	granted, err := s.Permissions(ctx, user.Roles)
+	if err != nil {
+		return err
+	}
+	if granted[action.Any()] {
+		return nil
+	}
+	if granted[action.Own()] && utils.ShopRoleAllows(ShopRoleOf(shop, user.ID), action) {
+		return nil
+	}
+	return fiber.NewError(fiber.StatusForbidden, "Insufficient permissions")
+}
+
+// ShopRoleOf returns the role of the user in the shop, or an empty role when they are
+// not an accepted member. The creator is treated as an owner only in shops without
+// any membership, the ones not migrated yet.
+func ShopRoleOf(shop *model.Shop, userID primitive.ObjectID) utils.ShopRole {
+	for _, member := range shop.Members {
+		if member.UserID != nil && *member.UserID == userID && member.Status == model.MemberAccepted {
+			return utils.ShopRole(member.Role)
+		}
+	}
+	if !shop.HasMembers && len(shop.Members) == 0 && shop.CreatedBy == userID {
+		return utils.ShopOwner
+	}
+	return ""
+}
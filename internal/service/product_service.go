@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/dto"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ProductService struct {
+	productRepo   repository.ProductRepository
+	categoryRepo  repository.CategoryRepository
+	fileStoreRepo repository.FileStoreRepository
+}
+
+func NewProductService(productRepo repository.ProductRepository, categoryRepo repository.CategoryRepository, fileStoreRepo repository.FileStoreRepository) *ProductService {
+	return &ProductService{
+		productRepo:   productRepo,
+		categoryRepo:  categoryRepo,
+		fileStoreRepo: fileStoreRepo,
+	}
+}
+
+func (s *ProductService) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Product, error) {
+	return s.productRepo.FindAll(ctx, query, opts)
+}
+
+func (s *ProductService) Count(ctx context.Context, query bson.M) (int64, error) {
+	return s.productRepo.Count(ctx, query)
+}
+
+func (s *ProductService) FindByID(ctx context.Context, shop *model.Shop, id primitive.ObjectID) (*model.Product, error) {
+	return s.productRepo.FindOne(ctx, bson.M{"_id": id, "shop_id": shop.ID})
+}
+
+func (s *ProductService) Create(ctx context.Context, payload *dto.ProductRequest, shop *model.Shop) (*model.Product, error) {
+	imageID, categoryID, err := s.resolveReferences(ctx, payload, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &model.Product{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Price:       payload.Price,
+		ImageID:     imageID,
+		CategoryID:  categoryID,
+		ShopID:      shop.ID,
+	}
+	return s.productRepo.Create(ctx, product)
+}
+
+func (s *ProductService) Update(ctx context.Context, id primitive.ObjectID, payload *dto.ProductRequest, shop *model.Shop) (*model.Product, error) {
+	imageID, categoryID, err := s.resolveReferences(ctx, payload, shop)
+	if err != nil {
+		return nil, err
+	}
+
+	product := &dto.UpdateProductRequest{
+		Name:        payload.Name,
+		Description: payload.Description,
+		Price:       payload.Price,
+		ImageID:     imageID,
+		CategoryID:  categoryID,
+	}
+	return s.productRepo.UpdateByID(ctx, id, product)
+}
+
+func (s *ProductService) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return s.productRepo.Delete(ctx, id)
+}
+
+// resolveReferences makes sure the category and image of a product belong to the same shop
+func (s *ProductService) resolveReferences(ctx context.Context, payload *dto.ProductRequest, shop *model.Shop) (primitive.ObjectID, primitive.ObjectID, error) {
+	var imageID, categoryID primitive.ObjectID
+
+	if payload.CategoryID != "" {
+		id, err := primitive.ObjectIDFromHex(payload.CategoryID)
+		if err != nil {
+			return imageID, categoryID, fiber.NewError(fiber.StatusBadRequest, "Invalid category ID format")
+		}
+		category, err := s.categoryRepo.Get(ctx, id)
+		if err != nil || category == nil || category.ShopID != shop.ID {
+			return imageID, categoryID, fiber.NewError(fiber.StatusBadRequest, "Category does not belong to this shop")
+		}
+		categoryID = category.ID
+	}
+
+	if payload.ImageID != "" {
+		id, err := primitive.ObjectIDFromHex(payload.ImageID)
+		if err != nil {
+			return imageID, categoryID, fiber.NewError(fiber.StatusBadRequest, "Invalid image ID format")
+		}
+		image, err := s.fileStoreRepo.FindOne(ctx, bson.M{"_id": id, "shop_id": shop.ID})
+		if err != nil || image == nil {
+			return imageID, categoryID, fiber.NewError(fiber.StatusBadRequest, "Image does not belong to this shop")
+		}
+		imageID = image.ID
+	}
+
+	return imageID, categoryID, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/dto"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// QuotaService keeps the storage usage of each shop within its quota. Usage
+// counts the files uploaded to a shop at full size, even when their content is
+// shared with other files. Image derivatives are not counted.
+type QuotaService struct {
+	shopRepo      repository.ShopRepository
+	fileStoreRepo repository.FileStoreRepository
+	plans         map[string]int64
+	defaultPlan   string
+}
+
+func NewQuotaService(shopRepo repository.ShopRepository, fileStoreRepo repository.FileStoreRepository, cfg *config.Config) (*QuotaService, error) {
+	plans := map[string]int64{}
+	for _, plan := range cfg.StoragePlans {
+		name, value, _ := strings.Cut(plan, ":")
+		quota, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || quota < 0 {
+			return nil, fmt.Errorf("invalid storage plan %q, expected name:bytes", plan)
+		}
+		plans[name] = quota
+	}
+	if _, ok := plans[cfg.StorageDefaultPlan]; !ok {
+		return nil, fmt.Errorf("storage default plan %q is not one of the storage plans", cfg.StorageDefaultPlan)
+	}
+	return &QuotaService{
+		shopRepo:      shopRepo,
+		fileStoreRepo: fileStoreRepo,
+		plans:         plans,
+		defaultPlan:   cfg.StorageDefaultPlan,
+	}, nil
+}
+
+// Plan is the plan of shop, shops without one are on the default plan
+func (s *QuotaService) Plan(shop *model.Shop) string {
+	if _, ok := s.plans[shop.Plan]; ok {
+		return shop.Plan
+	}
+	return s.defaultPlan
+}
+
+// Limit is the storage quota of shop in bytes, 0 means unlimited
+func (s *QuotaService) Limit(shop *model.Shop) int64 {
+	if shop.StorageQuota != nil {
+		return *shop.StorageQuota
+	}
+	return s.plans[s.Plan(shop)]
+}
+
+// Check fails when size more bytes would not fit the quota of shop, without reserving them
+func (s *QuotaService) Check(shop *model.Shop, size int64) error {
+	if limit := s.Limit(shop); limit > 0 && shop.StorageUsed+size > limit {
+		return quotaExceeded(limit)
+	}
+	return nil
+}
+
+// Reserve adds size bytes to the usage of shop, failing with 507 Insufficient
+// Storage when they do not fit its quota
+func (s *QuotaService) Reserve(ctx context.Context, shop *model.Shop, size int64) error {
+	limit := s.Limit(shop)
+	ok, err := s.shopRepo.AddStorageUsed(ctx, shop.ID, size, limit)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return quotaExceeded(limit)
+	}
+	return nil
+}
+
+// Adjust moves the usage of a shop by delta without checking the quota, it
+// gives back reserved bytes and accounts for content changed after upload
+func (s *QuotaService) Adjust(ctx context.Context, shopID primitive.ObjectID, delta int64) error {
+	if delta == 0 {
+		return nil
+	}
+	_, err := s.shopRepo.AddStorageUsed(ctx, shopID, delta, 0)
+	return err
+}
+
+// Settle turns a reservation of reserved bytes, made with the size a client
+// declared, into the stored bytes actually written. Extra bytes must fit the
+// quota of shop, the rest is given back.
+func (s *QuotaService) Settle(ctx context.Context, shop *model.Shop, reserved, stored int64) error {
+	if stored > reserved {
+		return s.Reserve(ctx, shop, stored-reserved)
+	}
+	return s.Adjust(ctx, shop.ID, stored-reserved)
+}
+
+// Usage reports the quota of shop with its files and bytes by content type
+func (s *QuotaService) Usage(ctx context.Context, shop *model.Shop) (*dto.StorageUsageResponse, error) {
+	byContentType, err := s.fileStoreRepo.Usage(ctx, shop.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &dto.StorageUsageResponse{
+		Plan:          s.Plan(shop),
+		Quota:         s.Limit(shop),
+		Used:          shop.StorageUsed,
+		ByContentType: []model.ContentTypeUsage{},
+	}
+	for _, u := range byContentType {
+		usage.Files += u.Files
+		usage.Bytes += u.Bytes
+		usage.ByContentType = append(usage.ByContentType, u)
+	}
+	return usage, nil
+}
+
+// UpdatePlan moves a shop to another plan, quota overrides the plan quota when set
+func (s *QuotaService) UpdatePlan(ctx context.Context, shopID primitive.ObjectID, payload *dto.UpdateStoragePlanRequest) (*model.Shop, error) {
+	if _, ok := s.plans[payload.Plan]; !ok {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown storage plan: "+payload.Plan)
+	}
+	return s.shopRepo.UpdateStoragePlan(ctx, shopID, payload.Plan, payload.Quota)
+}
+
+// MigrateUsage counts the usage of shops created before usage was tracked
+func (s *QuotaService) MigrateUsage(ctx context.Context) error {
+	return s.shopRepo.MigrateStorageUsed(ctx)
+}
+
+func quotaExceeded(limit int64) error {
+	return fiber.NewError(fiber.StatusInsufficientStorage, fmt.Sprintf("Storage quota of %d bytes exceeded", limit))
+}
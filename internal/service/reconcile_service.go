@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/storage"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// Content is missing: the file row points at a key no backend holds
+	FindingMissingContent = "missing_content"
+	// Content is orphaned: a stored object no file row points at
+	FindingOrphanedContent = "orphaned_content"
+	// File is orphaned: its shop no longer exists
+	FindingOrphanedFile = "orphaned_file"
+	// Blob reference count differs from the file rows pointing at it
+	FindingRefCount = "ref_count_mismatch"
+	// Blob deletion is unfinished: its tombstone blocks new copies of the content
+	FindingUnfinishedDeletion = "unfinished_deletion"
+
+	// ReconcileGrace is how long new objects and blobs are left alone
+	ReconcileGrace = time.Hour
+)
+
+// ReconcileFinding is one inconsistency between the database and the storage backends
+type ReconcileFinding struct {
+	Type     string              `json:"type"`
+	Backend  string              `json:"backend"`
+	Key      string              `json:"key"`
+	FileID   *primitive.ObjectID `json:"file_id,omitempty"`
+	ShopID   *primitive.ObjectID `json:"shop_id,omitempty"`
+	Size     int64               `json:"size,omitempty"`
+	Detail   string              `json:"detail,omitempty"`
+	Resolved bool                `json:"resolved"`
+	Error    string              `json:"error,omitempty"`
+}
+
+// ReconcileReport is the outcome of a reconciliation run
+type ReconcileReport struct {
+	Apply      bool               `json:"apply"`
+	StartedAt  time.Time          `json:"started_at"`
+	FinishedAt time.Time          `json:"finished_at"`
+	Files      int                `json:"files"`
+	Objects    int                `json:"objects"`
+	Findings   []ReconcileFinding `json:"findings"`
+	Counts     map[string]int     `json:"counts"`
+}
+
+// WriteJSON writes the report indented for people to read
+func (r *ReconcileReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Summary is a one line overview of the report
+func (r *ReconcileReport) Summary() string {
+	mode := "dry-run"
+	if r.Apply {
+		mode = "apply"
+	}
+	return fmt.Sprintf("reconcile (%s): %d files, %d objects, %d missing content, %d orphaned content, %d orphaned files, %d ref count mismatches",
+		mode, r.Files, r.Objects,
+		r.Counts[FindingMissingContent], r.Counts[FindingOrphanedContent], r.Counts[FindingOrphanedFile], r.Counts[FindingRefCount])
+}
+
+// Save writes the report into dir, named after the time the run started
+func (r *ReconcileReport) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(filepath.Join(dir, "reconcile-"+r.StartedAt.UTC().Format("20060102T150405Z")+".json"))
+	if err != nil {
+		return err
+	}
+	if err := r.WriteJSON(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (r *ReconcileReport) add(finding ReconcileFinding) {
+	r.Findings = append(r.Findings, finding)
+	r.Counts[finding.Type]++
+}
+
+func (f *ReconcileFinding) resolve(err error) {
+	if err != nil {
+		f.Error = err.Error()
+		return
+	}
+	f.Resolved = true
+}
+
+// ReconcileService finds drift between file rows, blobs and stored objects.
+// In apply mode it deletes rows without content or shop, deletes unreferenced
+// objects and corrects blob reference counts.
+type ReconcileService struct {
+	fileStoreRepo    repository.FileStoreRepository
+	blobRepo         repository.BlobRepository
+	backends         *storage.Backends
+	fileStoreService *FileStoreService
+}
+
+func NewReconcileService(fileStoreRepo repository.FileStoreRepository, blobRepo repository.BlobRepository, backends *storage.Backends, fileStoreService *FileStoreService) *ReconcileService {
+	return &ReconcileService{
+		fileStoreRepo:    fileStoreRepo,
+		blobRepo:         blobRepo,
+		backends:         backends,
+		fileStoreService: fileStoreService,
+	}
+}
+
+// Run checks every backend. Objects and blobs changed within grace are left
+// alone, they may belong to an upload that is still being recorded.
+func (s *ReconcileService) Run(ctx context.Context, apply bool, grace time.Duration) (*ReconcileReport, error) {
+	report := &ReconcileReport{Apply: apply, StartedAt: time.Now(), Counts: map[string]int{}}
+	cutoff := report.StartedAt.Add(-grace)
+
+	files, err := s.fileStoreRepo.FindAll(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	report.Files = len(files)
+
+	// references counts the file rows pointing at each backend and key
+	references := map[string]map[string]int64{}
+	for _, file := range files {
+		store, err := s.backends.Get(file.Backend)
+		if err != nil {
+			return nil, err
+		}
+		if references[store.Name()] == nil {
+			references[store.Name()] = map[string]int64{}
+		}
+		references[store.Name()][file.Key]++
+	}
+
+	objects := map[string]map[string]*storage.Object{}
+	for name := range references {
+		objects[name] = map[string]*storage.Object{}
+	}
+	objects[s.backends.Default.Name()] = map[string]*storage.Object{}
+	for name := range objects {
+		store, err := s.backends.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		err = store.List(ctx, "", func(object *storage.Object) error {
+			objects[name][object.Key] = object
+			report.Objects++
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list %s: %w", name, err)
+		}
+	}
+
+	// Counts are corrected first so the deletions below release the right amount
+	blobs, err := s.blobRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, blob := range blobs {
+		if blob.Deleting {
+			if blob.UpdatedAt.After(cutoff) {
+				continue
+			}
+			finding := ReconcileFinding{Type: FindingUnfinishedDeletion, Backend: blob.Backend, Key: blob.Key, Size: blob.Size}
+			if apply {
+				store, err := s.backends.Get(blob.Backend)
+				if err == nil {
+					err = store.Delete(ctx, blob.Key)
+				}
+				if err == nil {
+					err = s.blobRepo.RemoveByKey(ctx, blob.Backend, blob.Key)
+				}
+				finding.resolve(err)
+			}
+			report.add(finding)
+			delete(objects[blob.Backend], blob.Key)
+			continue
+		}
+
+		actual := references[blob.Backend][blob.Key]
+		if actual == blob.RefCount || blob.UpdatedAt.After(cutoff) {
+			continue
+		}
+		finding := ReconcileFinding{
+			Type:    FindingRefCount,
+			Backend: blob.Backend,
+			Key:     blob.Key,
+			Size:    blob.Size,
+			Detail:  fmt.Sprintf("recorded %d references, found %d", blob.RefCount, actual),
+		}
+		if apply {
+			finding.resolve(s.blobRepo.SetRefCount(ctx, blob.ID, actual))
+		}
+		report.add(finding)
+	}
+
+	for name, stored := range objects {
+		store, _ := s.backends.Get(name)
+		for key, object := range stored {
+			if references[name][key] > 0 || object.LastModified.After(cutoff) {
+				continue
+			}
+			finding := ReconcileFinding{Type: FindingOrphanedContent, Backend: name, Key: key, Size: object.Size}
+			if apply {
+				err := s.blobRepo.RemoveByKey(ctx, name, key)
+				if err == nil {
+					err = store.Delete(ctx, key)
+				}
+				finding.resolve(err)
+			}
+			report.add(finding)
+		}
+	}
+
+	orphaned, err := s.fileStoreRepo.FindWithoutShop(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deleting := map[primitive.ObjectID]bool{}
+	for _, file := range orphaned {
+		deleting[file.ID] = true
+	}
+	for _, file := range files {
+		store, _ := s.backends.Get(file.Backend)
+		if _, ok := objects[store.Name()][file.Key]; ok || deleting[file.ID] {
+			continue
+		}
+		orphaned = append(orphaned, file)
+		deleting[file.ID] = true
+	}
+
+	for _, file := range orphaned {
+		findingType := FindingOrphanedFile
+		store, _ := s.backends.Get(file.Backend)
+		if _, ok := objects[store.Name()][file.Key]; !ok {
+			findingType = FindingMissingContent
+		}
+		fileID, shopID := file.ID, file.ShopID
+		finding := ReconcileFinding{
+			Type:    findingType,
+			Backend: store.Name(),
+			Key:     file.Key,
+			FileID:  &fileID,
+			ShopID:  &shopID,
+			Size:    file.Size,
+		}
+		// Deleting an original takes its derivatives along
+		if apply && (file.ParentID == nil || !deleting[*file.ParentID]) {
+			err := s.fileStoreService.Delete(ctx, file.ID)
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				err = nil
+			}
+			finding.resolve(err)
+		} else if apply {
+			finding.Resolved = true
+			finding.Detail = "deleted with its original"
+		}
+		report.add(finding)
+	}
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+// Start runs the reconciliation every interval until ctx is done, writing
+// each report as JSON into reportDir
+func (s *ReconcileService) Start(ctx context.Context, interval time.Duration, apply bool, reportDir string) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report, err := s.Run(ctx, apply, ReconcileGrace)
+				if err != nil {
+					log.Printf("reconcile: %v", err)
+					continue
+				}
+				log.Println(report.Summary())
+				if err := report.Save(reportDir); err != nil {
+					log.Printf("reconcile: failed to save report: %v", err)
+				}
+			}
+		}
+	}()
+}
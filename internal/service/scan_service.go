@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/scanner"
+	"go-fiber-api/pkg/storage"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const scanJobTimeout = 5 * time.Minute
+
+// ScanService checks uploaded files for malware in background workers. Files
+// are pending until scanned, clean ones go on to the derivative pipeline and
+// infected ones stay in quarantine.
+type ScanService struct {
+	fileStoreRepo repository.FileStoreRepository
+	backends      *storage.Backends
+	scanner       scanner.Scanner
+	derivatives   *DerivativeService
+	workers       int
+	jobs          chan primitive.ObjectID
+}
+
+func NewScanService(fileStoreRepo repository.FileStoreRepository, backends *storage.Backends, fileScanner scanner.Scanner, derivatives *DerivativeService, cfg *config.Config) *ScanService {
+	workers := cfg.ScanWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	return &ScanService{
+		fileStoreRepo: fileStoreRepo,
+		backends:      backends,
+		scanner:       fileScanner,
+		derivatives:   derivatives,
+		workers:       workers,
+		jobs:          make(chan primitive.ObjectID, 256),
+	}
+}
+
+// Start runs the workers until ctx is done and queues the files left pending,
+// or failed, by a previous run
+func (s *ScanService) Start(ctx context.Context) {
+	for i := 0; i < s.workers; i++ {
+		go s.work(ctx)
+	}
+
+	pending, err := s.fileStoreRepo.FindAll(ctx, bson.M{"scan_status": bson.M{"$in": []string{model.ScanPending, model.ScanError}}})
+	if err != nil {
+		log.Printf("scan: failed to load pending files: %v", err)
+		return
+	}
+	// Unlike uploads, the backlog waits for room in the queue
+	go func() {
+		for _, file := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case s.jobs <- file.ID:
+			}
+		}
+	}()
+}
+
+// Enqueue queues the pending files for scanning. A full queue leaves them
+// pending until the next start.
+func (s *ScanService) Enqueue(ctx context.Context, files ...*model.FileStore) {
+	for _, file := range files {
+		// Nothing to wait for without a scanner
+		if _, ok := s.scanner.(scanner.Noop); ok {
+			if err := s.record(ctx, file, &scanner.Result{Clean: true}); err != nil {
+				log.Printf("scan: failed to mark %s clean: %v", file.ID.Hex(), err)
+			}
+			continue
+		}
+		select {
+		case s.jobs <- file.ID:
+		default:
+			log.Printf("scan: queue is full, %s stays pending", file.ID.Hex())
+		}
+	}
+}
+
+func (s *ScanService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-s.jobs:
+			jobCtx, cancel := context.WithTimeout(ctx, scanJobTimeout)
+			if err := s.process(jobCtx, id); err != nil {
+				log.Printf("scan: %s failed: %v", id.Hex(), err)
+				_, _ = s.fileStoreRepo.Update(jobCtx, id, bson.M{"scan_status": model.ScanError})
+			}
+			cancel()
+		}
+	}
+}
+
+func (s *ScanService) process(ctx context.Context, id primitive.ObjectID) error {
+	file, err := s.fileStoreRepo.FindById(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	store, err := s.backends.Get(file.Backend)
+	if err != nil {
+		return err
+	}
+	reader, _, err := store.Get(ctx, file.Key)
+	if err != nil {
+		return err
+	}
+	result, err := s.scanner.Scan(ctx, reader)
+	reader.Close()
+	if err != nil {
+		return err
+	}
+
+	// A replace while scanning queued the new content, this verdict is stale
+	current, err := s.fileStoreRepo.FindById(ctx, id)
+	if err != nil || current.Hash != file.Hash {
+		return nil
+	}
+	return s.record(ctx, current, result)
+}
+
+// record stores the verdict on file and starts the derivatives of clean files
+func (s *ScanService) record(ctx context.Context, file *model.FileStore, result *scanner.Result) error {
+	now := time.Now()
+	update := bson.M{"scan_status": model.ScanClean, "scan_signature": "", "scanned_by": s.scanner.Name(), "scanned_at": now}
+	if !result.Clean {
+		update["scan_status"] = model.ScanInfected
+		update["scan_signature"] = result.Signature
+		log.Printf("scan: %s of shop %s is infected with %s", file.ID.Hex(), file.ShopID.Hex(), result.Signature)
+	}
+	// Marked in the same write, the clean original is not served before its metadata is stripped
+	if result.Clean && s.derivatives != nil && s.derivatives.Wants(file) {
+		update["derivatives"] = model.DerivativesPending
+	}
+	if _, err := s.fileStoreRepo.Update(ctx, file.ID, update); err != nil {
+		return err
+	}
+	file.ScanStatus, file.ScanSignature, file.ScannedAt = update["scan_status"].(string), result.Signature, &now
+	file.ScannedBy = s.scanner.Name()
+	if update["derivatives"] != nil {
+		file.Derivatives = model.DerivativesPending
+	}
+
+	if result.Clean && s.derivatives != nil {
+		s.derivatives.Enqueue(ctx, file)
+	}
+	return nil
+}
+
+// CheckScanned refuses files that are not known to be clean, derivatives carry
+// the verdict of their original. Clean originals are held while their metadata
+// is being stripped.
+func CheckScanned(file *model.FileStore) error {
+	switch file.ScanStatus {
+	case model.ScanClean:
+		if file.ParentID == nil && file.Derivatives == model.DerivativesPending {
+			return fiber.NewError(http.StatusConflict, "File is still being processed")
+		}
+		return nil
+	case model.ScanInfected:
+		return fiber.NewError(http.StatusForbidden, "File is quarantined, malware was detected")
+	case model.ScanError:
+		return fiber.NewError(http.StatusConflict, "File could not be scanned for malware")
+	default:
+		return fiber.NewError(http.StatusConflict, "File has not been scanned for malware yet")
+	}
+}
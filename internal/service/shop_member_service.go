@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/notifier"
+	"go-fiber-api/pkg/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type ShopMemberService struct {
+	memberRepo repository.ShopMemberRepository
+	notifier   notifier.Notifier
+	config     *config.Config
+}
+
+func NewShopMemberService(memberRepo repository.ShopMemberRepository, notifier notifier.Notifier, config *config.Config) *ShopMemberService {
+	return &ShopMemberService{
+		memberRepo: memberRepo,
+		notifier:   notifier,
+		config:     config,
+	}
+}
+
+func (s *ShopMemberService) EnsureIndexes(ctx context.Context) error {
+	return s.memberRepo.EnsureIndexes(ctx)
+}
+
+// MigrateCreators makes the creators of shops without any membership their owner
+func (s *ShopMemberService) MigrateCreators(ctx context.Context) error {
+	return s.memberRepo.MigrateCreators(ctx)
+}
+
+// AddOwner registers the creator of a shop as its first owner
+func (s *ShopMemberService) AddOwner(ctx context.Context, shop *model.Shop, user *model.User) (*model.ShopMember, error) {
+	return s.memberRepo.Create(ctx, &model.ShopMember{
+		ShopID:    shop.ID,
+		UserID:    &user.ID,
+		Email:     strings.ToLower(user.Email),
+		Role:      string(utils.ShopOwner),
+		Status:    model.MemberAccepted,
+		InvitedBy: user.ID,
+	})
+}
+
+func (s *ShopMemberService) FindAll(ctx context.Context, shop *model.Shop) ([]model.ShopMember, error) {
+	return s.memberRepo.FindAll(ctx, bson.M{"shop_id": shop.ID})
+}
+
+func (s *ShopMemberService) FindByID(ctx context.Context, shop *model.Shop, id primitive.ObjectID) (*model.ShopMember, error) {
+	return s.memberRepo.FindOne(ctx, bson.M{"_id": id, "shop_id": shop.ID})
+}
+
+// Invite creates a pending membership for an email and notifies it
+func (s *ShopMemberService) Invite(ctx context.Context, shop *model.Shop, inviter *model.User, email, role string) (*model.ShopMember, error) {
+	if !utils.IsKnownShopRole(role) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown shop role: "+role)
+	}
+
+	email = strings.ToLower(email)
+	existing, err := s.memberRepo.FindOne(ctx, bson.M{"shop_id": shop.ID, "email": email})
+	if err != nil {
+		return nil, err
+	}
+
+	var member *model.ShopMember
+	switch {
+	case existing == nil:
+		member, err = s.memberRepo.Create(ctx, &model.ShopMember{
+			ShopID:    shop.ID,
+			Email:     email,
+			Role:      role,
+			Status:    model.MemberPending,
+			InvitedBy: inviter.ID,
+		})
+	case existing.Status == model.MemberDeclined:
+		// A declined invitation can be sent again
+		member, err = s.memberRepo.UpdateByID(ctx, existing.ID, bson.M{
+			"role":       role,
+			"status":     model.MemberPending,
+			"invited_by": inviter.ID,
+		})
+	default:
+		return nil, fiber.NewError(fiber.StatusConflict, "User is already a member or invited")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.notifier.Send(ctx, &notifier.Message{
+		To:      email,
+		Subject: fmt.Sprintf("You have been invited to %s", shop.Name),
+		Body: fmt.Sprintf("%s invited you to join %s as %s:\n%s/invitations",
+			inviter.Name, shop.Name, role, s.config.ClientURL),
+	}); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// errInvitationsUnverified keeps invitations from whoever registered an invited
+// address without owning it, in both the limit and block verification modes
+var errInvitationsUnverified = fiber.NewError(fiber.StatusForbidden, "Verify your email address to see your invitations")
+
+// checkVerified refuses users with an unverified email while verification is on
+func (s *ShopMemberService) checkVerified(user *model.User) error {
+	mode := s.config.EmailVerificationMode
+	if (mode == "limit" || mode == "block") && !user.EmailVerified {
+		return errInvitationsUnverified
+	}
+	return nil
+}
+
+// Invitations lists the pending invitations sent to the user's email
+func (s *ShopMemberService) Invitations(ctx context.Context, user *model.User) ([]model.ShopMember, error) {
+	if err := s.checkVerified(user); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.FindAll(ctx, bson.M{"email": strings.ToLower(user.Email), "status": model.MemberPending})
+}
+
+// Respond accepts or declines an invitation sent to the user
+func (s *ShopMemberService) Respond(ctx context.Context, user *model.User, id primitive.ObjectID, accept bool) (*model.ShopMember, error) {
+	if err := s.checkVerified(user); err != nil {
+		return nil, err
+	}
+	member, err := s.memberRepo.FindOne(ctx, bson.M{
+		"_id":    id,
+		"email":  strings.ToLower(user.Email),
+		"status": model.MemberPending,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Invitation not found")
+	}
+
+	if !accept {
+		return s.memberRepo.UpdateByID(ctx, member.ID, bson.M{"status": model.MemberDeclined})
+	}
+	return s.memberRepo.UpdateByID(ctx, member.ID, bson.M{
+		"status":  model.MemberAccepted,
+		"user_id": user.ID,
+	})
+}
+
+var errLastOwner = fiber.NewError(fiber.StatusConflict, "A shop needs at least one owner")
+
+func (s *ShopMemberService) UpdateRole(ctx context.Context, member *model.ShopMember, role string) (*model.ShopMember, error) {
+	if !utils.IsKnownShopRole(role) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown shop role: "+role)
+	}
+	updated, err := s.memberRepo.UpdateRole(ctx, member.ID, role)
+	if errors.Is(err, repository.ErrLastOwner) {
+		return nil, errLastOwner
+	}
+	return updated, err
+}
+
+func (s *ShopMemberService) Remove(ctx context.Context, member *model.ShopMember) error {
+	err := s.memberRepo.Delete(ctx, member.ID)
+	if errors.Is(err, repository.ErrLastOwner) {
+		return errLastOwner
+	}
+	return err
+}
@@ -28,6 +28,12 @@ func (s *ShopService) Count(ctx context.Context, query bson.D) (int64, error) {
 	return s.shopRepo.Count(ctx, query)
 }
 
+// WithTransaction runs fn in one transaction, so the shop and the documents
+// created with it are saved together or not at all
+func (s *ShopService) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return s.shopRepo.WithTransaction(ctx, fn)
+}
+
 func (s *ShopService) Create(ctx context.Context, payload *dto.ShopRequest, user *model.User) (*model.Shop, error) {
 	shop := &model.Shop{
 		Name:      payload.Name,
@@ -45,19 +51,21 @@ func (s *ShopService) FindByID(ctx context.Context, id primitive.ObjectID) (*mod
 	return s.shopRepo.FindOne(ctx, bson.M{"_id": id})
 }
 
-func (s *ShopService) Update(ctx context.Context, id primitive.ObjectID, payload *dto.ShopRequest) (*model.Shop, error) {
-	shop := &dto.UpdateShopRequest{
-		Name:   payload.Name,
-		Budget: payload.Budget,
-	}
-
-	updatedShop, err := s.shopRepo.UpdateByID(ctx, id, shop)
+// Update changes the shop details, the budget only changes through the budget ledger
+func (s *ShopService) Update(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateShopRequest) (*model.Shop, error) {
+	updatedShop, err := s.shopRepo.UpdateByID(ctx, id, payload)
 	if err != nil {
 		return nil, err
 	}
 	return updatedShop, nil
 }
 
-func (s *ShopService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.shopRepo.Delete(ctx, id)
+// MigrateBudgets converts budgets stored as float currency units into int64 minor units
+func (s *ShopService) MigrateBudgets(ctx context.Context) error {
+	return s.shopRepo.MigrateBudgets(ctx)
+}
+
+// Delete moves the shop to the trash, the trash service restores or purges it
+func (s *ShopService) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	return s.shopRepo.Delete(ctx, id, deletedBy)
 }
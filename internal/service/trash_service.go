@@ -0,0 +1,303 @@
+package service
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/dto"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	TrashUser     = "user"
+	TrashShop     = "shop"
+	TrashCategory = "category"
+)
+
+var errUnknownTrashType = fiber.NewError(fiber.StatusBadRequest, "Type must be one of "+strings.Join([]string{TrashUser, TrashShop, TrashCategory}, ", "))
+
+// TrashService lists, restores and purges soft deleted users, shops and
+// categories. Purging goes through the deletion service so its policies apply.
+type TrashService struct {
+	userRepo        repository.UserRepository
+	shopRepo        repository.ShopRepository
+	categoryRepo    repository.CategoryRepository
+	deletionService *DeletionService
+	retention       time.Duration
+}
+
+func NewTrashService(userRepo repository.UserRepository, shopRepo repository.ShopRepository, categoryRepo repository.CategoryRepository, deletionService *DeletionService, cfg *config.Config) (*TrashService, error) {
+	retention, err := time.ParseDuration(cfg.TrashRetention)
+	if err != nil {
+		return nil, err
+	}
+	return &TrashService{
+		userRepo:        userRepo,
+		shopRepo:        shopRepo,
+		categoryRepo:    categoryRepo,
+		deletionService: deletionService,
+		retention:       retention,
+	}, nil
+}
+
+// List returns a page of trashed items of one type, the most recently deleted first
+func (s *TrashService) List(ctx context.Context, kind string, page, pageSize int) ([]dto.TrashItem, int64, error) {
+	opts := options.Find().
+		SetSkip(int64((page - 1) * pageSize)).
+		SetLimit(int64(pageSize)).
+		SetSort(bson.D{{Key: "deleted_at", Value: -1}})
+	items := []dto.TrashItem{}
+
+	switch kind {
+	case TrashUser:
+		query := bson.D{{Key: "deleted_at", Value: repository.InTrash}}
+		total, err := s.userRepo.Count(ctx, query)
+		if err != nil {
+			return nil, 0, err
+		}
+		users, err := s.userRepo.FindAll(ctx, query, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, user := range users {
+			items = append(items, s.item(TrashUser, user.ID, user.Email, nil, user.DeletedAt, user.DeletedBy))
+		}
+		return items, total, nil
+	case TrashShop:
+		total, err := s.shopRepo.Count(ctx, bson.D{{Key: "deleted_at", Value: repository.InTrash}})
+		if err != nil {
+			return nil, 0, err
+		}
+		shops, err := s.shopRepo.FindAll(ctx, bson.M{"deleted_at": repository.InTrash}, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, shop := range shops {
+			items = append(items, s.item(TrashShop, shop.ID, shop.Name, nil, shop.DeletedAt, shop.DeletedBy))
+		}
+		return items, total, nil
+	case TrashCategory:
+		query := bson.M{"deleted_at": repository.InTrash}
+		total, err := s.categoryRepo.Count(ctx, query)
+		if err != nil {
+			return nil, 0, err
+		}
+		categories, err := s.categoryRepo.FindAll(ctx, query, opts)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, category := range categories {
+			shopID := category.ShopID
+			items = append(items, s.item(TrashCategory, category.ID, category.Name, &shopID, category.DeletedAt, category.DeletedBy))
+		}
+		return items, total, nil
+	}
+	return nil, 0, errUnknownTrashType
+}
+
+// Restore takes an item out of the trash. A category comes back with the
+// subcategories trashed along with it, once its parent is restored.
+func (s *TrashService) Restore(ctx context.Context, kind string, id primitive.ObjectID) error {
+	switch kind {
+	case TrashUser:
+		user, err := s.trashedUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		// The email may have been registered again in the meantime
+		existing, err := s.userRepo.FindOne(ctx, bson.M{"email": user.Email})
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			return fiber.NewError(fiber.StatusConflict, "Another user has registered with this email")
+		}
+		return s.userRepo.Restore(ctx, id)
+	case TrashShop:
+		if _, err := s.trashedShop(ctx, id); err != nil {
+			return err
+		}
+		return s.shopRepo.Restore(ctx, id)
+	case TrashCategory:
+		category, err := s.trashedCategory(ctx, id)
+		if err != nil {
+			return err
+		}
+		if category.ParentID != nil {
+			parent, err := s.categoryRepo.Get(ctx, *category.ParentID)
+			if err != nil || parent == nil {
+				return fiber.NewError(fiber.StatusConflict, "Restore the parent category first")
+			}
+		}
+		query := SubtreeQuery(category)
+		query["deleted_at"] = *category.DeletedAt
+		if _, err := s.categoryRepo.Restore(ctx, query); err != nil {
+			return categoryError(err)
+		}
+		return nil
+	}
+	return errUnknownTrashType
+}
+
+// PurgeShop deletes a trashed shop for good with everything referencing it
+func (s *TrashService) PurgeShop(ctx context.Context, id primitive.ObjectID, dryRun bool) (*dto.DeletionReport, error) {
+	shop, err := s.trashedShop(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.deletionService.DeleteShop(ctx, shop, dryRun)
+}
+
+// PurgeUser deletes a trashed user for good, see DeletionService.DeleteUser
+func (s *TrashService) PurgeUser(ctx context.Context, id primitive.ObjectID, newOwner *model.User, overrides []string, dryRun bool) (*dto.DeletionReport, error) {
+	user, err := s.trashedUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.deletionService.DeleteUser(ctx, user, newOwner, overrides, dryRun)
+}
+
+// PurgeCategory deletes a trashed category for good with its subcategories
+func (s *TrashService) PurgeCategory(ctx context.Context, id primitive.ObjectID, dryRun bool) (*dto.DeletionReport, error) {
+	category, err := s.trashedCategory(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.deletionService.DeleteCategory(ctx, category, dryRun)
+}
+
+// PurgeExpired purges everything trashed longer than the retention ago.
+// Users hand their shops to whoever trashed them when the policies reassign.
+// Purges blocked by a policy are logged and retried on the next run.
+func (s *TrashService) PurgeExpired(ctx context.Context) (int, error) {
+	expired := bson.M{"deleted_at": bson.M{"$lt": time.Now().Add(-s.retention)}}
+	purged := 0
+
+	// Ancestors sort before their subtree, which goes along with them
+	categories, err := s.categoryRepo.FindAll(ctx, expired, options.Find().SetSort(bson.D{{Key: "path", Value: 1}}))
+	if err != nil {
+		return purged, err
+	}
+	var purgedPaths []string
+	for _, category := range categories {
+		if slices.ContainsFunc(purgedPaths, func(path string) bool { return strings.HasPrefix(category.Path, path) }) {
+			continue
+		}
+		report, err := s.deletionService.DeleteCategory(ctx, &category, false)
+		if s.logPurge(TrashCategory, category.ID, report, err) {
+			purgedPaths = append(purgedPaths, category.Path)
+			purged++
+		}
+	}
+
+	shops, err := s.shopRepo.FindAll(ctx, expired, nil)
+	if err != nil {
+		return purged, err
+	}
+	for _, shop := range shops {
+		report, err := s.deletionService.DeleteShop(ctx, &shop, false)
+		if s.logPurge(TrashShop, shop.ID, report, err) {
+			purged++
+		}
+	}
+
+	users, err := s.userRepo.FindAll(ctx, bson.D{{Key: "deleted_at", Value: expired["deleted_at"]}}, nil)
+	if err != nil {
+		return purged, err
+	}
+	for _, user := range users {
+		var newOwner *model.User
+		if user.DeletedBy != nil {
+			if newOwner, err = s.userRepo.FindOne(ctx, bson.M{"_id": *user.DeletedBy}); err != nil {
+				return purged, err
+			}
+		}
+		report, err := s.deletionService.DeleteUser(ctx, &user, newOwner, nil, false)
+		if s.logPurge(TrashUser, user.ID, report, err) {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// Start purges the expired trash every interval until ctx is done
+func (s *TrashService) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purged, err := s.PurgeExpired(ctx)
+				if err != nil {
+					log.Printf("trash: %v", err)
+				}
+				if purged > 0 {
+					log.Printf("trash: purged %d expired items", purged)
+				}
+			}
+		}
+	}()
+}
+
+func (s *TrashService) item(kind string, id primitive.ObjectID, name string, shopID *primitive.ObjectID, deletedAt *time.Time, deletedBy *primitive.ObjectID) dto.TrashItem {
+	item := dto.TrashItem{Type: kind, ID: id, Name: name, ShopID: shopID, DeletedBy: deletedBy}
+	if deletedAt != nil {
+		item.DeletedAt = *deletedAt
+		item.PurgeAt = deletedAt.Add(s.retention)
+	}
+	return item
+}
+
+// logPurge logs a failed purge of the expired trash and tells whether it went through
+func (s *TrashService) logPurge(kind string, id primitive.ObjectID, report *dto.DeletionReport, err error) bool {
+	if err != nil {
+		log.Printf("trash: failed to purge %s %s: %v", kind, id.Hex(), err)
+		return false
+	}
+	return report.Deleted
+}
+
+func (s *TrashService) trashedUser(ctx context.Context, id primitive.ObjectID) (*model.User, error) {
+	user, err := s.userRepo.FindOne(ctx, bson.M{"_id": id, "deleted_at": repository.InTrash})
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "User not found in the trash")
+	}
+	return user, nil
+}
+
+func (s *TrashService) trashedShop(ctx context.Context, id primitive.ObjectID) (*model.Shop, error) {
+	shop, err := s.shopRepo.FindOne(ctx, bson.M{"_id": id, "deleted_at": repository.InTrash})
+	if err != nil {
+		return nil, err
+	}
+	if shop == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Shop not found in the trash")
+	}
+	return shop, nil
+}
+
+func (s *TrashService) trashedCategory(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	categories, err := s.categoryRepo.FindAll(ctx, bson.M{"_id": id, "deleted_at": repository.InTrash}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) == 0 {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Category not found in the trash")
+	}
+	return &categories[0], nil
+}
@@ -0,0 +1,373 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/utils"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	TusVersion    = "1.0.0"
+	TusExtensions = "creation,expiration,termination"
+
+	tusLockTimeout   = time.Minute
+	tusFinishTimeout = 10 * time.Minute
+)
+
+var errTusLockLost = fiber.NewError(fiber.StatusConflict, "Upload lock expired, resume from the current offset")
+
+// TusUpload is the state of a resumable upload, kept in Redis while it is in progress
+type TusUpload struct {
+	ID        string
+	ShopID    primitive.ObjectID
+	UserID    primitive.ObjectID
+	Length    int64
+	Offset    int64
+	Metadata  string
+	Filename  string
+	ExpiresAt time.Time
+}
+
+// TusService implements the tus 1.0 resumable upload protocol. Chunks are
+// appended to a staging file, the finished upload becomes a shop file.
+type TusService struct {
+	redisClient      *redis.Client
+	fileStoreService *FileStoreService
+	stagingPath      string
+	expiresIn        time.Duration
+}
+
+func NewTusService(redisClient *redis.Client, fileStoreService *FileStoreService, cfg *config.Config) (*TusService, error) {
+	expiresIn, err := time.ParseDuration(cfg.TusExpiresIn)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.TusStagingPath, 0755); err != nil {
+		return nil, err
+	}
+	return &TusService{
+		redisClient:      redisClient,
+		fileStoreService: fileStoreService,
+		stagingPath:      cfg.TusStagingPath,
+		expiresIn:        expiresIn,
+	}, nil
+}
+
+// MaxSize is the largest upload accepted, zero means no limit
+func (s *TusService) MaxSize() int64 {
+	return s.fileStoreService.MaxFileSize()
+}
+
+// Create starts an upload of length bytes into shop
+func (s *TusService) Create(ctx context.Context, shop *model.Shop, user *model.User, length int64, metadata string) (*TusUpload, error) {
+	if length < 0 {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid Upload-Length")
+	}
+	if max := s.MaxSize(); max > 0 && length > max {
+		return nil, fiber.NewError(fiber.StatusRequestEntityTooLarge, "Upload exceeds the maximum size")
+	}
+	if err := s.fileStoreService.CheckQuota(shop, length); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+	upload := &TusUpload{
+		ID:        id,
+		ShopID:    shop.ID,
+		UserID:    user.ID,
+		Length:    length,
+		Metadata:  metadata,
+		Filename:  parseTusMetadata(metadata)["filename"],
+		ExpiresAt: time.Now().Add(s.expiresIn).Truncate(time.Second),
+	}
+	if upload.Filename == "" {
+		upload.Filename = "upload"
+	}
+
+	file, err := os.Create(s.stagingFile(id))
+	if err != nil {
+		return nil, err
+	}
+	file.Close()
+
+	pipe := s.redisClient.TxPipeline()
+	pipe.HSet(ctx, tusKey(id), map[string]interface{}{
+		"shop_id":    upload.ShopID.Hex(),
+		"user_id":    upload.UserID.Hex(),
+		"length":     upload.Length,
+		"offset":     0,
+		"metadata":   upload.Metadata,
+		"filename":   upload.Filename,
+		"expires_at": upload.ExpiresAt.Unix(),
+	})
+	pipe.ExpireAt(ctx, tusKey(id), upload.ExpiresAt)
+	if _, err := pipe.Exec(ctx); err != nil {
+		os.Remove(s.stagingFile(id))
+		return nil, err
+	}
+	return upload, nil
+}
+
+// Find returns the upload id of shop, or a 404 error when it is unknown or expired
+func (s *TusService) Find(ctx context.Context, shop *model.Shop, id string) (*TusUpload, error) {
+	upload, err := s.load(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if upload == nil || upload.ShopID != shop.ID {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+	}
+	return upload, nil
+}
+
+// load reads the state of upload id, nil when it is unknown or expired
+func (s *TusService) load(ctx context.Context, id string) (*TusUpload, error) {
+	values, err := s.redisClient.HGetAll(ctx, tusKey(id)).Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	upload := &TusUpload{ID: id, Metadata: values["metadata"], Filename: values["filename"]}
+	upload.ShopID, _ = primitive.ObjectIDFromHex(values["shop_id"])
+	upload.UserID, _ = primitive.ObjectIDFromHex(values["user_id"])
+	upload.Length, _ = strconv.ParseInt(values["length"], 10, 64)
+	upload.Offset, _ = strconv.ParseInt(values["offset"], 10, 64)
+	expiresAt, _ := strconv.ParseInt(values["expires_at"], 10, 64)
+	upload.ExpiresAt = time.Unix(expiresAt, 0)
+	return upload, nil
+}
+
+// Append writes chunk at offset. When the upload is complete it is turned
+// into a shop file, which is returned.
+func (s *TusService) Append(ctx context.Context, shop *model.Shop, upload *TusUpload, offset int64, chunk io.Reader) (*model.FileStore, error) {
+	token, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := s.redisClient.SetNX(ctx, tusLockKey(upload.ID), token, tusLockTimeout).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !locked {
+		return nil, fiber.NewError(fiber.StatusLocked, "Upload is being written by another request")
+	}
+	defer s.unlock(upload.ID, token)
+
+	// The upload was read before the lock, a request that held it moved the offset
+	current, err := s.load(ctx, upload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if current == nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+	}
+	*upload = *current
+
+	if offset != upload.Offset {
+		return nil, fiber.NewError(fiber.StatusConflict, "Upload-Offset does not match the current offset")
+	}
+
+	file, err := os.OpenFile(s.stagingFile(upload.ID), os.O_WRONLY, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Upload not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	// Never write past the declared length, a longer body is an error
+	written, err := io.Copy(file, io.LimitReader(chunk, upload.Length-offset+1))
+	if err == nil && offset+written > upload.Length {
+		err = fiber.NewError(fiber.StatusRequestEntityTooLarge, "Chunk exceeds Upload-Length")
+		written = 0
+		file.Truncate(offset)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.setOffset(ctx, upload.ID, token, upload.Offset+written); err != nil {
+		return nil, err
+	}
+	upload.Offset += written
+	if upload.Offset < upload.Length {
+		return nil, nil
+	}
+	return s.finish(shop, upload)
+}
+
+// setOffset records the new offset while the lock is still held by token. A
+// write that outlived its lock is not recorded, the client resumes from the
+// offset of the request that took the lock over.
+func (s *TusService) setOffset(ctx context.Context, id, token string, offset int64) error {
+	err := s.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		holder, err := tx.Get(ctx, tusLockKey(id)).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if holder != token {
+			return errTusLockLost
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, tusKey(id), "offset", offset)
+			return nil
+		})
+		return err
+	}, tusLockKey(id))
+	if err == redis.TxFailedErr {
+		return errTusLockLost
+	}
+	return err
+}
+
+// unlock releases the lock only while it is still held by token, an expired
+// lock may belong to another request by now
+func (s *TusService) unlock(id, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := s.redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		holder, err := tx.Get(ctx, tusLockKey(id)).Result()
+		if err == redis.Nil {
+			return nil
+		}
+		if err != nil || holder != token {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, tusLockKey(id))
+			return nil
+		})
+		return err
+	}, tusLockKey(id))
+	if err != nil && err != redis.TxFailedErr {
+		log.Printf("tus: failed to unlock upload %s: %v", id, err)
+	}
+}
+
+// Terminate drops an upload and its staged data
+func (s *TusService) Terminate(ctx context.Context, upload *TusUpload) error {
+	if err := s.redisClient.Del(ctx, tusKey(upload.ID)).Err(); err != nil {
+		return err
+	}
+	return removeIfExists(s.stagingFile(upload.ID))
+}
+
+// StartCleanup removes staged data of expired uploads until ctx is done
+func (s *TusService) StartCleanup(ctx context.Context, every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.cleanup(ctx)
+			}
+		}
+	}()
+}
+
+func (s *TusService) cleanup(ctx context.Context) {
+	entries, err := os.ReadDir(s.stagingPath)
+	if err != nil {
+		log.Printf("tus: failed to list staging files: %v", err)
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < s.expiresIn {
+			continue
+		}
+		exists, err := s.redisClient.Exists(ctx, tusKey(entry.Name())).Result()
+		if err == nil && exists == 0 {
+			_ = removeIfExists(filepath.Join(s.stagingPath, entry.Name()))
+		}
+	}
+}
+
+func (s *TusService) finish(shop *model.Shop, upload *TusUpload) (*model.FileStore, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tusFinishTimeout)
+	defer cancel()
+
+	file, err := os.Open(s.stagingFile(upload.ID))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fileStore, err := s.fileStoreService.UploadReader(ctx, shop, upload.UserID, file, upload.Filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Terminate(ctx, upload); err != nil {
+		log.Printf("tus: failed to clean up upload %s: %v", upload.ID, err)
+	}
+	return fileStore, nil
+}
+
+func (s *TusService) stagingFile(id string) string {
+	return filepath.Join(s.stagingPath, id)
+}
+
+func tusKey(id string) string {
+	return "tus:" + id
+}
+
+func tusLockKey(id string) string {
+	return tusKey(id) + ":lock"
+}
+
+// parseTusMetadata decodes an Upload-Metadata header: comma separated keys
+// followed by a space and a base64 value
+func parseTusMetadata(header string) map[string]string {
+	metadata := map[string]string{}
+	for _, pair := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			continue
+		}
+		metadata[key] = string(decoded)
+	}
+	return metadata
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
@@ -2,10 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"go-fiber-api/internal/config"
 	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/repository"
 	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/notifier"
 	"go-fiber-api/pkg/utils"
 	"time"
 
@@ -19,14 +22,18 @@ import (
 
 type UserService struct {
 	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
 	redisClient *redis.Client
+	notifier    notifier.Notifier
 	config      *config.Config
 }
 
-func NewUserService(userRepo repository.UserRepository, redisClient *redis.Client, config *config.Config) *UserService {
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, redisClient *redis.Client, notifier notifier.Notifier, config *config.Config) *UserService {
 	return &UserService{
 		userRepo:    userRepo,
+		roleRepo:    roleRepo,
 		redisClient: redisClient,
+		notifier:    notifier,
 		config:      config,
 	}
 }
@@ -52,17 +59,13 @@ func (s *UserService) Create(ctx context.Context, payload *dto.RegisterRequest)
 		return nil, err
 	}
 
-	if len(payload.Roles) == 0 {
-		payload.Roles = []string{string(utils.UserRole)}
-	}
-
 	now := time.Now()
 	user := &model.User{
 		ID:        primitive.NewObjectID(),
 		Name:      payload.Name,
 		Email:     payload.Email,
 		Password:  string(hashedPassword),
-		Roles:     payload.Roles,
+		Roles:     []string{string(utils.UserRole)},
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
@@ -77,17 +80,17 @@ func (s *UserService) Login(ctx context.Context, password string, user *model.Us
 		return nil, err
 	}
 
+	if s.RequiresVerifiedEmail(user) {
+		return nil, fiber.NewError(fiber.StatusForbidden, "Email address is not verified")
+	}
+
 	auth := utils.NewAuthHandler(s.config.JWTSecretKey, s.config.JWTRefreshKey, s.config.JWTExpiresIn, s.config.JWTRefreshIn)
 	tokenPair, err := auth.GenerateTokenPair(user.ID.Hex(), user.Roles)
 	if err != nil {
 		return nil, err
 	}
 
-	expires, _ := time.ParseDuration(s.config.JWTExpiresIn)
-	if err := s.redisClient.Set(ctx,
-		tokenPair.AccessToken,
-		user.ID.Hex(),
-		expires).Err(); err != nil {
+	if err := s.storeSession(ctx, user, tokenPair); err != nil {
 		return nil, err
 	}
 	return tokenPair, nil
@@ -109,17 +112,16 @@ func (s *UserService) RefreshToken(ctx context.Context, refreshToken string) (*u
 	if err != nil {
 		return nil, err
 	}
+	if user == nil {
+		return nil, fiber.NewError(fiber.StatusUnauthorized, "User not found")
+	}
 
 	tokenPair, err := auth.GenerateTokenPair(user.ID.Hex(), user.Roles)
 	if err != nil {
 		return nil, err
 	}
 
-	expires, _ := time.ParseDuration(s.config.JWTExpiresIn)
-	if err := s.redisClient.Set(ctx,
-		tokenPair.AccessToken,
-		user.ID.Hex(),
-		expires).Err(); err != nil {
+	if err := s.storeSession(ctx, user, tokenPair); err != nil {
 		return nil, err
 	}
 
@@ -147,8 +149,43 @@ func (s *UserService) UpdateById(ctx context.Context, id primitive.ObjectID, pay
 	return err
 }
 
-func (s *UserService) Delete(ctx context.Context, id primitive.ObjectID) error {
-	return s.userRepo.Delete(ctx, id)
+// UpdateRoles replaces the roles of a user and revokes the user's sessions so
+// tokens carrying the old roles stop working
+func (s *UserService) UpdateRoles(ctx context.Context, user *model.User, roles []string) (*model.User, error) {
+	seen := make(map[string]bool)
+	var newRoles []string
+	for _, role := range roles {
+		if !seen[role] {
+			seen[role] = true
+			newRoles = append(newRoles, role)
+		}
+	}
+
+	known, err := s.roleRepo.FindByNames(ctx, newRoles)
+	if err != nil {
+		return nil, err
+	}
+	if len(known) != len(newRoles) {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown role")
+	}
+
+	updatedUser, err := s.userRepo.UpdateRoles(ctx, user.ID, newRoles)
+	if errors.Is(err, repository.ErrLastAdmin) {
+		return nil, fiber.NewError(fiber.StatusConflict, "Cannot remove the last admin")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.RevokeSessions(ctx, user.ID); err != nil {
+		return nil, err
+	}
+	return updatedUser, nil
+}
+
+// Delete moves the user to the trash, the trash service restores or purges it
+func (s *UserService) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	return s.userRepo.Delete(ctx, id, deletedBy)
 }
 
 // check redis for blacklisted token
@@ -193,3 +230,197 @@ func (s *UserService) Logout(ctx context.Context, accessToken, refreshToken stri
 	_, err := pipe.Exec(ctx)
 	return err
 }
+
+// storeSession activates the access token and remembers both tokens of the pair
+// under the user so they can be revoked together
+func (s *UserService) storeSession(ctx context.Context, user *model.User, tokenPair *utils.TokenPair) error {
+	expires, _ := time.ParseDuration(s.config.JWTExpiresIn)
+	refreshExpires, _ := time.ParseDuration(s.config.JWTRefreshIn)
+	sessionsKey := "sessions:" + user.ID.Hex()
+
+	pipe := s.redisClient.Pipeline()
+	pipe.Set(ctx,
+		tokenPair.AccessToken,
+		user.ID.Hex(),
+		expires)
+	pipe.SAdd(ctx, sessionsKey, tokenPair.AccessToken, tokenPair.RefreshToken)
+	pipe.Expire(ctx, sessionsKey, refreshExpires)
+
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// RevokeSessions blacklists every access and refresh token issued to the user
+func (s *UserService) RevokeSessions(ctx context.Context, userID primitive.ObjectID) error {
+	sessionsKey := "sessions:" + userID.Hex()
+	tokens, err := s.redisClient.SMembers(ctx, sessionsKey).Result()
+	if err != nil {
+		return err
+	}
+
+	refreshExpires, _ := time.ParseDuration(s.config.JWTRefreshIn)
+	if refreshExpires < 48*time.Hour {
+		refreshExpires = 48 * time.Hour
+	}
+
+	pipe := s.redisClient.Pipeline()
+	for _, token := range tokens {
+		pipe.Set(ctx, "blacklist:"+token, "true", refreshExpires)
+		pipe.Del(ctx, token)
+	}
+	pipe.Del(ctx, sessionsKey)
+
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// issueToken creates a random single-use token for the user. Only the hash of the
+// token is stored and a new token replaces the previous one of the same kind.
+func (s *UserService) issueToken(ctx context.Context, kind string, userID primitive.ObjectID, expires time.Duration) (string, error) {
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	userKey := kind + "_user:" + userID.Hex()
+	if previous, err := s.redisClient.Get(ctx, userKey).Result(); err == nil {
+		s.redisClient.Del(ctx, kind+":"+previous)
+	}
+
+	hashed := utils.HashToken(token)
+	pipe := s.redisClient.Pipeline()
+	pipe.Set(ctx, kind+":"+hashed, userID.Hex(), expires)
+	pipe.Set(ctx, userKey, hashed, expires)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeToken returns the user of a token issued by issueToken and invalidates it
+func (s *UserService) consumeToken(ctx context.Context, kind, token string) (*model.User, error) {
+	userID, err := s.redisClient.GetDel(ctx, kind+":"+utils.HashToken(token)).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.redisClient.Del(ctx, kind+"_user:"+userID)
+
+	return s.FindByID(ctx, userID)
+}
+
+// ForgotPassword sends a single-use reset token to the user. Unknown emails are
+// ignored so the endpoint cannot be used to discover accounts.
+func (s *UserService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	expires, err := time.ParseDuration(s.config.PasswordResetExpiresIn)
+	if err != nil {
+		expires = 30 * time.Minute
+	}
+
+	token, err := s.issueToken(ctx, "password_reset", user.ID, expires)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, &notifier.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this link to reset your password, it expires in %s:\n%s/reset-password?token=%s",
+			expires, s.config.ClientURL, token),
+	})
+}
+
+// ResetPassword consumes a reset token, changes the password and signs the user out everywhere
+func (s *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	user, err := s.consumeToken(ctx, "password_reset", token)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return fiber.NewError(fiber.StatusBadRequest, "Reset token is invalid or has expired")
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, string(hashedPassword)); err != nil {
+		return err
+	}
+
+	return s.RevokeSessions(ctx, user.ID)
+}
+
+// SendVerification emails a verification token to an unverified user
+func (s *UserService) SendVerification(ctx context.Context, user *model.User) error {
+	if user.EmailVerified {
+		return fiber.NewError(fiber.StatusBadRequest, "Email is already verified")
+	}
+
+	expires, err := time.ParseDuration(s.config.EmailVerificationExpiresIn)
+	if err != nil {
+		expires = 24 * time.Hour
+	}
+
+	token, err := s.issueToken(ctx, "email_verify", user.ID, expires)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(ctx, &notifier.Message{
+		To:      user.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf("Use this link to verify your email, it expires in %s:\n%s/verify-email?token=%s",
+			expires, s.config.ClientURL, token),
+	})
+}
+
+// ResendVerification sends a new verification token by email address. Unknown
+// and already verified emails are ignored so accounts cannot be discovered.
+func (s *UserService) ResendVerification(ctx context.Context, email string) error {
+	user, err := s.FindByEmail(ctx, email)
+	if err != nil {
+		return err
+	}
+	if user == nil || user.EmailVerified {
+		return nil
+	}
+	return s.SendVerification(ctx, user)
+}
+
+// VerifyEmail consumes a verification token and marks the email as verified
+func (s *UserService) VerifyEmail(ctx context.Context, token string) (*model.User, error) {
+	user, err := s.consumeToken(ctx, "email_verify", token)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Verification token is invalid or has expired")
+	}
+	return s.userRepo.UpdateEmailVerified(ctx, user.ID, true)
+}
+
+// RequiresVerifiedEmail reports whether the user is locked out until the email is verified
+func (s *UserService) RequiresVerifiedEmail(user *model.User) bool {
+	return s.config.EmailVerificationMode == "block" && !user.EmailVerified
+}
+
+// MigrateEmailVerified marks the users registered before email verification existed as verified
+func (s *UserService) MigrateEmailVerified(ctx context.Context) error {
+	return s.userRepo.MigrateEmailVerified(ctx)
+}
+
+func (s *UserService) SetEmailVerified(ctx context.Context, id primitive.ObjectID, verified bool) (*model.User, error) {
+	return s.userRepo.UpdateEmailVerified(ctx, id, verified)
+}
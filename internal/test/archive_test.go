@@ -0,0 +1,65 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/storage"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestFileStoreService_WriteArchive(t *testing.T) {
+	local, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+	require.NoError(t, local.Put(ctx, "blobs/aa/1", strings.NewReader("first"), 5, "image/png"))
+	require.NoError(t, local.Put(ctx, "blobs/bb/2", strings.NewReader("second"), 6, "text/plain"))
+
+	fileStoreService := service.NewFileStoreService(nil, nil, storage.NewBackends(local), nil, nil, &config.Config{})
+	shop := &model.Shop{ID: primitive.NewObjectID(), Name: "Coffee"}
+	files := []model.FileStore{
+		{ID: primitive.NewObjectID(), OriginalName: "logo.png", Backend: "local", Key: "blobs/aa/1", ContentType: "image/png", Size: 5, ScanStatus: model.ScanClean},
+		{ID: primitive.NewObjectID(), OriginalName: "../logo.png", Backend: "local", Key: "blobs/bb/2", ContentType: "text/plain", Size: 6, ScanStatus: model.ScanClean},
+		{ID: primitive.NewObjectID(), OriginalName: "gone.pdf", Backend: "local", Key: "blobs/cc/3", ContentType: "application/pdf", ScanStatus: model.ScanClean},
+		{ID: primitive.NewObjectID(), OriginalName: "invoice.pdf", Backend: "local", Key: "blobs/aa/1", ContentType: "application/pdf", ScanStatus: model.ScanInfected},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, fileStoreService.WriteArchive(ctx, &buf, shop, files))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	contents := map[string]string{}
+	for _, entry := range archive.File {
+		reader, err := entry.Open()
+		require.NoError(t, err)
+		body, _ := io.ReadAll(reader)
+		reader.Close()
+		contents[entry.Name] = string(body)
+	}
+
+	assert.Equal(t, "first", contents["logo.png"])
+	assert.Equal(t, "second", contents["logo (2).png"])
+	assert.Len(t, contents, 3)
+
+	var manifest dto.ArchiveManifest
+	require.NoError(t, json.Unmarshal([]byte(contents[service.ArchiveManifestName]), &manifest))
+	require.Len(t, manifest.Files, 4)
+	assert.Equal(t, "Coffee", manifest.ShopName)
+	assert.Equal(t, "logo (2).png", manifest.Files[1].Path)
+	assert.Empty(t, manifest.Files[2].Path)
+	assert.NotEmpty(t, manifest.Files[2].Error)
+	assert.Empty(t, manifest.Files[3].Path)
+	assert.Contains(t, manifest.Files[3].Error, "quarantined")
+}
@@ -0,0 +1,171 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/middleware"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeAuditRepository keeps the recorded entries in memory
+type fakeAuditRepository struct {
+	entries []model.AuditEntry
+}
+
+func (r *fakeAuditRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeAuditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	r.entries = append(r.entries, *entry)
+	return nil
+}
+
+func (r *fakeAuditRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.AuditEntry, error) {
+	return r.entries, nil
+}
+
+func (r *fakeAuditRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return int64(len(r.entries)), nil
+}
+
+func TestAuditChanges(t *testing.T) {
+	before := &model.User{ID: primitive.NewObjectID(), Name: "Ann", Email: "ann@example.com", Password: "old-hash", Roles: []string{"user"}}
+	after := *before
+	after.Roles = []string{"user", "moderator"}
+	after.Password = "new-hash"
+
+	changes := service.AuditChanges(before, &after)
+
+	assert.Len(t, changes, 1)
+	assert.Equal(t, []interface{}{"user"}, changes["roles"].Before)
+	assert.Equal(t, []interface{}{"user", "moderator"}, changes["roles"].After)
+
+	t.Run("creation lists the set fields", func(t *testing.T) {
+		changes := service.AuditChanges(nil, &model.Category{Name: "Drinks"})
+		assert.Equal(t, "Drinks", changes["name"].After)
+		assert.Nil(t, changes["name"].Before)
+		assert.NotContains(t, changes, "updated_at")
+	})
+}
+
+func TestAuditMiddleware_Record(t *testing.T) {
+	repo := &fakeAuditRepository{}
+	actor := &model.User{ID: primitive.NewObjectID(), Email: "admin@example.com"}
+
+	app := fiber.New()
+	app.Use(func(c *fiber.Ctx) error {
+		c.Locals("user", actor)
+		return c.Next()
+	})
+	app.Use(middleware.NewAuditMiddleware(service.NewAuditService(repo)).Record())
+	app.Get("/shop/:id", func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Put("/shop/:id", func(c *fiber.Ctx) error {
+		middleware.Audit(c, "shop.update", "shop", c.Params("id"), &model.Shop{Name: "Old"}, &model.Shop{Name: "New"})
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Post("/shop/:id/files", func(c *fiber.Ctx) error {
+		middleware.Audit(c, "file.upload", "file", "a", nil, &model.FileStore{Name: "a.png"})
+		middleware.Audit(c, "file.upload", "file", "b", nil, &model.FileStore{Name: "b.png"})
+		return c.SendStatus(fiber.StatusCreated)
+	})
+	app.Post("/shop/:id/uploads", func(c *fiber.Ctx) error {
+		middleware.SkipAudit(c)
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+	app.Delete("/shop/:id", func(c *fiber.Ctx) error {
+		return fiber.NewError(fiber.StatusForbidden, "Unauthorized")
+	})
+
+	for _, request := range []struct{ method, path string }{
+		{fiber.MethodGet, "/shop/1"},
+		{fiber.MethodPut, "/shop/1"},
+		{fiber.MethodPost, "/shop/1/files"},
+		{fiber.MethodPost, "/shop/1/uploads"},
+		{fiber.MethodDelete, "/shop/1"},
+	} {
+		req := httptest.NewRequest(request.method, request.path, nil)
+		req.Header.Set(fiber.HeaderUserAgent, "audit-test")
+		_, err := app.Test(req)
+		assert.NoError(t, err)
+	}
+
+	if assert.Len(t, repo.entries, 4) {
+		update := repo.entries[0]
+		assert.Equal(t, "shop.update", update.Action)
+		assert.Equal(t, "shop", update.ResourceType)
+		assert.Equal(t, "1", update.ResourceID)
+		assert.Equal(t, actor.ID, *update.ActorID)
+		assert.Equal(t, "audit-test", update.UserAgent)
+		assert.Equal(t, fiber.StatusOK, update.Status)
+		assert.Equal(t, model.AuditChange{Before: "Old", After: "New"}, update.Changes["name"])
+
+		// A handler writing several resources leaves an entry for each
+		for i, id := range []string{"a", "b"} {
+			upload := repo.entries[1+i]
+			assert.Equal(t, "file.upload", upload.Action)
+			assert.Equal(t, "file", upload.ResourceType)
+			assert.Equal(t, id, upload.ResourceID)
+			assert.Equal(t, id+".png", upload.Changes["name"].After)
+			assert.Equal(t, fiber.StatusCreated, upload.Status)
+		}
+
+		// Without details from the handler the route is the action
+		denied := repo.entries[3]
+		assert.Equal(t, "delete /shop/:id", denied.Action)
+		assert.Equal(t, fiber.StatusForbidden, denied.Status)
+		assert.Empty(t, denied.Changes)
+	}
+}
+
+// slowAuditRepository holds every insert until release is closed
+type slowAuditRepository struct {
+	fakeAuditRepository
+	release chan struct{}
+}
+
+func (r *slowAuditRepository) Create(ctx context.Context, entry *model.AuditEntry) error {
+	<-r.release
+	return r.fakeAuditRepository.Create(ctx, entry)
+}
+
+func TestAuditService_RecordsInBackground(t *testing.T) {
+	repo := &slowAuditRepository{release: make(chan struct{})}
+	auditService := service.NewAuditService(repo)
+	auditService.Start()
+
+	recorded := make(chan struct{})
+	go func() {
+		for i := 0; i < 3; i++ {
+			auditService.Record(&model.AuditEntry{Action: "shop.update"})
+		}
+		close(recorded)
+	}()
+
+	// The request does not wait for the slow insert
+	select {
+	case <-recorded:
+	case <-time.After(time.Second):
+		t.Fatal("record waited for the insert")
+	}
+
+	// Stop writes what is still queued
+	close(repo.release)
+	auditService.Stop()
+	assert.Len(t, repo.entries, 3)
+
+	// Once stopped, entries are written right away
+	auditService.Record(&model.AuditEntry{Action: "shop.delete"})
+	assert.Len(t, repo.entries, 4)
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/storage"
+	"go-fiber-api/pkg/utils"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeBlobRepository applies each update atomically, as the single document
+// updates of mongo do, with the unique backend and hash index
+type fakeBlobRepository struct {
+	mu    sync.Mutex
+	blobs map[primitive.ObjectID]*model.Blob
+}
+
+func newFakeBlobRepository() *fakeBlobRepository {
+	return &fakeBlobRepository{blobs: map[primitive.ObjectID]*model.Blob{}}
+}
+
+func (r *fakeBlobRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeBlobRepository) Acquire(ctx context.Context, blob *model.Blob) (*model.Blob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.blobs {
+		if existing.Backend != blob.Backend || existing.Hash != blob.Hash {
+			continue
+		}
+		if existing.Deleting {
+			return nil, nil
+		}
+		existing.RefCount++
+		result := *existing
+		return &result, nil
+	}
+	inserted := *blob
+	inserted.ID, inserted.RefCount, inserted.Pending = primitive.NewObjectID(), 1, true
+	r.blobs[inserted.ID] = &inserted
+	result := inserted
+	return &result, nil
+}
+
+func (r *fakeBlobRepository) MarkStored(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if blob, ok := r.blobs[id]; ok {
+		blob.Pending = false
+	}
+	return nil
+}
+
+func (r *fakeBlobRepository) Release(ctx context.Context, backend, key string) (*model.Blob, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, blob := range r.blobs {
+		if blob.Backend == backend && blob.Key == key && blob.RefCount > 0 {
+			blob.RefCount--
+			result := *blob
+			return &result, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeBlobRepository) MarkDeleting(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	blob, ok := r.blobs[id]
+	if !ok || blob.RefCount > 0 || blob.Deleting {
+		return false, nil
+	}
+	blob.Deleting = true
+	return true, nil
+}
+
+func (r *fakeBlobRepository) UnmarkDeleting(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if blob, ok := r.blobs[id]; ok {
+		blob.Deleting = false
+	}
+	return nil
+}
+
+func (r *fakeBlobRepository) Remove(ctx context.Context, id primitive.ObjectID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if blob, ok := r.blobs[id]; ok && blob.Deleting {
+		delete(r.blobs, id)
+	}
+	return nil
+}
+
+func (r *fakeBlobRepository) RemoveByKey(ctx context.Context, backend, key string) error {
+	return nil
+}
+
+func (r *fakeBlobRepository) FindAll(ctx context.Context) ([]model.Blob, error) {
+	return nil, nil
+}
+
+func (r *fakeBlobRepository) SetRefCount(ctx context.Context, id primitive.ObjectID, refCount int64) error {
+	return nil
+}
+
+func (r *fakeBlobRepository) Stats(ctx context.Context) (*model.BlobStats, error) {
+	return &model.BlobStats{}, nil
+}
+
+// pausingStorage holds the first Delete until proceed is closed
+type pausingStorage struct {
+	storage.Storage
+	once     sync.Once
+	deleting chan struct{}
+	proceed  chan struct{}
+}
+
+func (s *pausingStorage) Delete(ctx context.Context, key string) error {
+	s.once.Do(func() {
+		close(s.deleting)
+		<-s.proceed
+	})
+	return s.Storage.Delete(ctx, key)
+}
+
+func TestBlobService_StoreDuringRelease(t *testing.T) {
+	ctx := context.Background()
+	local, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	store := &pausingStorage{Storage: local, deleting: make(chan struct{}), proceed: make(chan struct{})}
+	repo := newFakeBlobRepository()
+	blobService := service.NewBlobService(repo, storage.NewBackends(store))
+
+	data := []byte("same content uploaded twice")
+	hash := utils.HashBytes(data)
+
+	backend, key, err := blobService.Store(ctx, hash, bytes.NewReader(data), int64(len(data)), "text/plain")
+	require.NoError(t, err)
+
+	// The last reference goes and the content is about to be deleted
+	released := make(chan error)
+	go func() {
+		released <- blobService.Release(ctx, backend, key)
+	}()
+	<-store.deleting
+
+	// The same content is uploaded again meanwhile
+	stored := make(chan error)
+	go func() {
+		_, _, err := blobService.Store(ctx, hash, bytes.NewReader(data), int64(len(data)), "text/plain")
+		stored <- err
+	}()
+
+	select {
+	case err := <-stored:
+		t.Fatalf("store returned before the deletion finished: %v", err)
+	case <-time.After(200 * time.Millisecond):
+	}
+	close(store.proceed)
+	require.NoError(t, <-released)
+	require.NoError(t, <-stored)
+
+	// The new reference points at content that is still there
+	_, err = store.Stat(ctx, key)
+	assert.NoError(t, err)
+	if assert.Len(t, repo.blobs, 1) {
+		for _, blob := range repo.blobs {
+			assert.Equal(t, int64(1), blob.RefCount)
+			assert.False(t, blob.Deleting)
+			assert.False(t, blob.Pending)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCategoryService_Update(t *testing.T) {
+	ctx := context.Background()
+	category := &model.Category{ID: primitive.NewObjectID(), Name: "Drinks", ShopID: primitive.NewObjectID()}
+
+	t.Run("renames within the shop", func(t *testing.T) {
+		mockRepo := &MockCategoryRepository{}
+		renamed := *category
+		renamed.Name = "Coffee"
+		mockRepo.On("UpdateByID", ctx, category.ID, bson.M{"name": "Coffee"}).Return(&renamed, nil)
+
+		categoryService := service.NewCategoryService(mockRepo)
+		updated, err := categoryService.Update(ctx, category, &dto.UpdateCategoryRequest{ShopId: category.ShopID.Hex(), Name: " Coffee "})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Coffee", updated.Name)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("refuses another shop", func(t *testing.T) {
+		mockRepo := &MockCategoryRepository{}
+		categoryService := service.NewCategoryService(mockRepo)
+		_, err := categoryService.Update(ctx, category, &dto.UpdateCategoryRequest{ShopId: primitive.NewObjectID().Hex(), Name: "Coffee"})
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+		mockRepo.AssertNotCalled(t, "UpdateByID", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("reports a taken name as a conflict", func(t *testing.T) {
+		mockRepo := &MockCategoryRepository{}
+		duplicate := mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000, Message: "duplicate key"}}}
+		mockRepo.On("UpdateByID", ctx, category.ID, bson.M{"name": "Food"}).Return((*model.Category)(nil), duplicate)
+
+		categoryService := service.NewCategoryService(mockRepo)
+		_, err := categoryService.Update(ctx, category, &dto.UpdateCategoryRequest{Name: "Food"})
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusConflict, fiberErr.Code)
+	})
+}
+
+// categoryTree builds Drinks > Coffee > Espresso and Food in one shop
+func categoryTree() (shopID primitive.ObjectID, drinks, coffee, espresso, food model.Category) {
+	shopID = primitive.NewObjectID()
+	newCategory := func(name string, parent *model.Category, position int) model.Category {
+		category := model.Category{ID: primitive.NewObjectID(), Name: name, ShopID: shopID, Position: position}
+		parentPath := ""
+		if parent != nil {
+			category.ParentID, parentPath = &parent.ID, parent.Path
+		}
+		category.Path = model.CategoryPath(parentPath, category.ID)
+		return category
+	}
+	drinks = newCategory("Drinks", nil, 0)
+	coffee = newCategory("Coffee", &drinks, 0)
+	espresso = newCategory("Espresso", &coffee, 0)
+	food = newCategory("Food", nil, 1)
+	return
+}
+
+func TestCategoryService_Tree(t *testing.T) {
+	ctx := context.Background()
+	shopID, drinks, coffee, espresso, food := categoryTree()
+
+	mockRepo := &MockCategoryRepository{}
+	mockRepo.On("FindAll", ctx, bson.M{"shop_id": shopID}).Return([]model.Category{drinks, coffee, espresso, food}, nil)
+
+	tree, err := service.NewCategoryService(mockRepo).Tree(ctx, shopID)
+
+	assert.NoError(t, err)
+	assert.Len(t, tree, 2)
+	assert.Equal(t, "Drinks", tree[0].Name)
+	assert.Equal(t, "Food", tree[1].Name)
+	assert.Equal(t, "Coffee", tree[0].Children[0].Name)
+	assert.Equal(t, "Espresso", tree[0].Children[0].Children[0].Name)
+	assert.Empty(t, tree[1].Children)
+}
+
+func TestCategoryService_Move(t *testing.T) {
+	ctx := context.Background()
+	_, drinks, coffee, espresso, food := categoryTree()
+
+	t.Run("refuses a cycle", func(t *testing.T) {
+		mockRepo := &MockCategoryRepository{}
+		mockRepo.On("Get", ctx, espresso.ID).Return(&espresso, nil)
+		mockRepo.On("Get", ctx, drinks.ID).Return(&drinks, nil)
+		categoryService := service.NewCategoryService(mockRepo)
+
+		_, err := categoryService.Move(ctx, &drinks, &dto.MoveCategoryRequest{ParentId: espresso.ID.Hex()})
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+
+		_, err = categoryService.Move(ctx, &drinks, &dto.MoveCategoryRequest{ParentId: drinks.ID.Hex()})
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "Move", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rewrites the path below the new parent", func(t *testing.T) {
+		mockRepo := &MockCategoryRepository{}
+		mockRepo.On("Get", ctx, food.ID).Return(&food, nil)
+		mockRepo.On("Move", ctx, &coffee, &food.ID, food.Path+coffee.ID.Hex()+"/", 2).Return(nil)
+		mockRepo.On("Get", ctx, coffee.ID).Return(&coffee, nil)
+		position := 2
+
+		_, err := service.NewCategoryService(mockRepo).Move(ctx, &coffee, &dto.MoveCategoryRequest{ParentId: food.ID.Hex(), Position: &position})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestCategoryService_DeleteRefusesSubcategories(t *testing.T) {
+	ctx := context.Background()
+	_, drinks, _, _, _ := categoryTree()
+
+	mockRepo := &MockCategoryRepository{}
+	mockRepo.On("Count", ctx, bson.M{"shop_id": drinks.ShopID, "parent_id": drinks.ID}).Return(int64(1), nil)
+
+	_, err := service.NewCategoryService(mockRepo).Delete(ctx, &drinks, false, primitive.NewObjectID())
+
+	var fiberErr *fiber.Error
+	assert.True(t, errors.As(err, &fiberErr))
+	assert.Equal(t, fiber.StatusConflict, fiberErr.Code)
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeDeletionRepository knows how many documents each collection holds for
+// the deleted resource and records what was deleted or updated
+type fakeDeletionRepository struct {
+	counts       map[string]int64
+	ids          map[string][]primitive.ObjectID
+	deleted      []string
+	updated      []string
+	owners       []primitive.ObjectID
+	transactions int
+}
+
+func (r *fakeDeletionRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.transactions++
+	return fn(ctx)
+}
+
+func (r *fakeDeletionRepository) Count(ctx context.Context, collection string, query bson.M) (int64, error) {
+	return r.counts[collection], nil
+}
+
+func (r *fakeDeletionRepository) FindIDs(ctx context.Context, collection string, query bson.M) ([]primitive.ObjectID, error) {
+	return r.ids[collection], nil
+}
+
+func (r *fakeDeletionRepository) FindFiles(ctx context.Context, query bson.M) ([]model.FileStore, error) {
+	return nil, nil
+}
+
+func (r *fakeDeletionRepository) DeleteMany(ctx context.Context, collection string, query bson.M) (int64, error) {
+	r.deleted = append(r.deleted, collection)
+	return r.counts[collection], nil
+}
+
+func (r *fakeDeletionRepository) UpdateMany(ctx context.Context, collection string, query bson.M, set bson.M) (int64, error) {
+	r.updated = append(r.updated, collection)
+	return 0, nil
+}
+
+func (r *fakeDeletionRepository) UpsertOwner(ctx context.Context, shopID primitive.ObjectID, owner *model.User, invitedBy primitive.ObjectID) error {
+	r.owners = append(r.owners, owner.ID)
+	return nil
+}
+
+func TestParseDeletionPolicies(t *testing.T) {
+	policies, err := service.ParseDeletionPolicies(nil)
+	require.NoError(t, err)
+	assert.Equal(t, service.PolicyBlock, policies[service.RelationUserShops])
+	assert.Equal(t, service.PolicyCascade, policies[service.RelationShopFiles])
+
+	policies, err = service.ParseDeletionPolicies([]string{"user.shops:reassign", "shop.budget_transactions:block"})
+	require.NoError(t, err)
+	assert.Equal(t, service.PolicyReassign, policies[service.RelationUserShops])
+	assert.Equal(t, service.PolicyBlock, policies[service.RelationShopBudget])
+
+	_, err = service.ParseDeletionPolicies([]string{"shop.files:reassign"})
+	assert.Error(t, err)
+	_, err = service.ParseDeletionPolicies([]string{"shop.reviews:cascade"})
+	assert.Error(t, err)
+}
+
+func TestDeletionService_DeleteUser(t *testing.T) {
+	ctx := context.Background()
+	user := &model.User{ID: primitive.NewObjectID(), Email: "Owner@example.com"}
+	admin := &model.User{ID: primitive.NewObjectID(), Email: "admin@example.com"}
+	newRepo := func() *fakeDeletionRepository {
+		return &fakeDeletionRepository{
+			counts: map[string]int64{"shop_members": 2, "file_stores": 3, "categories": 1},
+			ids:    map[string][]primitive.ObjectID{"shops": {primitive.NewObjectID()}},
+		}
+	}
+
+	t.Run("blocked by owned shops", func(t *testing.T) {
+		repo := newRepo()
+		deletionService, err := service.NewDeletionService(repo, nil, &config.Config{})
+		require.NoError(t, err)
+
+		report, err := deletionService.DeleteUser(ctx, user, admin, nil, false)
+
+		var fiberErr *fiber.Error
+		require.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusConflict, fiberErr.Code)
+		assert.Equal(t, []string{service.RelationUserShops}, report.Blocked)
+		assert.False(t, report.Deleted)
+		assert.Empty(t, repo.deleted)
+	})
+
+	t.Run("dry run reports the cascade", func(t *testing.T) {
+		repo := newRepo()
+		deletionService, err := service.NewDeletionService(repo, nil, &config.Config{})
+		require.NoError(t, err)
+
+		report, err := deletionService.DeleteUser(ctx, user, admin, []string{"user.shops:cascade"}, true)
+
+		require.NoError(t, err)
+		assert.True(t, report.DryRun)
+		assert.False(t, report.Deleted)
+		counts := map[string]int64{}
+		for _, relation := range report.Relations {
+			counts[relation.Relation] = relation.Count
+		}
+		assert.Equal(t, int64(1), counts[service.RelationUserShops])
+		assert.Equal(t, int64(3), counts[service.RelationShopFiles])
+		assert.Equal(t, int64(2), counts[service.RelationUserMemberships])
+		assert.Empty(t, repo.deleted)
+		assert.Zero(t, repo.transactions)
+	})
+
+	t.Run("reassigns shops in a transaction", func(t *testing.T) {
+		repo := newRepo()
+		deletionService, err := service.NewDeletionService(repo, nil, &config.Config{DeletionPolicies: []string{"user.shops:reassign"}})
+		require.NoError(t, err)
+
+		report, err := deletionService.DeleteUser(ctx, user, admin, nil, false)
+
+		require.NoError(t, err)
+		assert.True(t, report.Deleted)
+		assert.Equal(t, &admin.ID, report.NewOwner)
+		assert.Equal(t, 1, repo.transactions)
+		assert.Equal(t, []string{"shops"}, repo.updated)
+		assert.Equal(t, []primitive.ObjectID{admin.ID}, repo.owners)
+		assert.Equal(t, []string{"shop_members", "users"}, repo.deleted)
+
+		_, err = deletionService.DeleteUser(ctx, user, user, nil, false)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/utils"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type MockRoleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRoleRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockRoleRepository) FindAll(ctx context.Context) ([]model.Role, error) {
+	return nil, nil
+}
+
+func (m *MockRoleRepository) FindByNames(ctx context.Context, names []string) ([]model.Role, error) {
+	args := m.Called(ctx, names)
+	return args.Get(0).([]model.Role), args.Error(1)
+}
+
+func (m *MockRoleRepository) Seed(ctx context.Context, name string, defaults []string) error {
+	return nil
+}
+
+func (m *MockRoleRepository) Upsert(ctx context.Context, name string, permissions []string) (*model.Role, error) {
+	return nil, nil
+}
+
+func TestPolicyService_AuthorizeShop(t *testing.T) {
+	mockRepo := &MockRoleRepository{}
+	policyService := service.NewPolicyService(mockRepo)
+
+	ctx := context.Background()
+	owner := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	other := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	staff := &model.User{ID: primitive.NewObjectID(), Roles: []string{"user"}}
+	moderator := &model.User{ID: primitive.NewObjectID(), Roles: []string{"moderator"}}
+	shop := &model.Shop{
+		ID:        primitive.NewObjectID(),
+		CreatedBy: owner.ID,
+		Members: []*model.ShopMember{
+			{UserID: &owner.ID, Role: string(utils.ShopOwner), Status: model.MemberAccepted},
+			{UserID: &staff.ID, Role: string(utils.ShopStaff), Status: model.MemberAccepted},
+		},
+		HasMembers: true,
+	}
+
+	mockRepo.On("FindByNames", ctx, []string{"user"}).Return([]model.Role{
+		{Name: "user", Permissions: []string{string(utils.ShopUpdate.Own())}},
+	}, nil)
+	mockRepo.On("FindByNames", ctx, []string{"moderator"}).Return([]model.Role{
+		{Name: "moderator", Permissions: []string{string(utils.ShopUpdate.Any())}},
+	}, nil)
+
+	assert.NoError(t, policyService.AuthorizeShop(ctx, owner, utils.ShopUpdate, shop))
+	assert.Error(t, policyService.AuthorizeShop(ctx, other, utils.ShopUpdate, shop))
+	assert.Error(t, policyService.AuthorizeShop(ctx, staff, utils.ShopUpdate, shop))
+	assert.NoError(t, policyService.AuthorizeShop(ctx, moderator, utils.ShopUpdate, shop))
+	assert.Error(t, policyService.AuthorizeShop(ctx, moderator, utils.ShopDelete, shop))
+}
+
+func TestShopRoleOf_CreatorFallback(t *testing.T) {
+	creator := primitive.NewObjectID()
+	staff := primitive.NewObjectID()
+
+	// Not migrated yet, the creator owns the shop
+	shop := &model.Shop{CreatedBy: creator}
+	assert.Equal(t, utils.ShopOwner, service.ShopRoleOf(shop, creator))
+
+	// Demoted
+	shop.Members = []*model.ShopMember{{UserID: &creator, Role: string(utils.ShopStaff), Status: model.MemberAccepted}}
+	shop.HasMembers = true
+	assert.Equal(t, utils.ShopStaff, service.ShopRoleOf(shop, creator))
+
+	// Removed, while another member remains
+	shop.Members = []*model.ShopMember{{UserID: &staff, Role: string(utils.ShopOwner), Status: model.MemberAccepted}}
+	assert.Equal(t, utils.ShopRole(""), service.ShopRoleOf(shop, creator))
+
+	// Removed, only an invitation is left
+	shop.Members = nil
+	assert.Equal(t, utils.ShopRole(""), service.ShopRoleOf(shop, creator))
+}
+
+func TestShopMemberService_InvitationsNeedVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	memberService := service.NewShopMemberService(nil, nil, &config.Config{EmailVerificationMode: "limit"})
+	user := &model.User{ID: primitive.NewObjectID(), Email: "invitee@example.com"}
+
+	for _, err := range []error{
+		func() error { _, err := memberService.Invitations(ctx, user); return err }(),
+		func() error { _, err := memberService.Respond(ctx, user, primitive.NewObjectID(), true); return err }(),
+	} {
+		var fiberErr *fiber.Error
+		if assert.ErrorAs(t, err, &fiberErr) {
+			assert.Equal(t, fiber.StatusForbidden, fiberErr.Code)
+		}
+	}
+
+	// With verification off, invitations are open to unverified users
+	memberService = service.NewShopMemberService(newFakeShopMemberRepository(), nil, &config.Config{EmailVerificationMode: "off"})
+	_, err := memberService.Invitations(ctx, user)
+	assert.NoError(t, err)
+}
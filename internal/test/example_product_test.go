@@ -0,0 +1,156 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type MockProductRepository struct {
+	mock.Mock
+}
+
+func (m *MockProductRepository) Create(ctx context.Context, product *model.Product) (*model.Product, error) {
+	args := m.Called(ctx, product)
+	return args.Get(0).(*model.Product), args.Error(1)
+}
+
+func (m *MockProductRepository) FindOne(ctx context.Context, query bson.M) (*model.Product, error) {
+	return nil, nil
+}
+
+func (m *MockProductRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Product, error) {
+	return nil, nil
+}
+
+func (m *MockProductRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockProductRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateProductRequest) (*model.Product, error) {
+	return nil, nil
+}
+
+func (m *MockProductRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+type MockCategoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockCategoryRepository) Create(ctx context.Context, category *model.Category) (*model.Category, error) {
+	return nil, nil
+}
+
+func (m *MockCategoryRepository) Get(ctx context.Context, id primitive.ObjectID) (*model.Category, error) {
+	args := m.Called(ctx, id)
+	return args.Get(0).(*model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockCategoryRepository) FindAll(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.Category, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).([]model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCategoryRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.Category, error) {
+	args := m.Called(ctx, id, set)
+	return args.Get(0).(*model.Category), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Delete(ctx context.Context, query bson.M, deletedBy primitive.ObjectID) (int64, error) {
+	args := m.Called(ctx, query, deletedBy)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCategoryRepository) Restore(ctx context.Context, query bson.M) (int64, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockCategoryRepository) MigrateTree(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockCategoryRepository) NextPosition(ctx context.Context, shopID primitive.ObjectID, parentID *primitive.ObjectID) (int, error) {
+	return 0, nil
+}
+
+func (m *MockCategoryRepository) Move(ctx context.Context, category *model.Category, parentID *primitive.ObjectID, path string, position int) error {
+	args := m.Called(ctx, category, parentID, path, position)
+	return args.Error(0)
+}
+
+func (m *MockCategoryRepository) Reorder(ctx context.Context, shopID primitive.ObjectID, ids []primitive.ObjectID) error {
+	return nil
+}
+
+func TestProductService_Create(t *testing.T) {
+	mockRepo := &MockProductRepository{}
+	mockCategoryRepo := &MockCategoryRepository{}
+	productService := service.NewProductService(mockRepo, mockCategoryRepo, nil)
+
+	ctx := context.Background()
+	shop := &model.Shop{ID: primitive.NewObjectID()}
+	category := &model.Category{ID: primitive.NewObjectID(), ShopID: shop.ID}
+	payload := &dto.ProductRequest{
+		Name:       "Espresso",
+		Price:      55,
+		CategoryID: category.ID.Hex(),
+	}
+
+	mockCategoryRepo.On("Get", ctx, category.ID).Return(category, nil)
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(p *model.Product) bool {
+		return p.Name == payload.Name &&
+			p.ShopID == shop.ID &&
+			p.CategoryID == category.ID
+	})).Return(&model.Product{Name: payload.Name, ShopID: shop.ID, CategoryID: category.ID}, nil)
+
+	result, err := productService.Create(ctx, payload, shop)
+
+	assert.NoError(t, err)
+	assert.Equal(t, category.ID, result.CategoryID)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestProductService_CreateRejectsForeignCategory(t *testing.T) {
+	mockRepo := &MockProductRepository{}
+	mockCategoryRepo := &MockCategoryRepository{}
+	productService := service.NewProductService(mockRepo, mockCategoryRepo, nil)
+
+	ctx := context.Background()
+	shop := &model.Shop{ID: primitive.NewObjectID()}
+	category := &model.Category{ID: primitive.NewObjectID(), ShopID: primitive.NewObjectID()}
+	payload := &dto.ProductRequest{
+		Name:       "Espresso",
+		Price:      55,
+		CategoryID: category.ID.Hex(),
+	}
+
+	mockCategoryRepo.On("Get", ctx, category.ID).Return(category, nil)
+
+	_, err := productService.Create(ctx, payload, shop)
+
+	var fiberErr *fiber.Error
+	assert.ErrorAs(t, err, &fiberErr)
+	assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -2,11 +2,14 @@ package test
 
 import (
 	"context"
+	"go-fiber-api/internal/handlers"
 	"go-fiber-api/internal/model"
 	"go-fiber-api/internal/service"
 	"go-fiber-api/pkg/dto"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/gofiber/fiber/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
@@ -18,6 +21,10 @@ type MockShopRepository struct {
 	mock.Mock
 }
 
+func (m *MockShopRepository) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
 func (m *MockShopRepository) Create(ctx context.Context, shop *model.Shop) (*model.Shop, error) {
 	args := m.Called(ctx, shop)
 	return args.Get(0).(*model.Shop), args.Error(1)
@@ -39,7 +46,28 @@ func (m *MockShopRepository) UpdateByID(ctx context.Context, id primitive.Object
 	return nil, nil
 }
 
-func (m *MockShopRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+func (m *MockShopRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	return nil
+}
+
+func (m *MockShopRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+func (m *MockShopRepository) MigrateBudgets(ctx context.Context) error {
+	return nil
+}
+
+func (m *MockShopRepository) AddStorageUsed(ctx context.Context, id primitive.ObjectID, delta, limit int64) (bool, error) {
+	args := m.Called(ctx, id, delta, limit)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockShopRepository) UpdateStoragePlan(ctx context.Context, id primitive.ObjectID, plan string, quota *int64) (*model.Shop, error) {
+	return nil, nil
+}
+
+func (m *MockShopRepository) MigrateStorageUsed(ctx context.Context) error {
 	return nil
 }
 
@@ -73,3 +101,14 @@ func TestShopService_Create(t *testing.T) {
 	assert.Equal(t, expectedShop, result)
 	mockRepo.AssertExpectations(t)
 }
+
+func TestShopHandler_GetShopNotFound(t *testing.T) {
+	handler := handlers.NewShopHandler(service.NewShopService(&MockShopRepository{}), nil, nil, nil, nil)
+	app := fiber.New()
+	app.Get("/shop/:id", handler.GetShop)
+
+	resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/shop/"+primitive.NewObjectID().Hex(), nil))
+
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusNotFound, resp.StatusCode)
+}
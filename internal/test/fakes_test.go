@@ -0,0 +1,408 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/pkg/utils"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeRedis speaks enough RESP2 for the tus uploads, the signed links and the user sessions:
+// strings, hashes, sets, transactions and WATCH. Only expirations given with
+// SET take effect, the others are accepted and ignored.
+type fakeRedis struct {
+	mu       sync.Mutex
+	strings  map[string]string
+	hashes   map[string]map[string]string
+	sets     map[string]map[string]bool
+	expires  map[string]time.Time
+	versions map[string]int
+}
+
+func newFakeRedis(t *testing.T) (*fakeRedis, *redis.Client) {
+	f := &fakeRedis{
+		strings:  map[string]string{},
+		hashes:   map[string]map[string]string{},
+		sets:     map[string]map[string]bool{},
+		expires:  map[string]time.Time{},
+		versions: map[string]int{},
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2})
+	t.Cleanup(func() { client.Close() })
+	return f, client
+}
+
+// Set changes a key the way another client would
+func (f *fakeRedis) Set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.strings[key] = value
+	f.versions[key]++
+}
+
+func (f *fakeRedis) Get(key string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expire(key)
+	return f.strings[key]
+}
+
+// expire drops key once its SET expiration passed
+func (f *fakeRedis) expire(key string) {
+	if deadline, ok := f.expires[key]; ok && !time.Now().Before(deadline) {
+		delete(f.strings, key)
+		delete(f.expires, key)
+		f.versions[key]++
+	}
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+
+	var queued [][]string
+	inMulti := false
+	watched := map[string]int{}
+	for {
+		args, err := readCommand(reader)
+		if err != nil {
+			return
+		}
+
+		var reply string
+		switch name := strings.ToUpper(args[0]); {
+		case name == "MULTI":
+			inMulti, queued, reply = true, nil, "+OK\r\n"
+		case name == "EXEC":
+			f.mu.Lock()
+			dirty := false
+			for key, version := range watched {
+				dirty = dirty || f.versions[key] != version
+			}
+			if dirty {
+				reply = "*-1\r\n"
+			} else {
+				reply = fmt.Sprintf("*%d\r\n", len(queued))
+				for _, command := range queued {
+					reply += f.exec(command)
+				}
+			}
+			f.mu.Unlock()
+			inMulti, queued, watched = false, nil, map[string]int{}
+		case name == "DISCARD":
+			inMulti, queued, watched, reply = false, nil, map[string]int{}, "+OK\r\n"
+		case name == "WATCH":
+			f.mu.Lock()
+			for _, key := range args[1:] {
+				watched[key] = f.versions[key]
+			}
+			f.mu.Unlock()
+			reply = "+OK\r\n"
+		case name == "UNWATCH":
+			watched, reply = map[string]int{}, "+OK\r\n"
+		case inMulti:
+			queued, reply = append(queued, args), "+QUEUED\r\n"
+		default:
+			f.mu.Lock()
+			reply = f.exec(args)
+			f.mu.Unlock()
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// exec runs a command with the lock held and returns its encoded reply
+func (f *fakeRedis) exec(args []string) string {
+	for _, key := range args[1:] {
+		f.expire(key)
+	}
+	switch strings.ToUpper(args[0]) {
+	case "PING":
+		return "+PONG\r\n"
+	case "CLIENT", "EXPIREAT", "EXPIRE", "PEXPIRE":
+		return ":1\r\n"
+	case "GET", "GETDEL":
+		value, ok := f.strings[args[1]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		if strings.ToUpper(args[0]) == "GETDEL" {
+			delete(f.strings, args[1])
+			delete(f.expires, args[1])
+			f.versions[args[1]]++
+		}
+		return bulk(value)
+	case "SET":
+		if _, exists := f.strings[args[1]]; exists && hasOption(args[3:], "NX") {
+			return "$-1\r\n"
+		}
+		f.strings[args[1]] = args[2]
+		delete(f.expires, args[1])
+		for i := 3; i+1 < len(args); i++ {
+			amount, err := strconv.Atoi(args[i+1])
+			switch {
+			case err != nil:
+			case strings.EqualFold(args[i], "EX"):
+				f.expires[args[1]] = time.Now().Add(time.Duration(amount) * time.Second)
+			case strings.EqualFold(args[i], "PX"):
+				f.expires[args[1]] = time.Now().Add(time.Duration(amount) * time.Millisecond)
+			}
+		}
+		f.versions[args[1]]++
+		return "+OK\r\n"
+	case "DEL", "EXISTS":
+		count := 0
+		for _, key := range args[1:] {
+			_, isString := f.strings[key]
+			_, isHash := f.hashes[key]
+			_, isSet := f.sets[key]
+			if isString || isHash || isSet {
+				count++
+			}
+			if strings.ToUpper(args[0]) == "DEL" {
+				delete(f.strings, key)
+				delete(f.hashes, key)
+				delete(f.sets, key)
+				delete(f.expires, key)
+				f.versions[key]++
+			}
+		}
+		return ":" + strconv.Itoa(count) + "\r\n"
+	case "SADD":
+		set := f.sets[args[1]]
+		if set == nil {
+			set = map[string]bool{}
+			f.sets[args[1]] = set
+		}
+		for _, member := range args[2:] {
+			set[member] = true
+		}
+		f.versions[args[1]]++
+		return ":" + strconv.Itoa(len(args)-2) + "\r\n"
+	case "SMEMBERS":
+		reply := fmt.Sprintf("*%d\r\n", len(f.sets[args[1]]))
+		for member := range f.sets[args[1]] {
+			reply += bulk(member)
+		}
+		return reply
+	case "HSET":
+		hash := f.hashes[args[1]]
+		if hash == nil {
+			hash = map[string]string{}
+			f.hashes[args[1]] = hash
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			hash[args[i]] = args[i+1]
+		}
+		f.versions[args[1]]++
+		return ":" + strconv.Itoa((len(args)-2)/2) + "\r\n"
+	case "HGETALL":
+		hash := f.hashes[args[1]]
+		reply := fmt.Sprintf("*%d\r\n", len(hash)*2)
+		for field, value := range hash {
+			reply += bulk(field) + bulk(value)
+		}
+		return reply
+	default:
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	}
+}
+
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, count)
+	for i := range args {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return nil, err
+		}
+		args[i] = string(data[:size])
+	}
+	return args, nil
+}
+
+func hasOption(args []string, option string) bool {
+	for _, arg := range args {
+		if strings.EqualFold(arg, option) {
+			return true
+		}
+	}
+	return false
+}
+
+func bulk(value string) string {
+	return fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+}
+
+// fakeFileStoreRepository keeps the created files in memory
+type fakeFileStoreRepository struct {
+	repository.FileStoreRepository
+	files []*model.FileStore
+}
+
+func (r *fakeFileStoreRepository) NextPosition(ctx context.Context, shopID primitive.ObjectID) (int, error) {
+	return len(r.files), nil
+}
+
+func (r *fakeFileStoreRepository) Create(ctx context.Context, files []*model.FileStore) ([]*model.FileStore, error) {
+	for _, file := range files {
+		file.ID = primitive.NewObjectID()
+		r.files = append(r.files, file)
+	}
+	return files, nil
+}
+
+func (r *fakeFileStoreRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) (*model.FileStore, error) {
+	for _, file := range r.files {
+		if file.ID == id {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeFileStoreRepository) FindOne(ctx context.Context, query bson.M) (*model.FileStore, error) {
+	for _, file := range r.files {
+		if file.ID == query["_id"] {
+			return file, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeFileStoreRepository) FindAll(ctx context.Context, query bson.M) ([]model.FileStore, error) {
+	return nil, nil
+}
+
+func (r *fakeFileStoreRepository) FindPage(ctx context.Context, query bson.M, opts *options.FindOptions) ([]model.FileStore, error) {
+	return nil, nil
+}
+
+// fakeShopMemberRepository keeps the members in memory, UpdateRole and Delete
+// apply the last owner check together with the write as the transaction does
+type fakeShopMemberRepository struct {
+	members map[primitive.ObjectID]*model.ShopMember
+}
+
+func newFakeShopMemberRepository(members ...*model.ShopMember) *fakeShopMemberRepository {
+	r := &fakeShopMemberRepository{members: map[primitive.ObjectID]*model.ShopMember{}}
+	for _, member := range members {
+		r.members[member.ID] = member
+	}
+	return r
+}
+
+func (r *fakeShopMemberRepository) hasOwner(shopID primitive.ObjectID) bool {
+	for _, member := range r.members {
+		if member.ShopID == shopID && member.Role == string(utils.ShopOwner) && member.Status == model.MemberAccepted {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeShopMemberRepository) EnsureIndexes(ctx context.Context) error {
+	return nil
+}
+
+func (r *fakeShopMemberRepository) Create(ctx context.Context, member *model.ShopMember) (*model.ShopMember, error) {
+	r.members[member.ID] = member
+	return member, nil
+}
+
+func (r *fakeShopMemberRepository) FindOne(ctx context.Context, query bson.M) (*model.ShopMember, error) {
+	return r.members[query["_id"].(primitive.ObjectID)], nil
+}
+
+func (r *fakeShopMemberRepository) FindAll(ctx context.Context, query bson.M) ([]model.ShopMember, error) {
+	return nil, nil
+}
+
+func (r *fakeShopMemberRepository) Count(ctx context.Context, query bson.M) (int64, error) {
+	return int64(len(r.members)), nil
+}
+
+func (r *fakeShopMemberRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, set bson.M) (*model.ShopMember, error) {
+	return r.members[id], nil
+}
+
+func (r *fakeShopMemberRepository) UpdateRole(ctx context.Context, id primitive.ObjectID, role string) (*model.ShopMember, error) {
+	member := r.members[id]
+	previous := member.Role
+	member.Role = role
+	if !r.hasOwner(member.ShopID) {
+		member.Role = previous
+		return nil, repository.ErrLastOwner
+	}
+	return member, nil
+}
+
+func (r *fakeShopMemberRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	member := r.members[id]
+	delete(r.members, id)
+	if !r.hasOwner(member.ShopID) {
+		r.members[id] = member
+		return repository.ErrLastOwner
+	}
+	return nil
+}
+
+func (r *fakeShopMemberRepository) MigrateCreators(ctx context.Context) error {
+	return nil
+}
+
+func assertStatus(t *testing.T, err error, status int) {
+	t.Helper()
+	var fiberErr *fiber.Error
+	if assert.ErrorAs(t, err, &fiberErr) {
+		assert.Equal(t, status, fiberErr.Code)
+	}
+}
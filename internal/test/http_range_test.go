@@ -0,0 +1,44 @@
+package test
+
+import (
+	"go-fiber-api/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRange(t *testing.T) {
+	cases := map[string][]utils.ByteRange{
+		"":                  nil,
+		"items=0-1":         nil,
+		"bytes=0-499":       {{Start: 0, Length: 500}},
+		"bytes=500-":        {{Start: 500, Length: 500}},
+		"bytes=-200":        {{Start: 800, Length: 200}},
+		"bytes=-5000":       {{Start: 0, Length: 1000}},
+		"bytes=900-5000":    {{Start: 900, Length: 100}},
+		"bytes=0-0, 10-19":  {{Start: 0, Length: 1}, {Start: 10, Length: 10}},
+		"bytes=5-1":         nil,
+		"bytes=abc":         nil,
+		"bytes=2000-, 0-9":  {{Start: 0, Length: 10}},
+		"bytes = 100 - 199": {{Start: 100, Length: 100}},
+	}
+	for header, expected := range cases {
+		ranges, err := utils.ParseRange(header, 1000)
+		assert.NoError(t, err, header)
+		assert.Equal(t, expected, ranges, header)
+	}
+
+	for _, header := range []string{"bytes=1000-", "bytes=-0", "bytes=5000-6000"} {
+		_, err := utils.ParseRange(header, 1000)
+		assert.ErrorIs(t, err, utils.ErrUnsatisfiableRange, header)
+	}
+
+	assert.Equal(t, "bytes 10-19/1000", utils.ByteRange{Start: 10, Length: 10}.ContentRange(1000))
+}
+
+func TestETagMatches(t *testing.T) {
+	assert.True(t, utils.ETagMatches(`"abc"`, `"abc"`))
+	assert.True(t, utils.ETagMatches(`"x", W/"abc"`, `"abc"`))
+	assert.True(t, utils.ETagMatches(`*`, `"abc"`))
+	assert.False(t, utils.ETagMatches(`"abcd"`, `"abc"`))
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go-fiber-api/pkg/imaging"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jpegWithOrientation encodes a width x height JPEG carrying an EXIF orientation tag
+func jpegWithOrientation(t *testing.T, width, height, orientation int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: 200, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, nil))
+	data := buf.Bytes()
+
+	// Big endian TIFF with a single IFD entry: orientation, SHORT, count 1
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, byte(orientation), 0, 0, 0, 0, 0, 0}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	length := len(payload) + 2
+	app1 := append([]byte{0xFF, 0xE1, byte(length >> 8), byte(length)}, payload...)
+
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestImaging_OrientationAndStrip(t *testing.T) {
+	data := jpegWithOrientation(t, 40, 20, 6)
+	assert.Equal(t, 6, imaging.Orientation(data))
+
+	img, err := imaging.Decode(data, 0)
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 20, 40), img.Bounds())
+
+	stripped := imaging.StripMetadata(data)
+	assert.Less(t, len(stripped), len(data))
+	assert.Equal(t, 1, imaging.Orientation(stripped))
+	_, err = jpeg.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
+
+func TestImaging_Render(t *testing.T) {
+	img, err := imaging.Decode(jpegWithOrientation(t, 400, 200, 1), 0)
+	require.NoError(t, err)
+
+	variants, err := imaging.ParseVariants("thumb:100x100:png,full:0x0:jpeg")
+	require.NoError(t, err)
+
+	out, err := imaging.Render(img, variants[0])
+	require.NoError(t, err)
+	thumb, format, err := image.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, "png", format)
+	assert.Equal(t, image.Rect(0, 0, 100, 50), thumb.Bounds())
+
+	out, err = imaging.Render(img, variants[1])
+	require.NoError(t, err)
+	full, err := jpeg.Decode(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 400, 200), full.Bounds())
+
+	_, err = imaging.ParseVariants("thumb:100:webp")
+	assert.Error(t, err)
+}
+
+func TestImaging_DecodeRefusesTooManyPixels(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))))
+	data := buf.Bytes()
+
+	// Declare 50000x50000 pixels in IHDR, a few bytes that would decode to gigabytes
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:], 50000)
+	binary.BigEndian.PutUint32(ihdr[4:], 50000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	_, err := imaging.Decode(data, 40000000)
+	assert.ErrorIs(t, err, imaging.ErrImageTooLarge)
+
+	_, err = imaging.Decode(jpegWithOrientation(t, 400, 200, 1), 400*200)
+	assert.NoError(t, err)
+}
+
+func TestImaging_StripWebPAndGIF(t *testing.T) {
+	chunk := func(fourCC string, payload []byte) []byte {
+		out := binary.LittleEndian.AppendUint32([]byte(fourCC), uint32(len(payload)))
+		out = append(out, payload...)
+		if len(payload)%2 == 1 {
+			out = append(out, 0)
+		}
+		return out
+	}
+	var body []byte
+	body = append(body, "WEBP"...)
+	body = append(body, chunk("VP8X", []byte{0x0C, 0, 0, 0, 0, 0, 0, 0, 0, 0})...)
+	body = append(body, chunk("VP8L", []byte{1, 2, 3, 4, 5})...)
+	body = append(body, chunk("EXIF", []byte("GPS 52.37N 4.89E"))...)
+	body = append(body, chunk("XMP ", []byte("<x:xmpmeta/>"))...)
+	webp := append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+
+	stripped := imaging.StripMetadata(webp)
+	assert.NotContains(t, string(stripped), "GPS")
+	assert.NotContains(t, string(stripped), "xmpmeta")
+	assert.Equal(t, uint32(len(stripped)-8), binary.LittleEndian.Uint32(stripped[4:]))
+	assert.Equal(t, byte(0), stripped[20], "EXIF and XMP flags are cleared")
+	assert.Contains(t, string(stripped), string(chunk("VP8L", []byte{1, 2, 3, 4, 5})))
+
+	var buf bytes.Buffer
+	require.NoError(t, gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White}), nil))
+	data := buf.Bytes()
+	// A comment extension right before the image descriptor
+	descriptor := bytes.IndexByte(data[13:], 0x2C) + 13
+	comment := append([]byte{0x21, 0xFE, 8}, "taken at"...)
+	withComment := append(append(append([]byte{}, data[:descriptor]...), append(comment, 0)...), data[descriptor:]...)
+
+	stripped = imaging.StripMetadata(withComment)
+	assert.Equal(t, data, stripped)
+	_, err := gif.Decode(bytes.NewReader(stripped))
+	assert.NoError(t, err)
+}
@@ -0,0 +1,51 @@
+package test
+
+import (
+	"encoding/json"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/utils"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMoney(t *testing.T) {
+	cases := map[string]int64{
+		"0":       0,
+		"12":      1200,
+		"12.5":    1250,
+		"12.50":   1250,
+		"0.01":    1,
+		".99":     99,
+		"-3.10":   -310,
+		" 100.00": 10000,
+	}
+	for input, expected := range cases {
+		amount, err := utils.ParseMoney(input)
+		assert.NoError(t, err, input)
+		assert.Equal(t, expected, amount, input)
+	}
+
+	for _, input := range []string{"", "abc", "1.234", "1,00", ".", "99999999999999999999"} {
+		_, err := utils.ParseMoney(input)
+		assert.ErrorIs(t, err, utils.ErrInvalidAmount, input)
+	}
+}
+
+func TestFormatMoney(t *testing.T) {
+	assert.Equal(t, "12.50", utils.FormatMoney(1250))
+	assert.Equal(t, "0.05", utils.FormatMoney(5))
+	assert.Equal(t, "-3.10", utils.FormatMoney(-310))
+}
+
+func TestShopResponse_FormatsBudget(t *testing.T) {
+	shop := &model.Shop{Name: "Coffee", Budget: 1250}
+	data, err := json.Marshal(&dto.ShopResponse{Shop: shop, Budget: utils.FormatMoney(shop.Budget)})
+	assert.NoError(t, err)
+
+	var decoded map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, "12.50", decoded["budget"])
+	assert.Equal(t, "Coffee", decoded["name"])
+}
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/scanner"
+	"go-fiber-api/pkg/storage"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestQuotaService_Limit(t *testing.T) {
+	quota, err := service.NewQuotaService(new(MockShopRepository), nil, &config.Config{
+		StoragePlans:       []string{"free:100", "pro:1000", "unlimited:0"},
+		StorageDefaultPlan: "free",
+	})
+	require.NoError(t, err)
+
+	override := int64(5)
+	assert.Equal(t, int64(100), quota.Limit(&model.Shop{}))
+	assert.Equal(t, int64(1000), quota.Limit(&model.Shop{Plan: "pro"}))
+	assert.Equal(t, int64(0), quota.Limit(&model.Shop{Plan: "unlimited"}))
+	assert.Equal(t, int64(5), quota.Limit(&model.Shop{Plan: "pro", StorageQuota: &override}))
+
+	assert.NoError(t, quota.Check(&model.Shop{StorageUsed: 60}, 40))
+	assert.Error(t, quota.Check(&model.Shop{StorageUsed: 60}, 41))
+	assert.NoError(t, quota.Check(&model.Shop{Plan: "unlimited", StorageUsed: 60}, 1<<40))
+
+	_, err = service.NewQuotaService(new(MockShopRepository), nil, &config.Config{
+		StoragePlans:       []string{"free:100"},
+		StorageDefaultPlan: "pro",
+	})
+	assert.Error(t, err)
+}
+
+func TestQuotaService_Reserve(t *testing.T) {
+	repo := new(MockShopRepository)
+	quota, err := service.NewQuotaService(repo, nil, &config.Config{
+		StoragePlans:       []string{"free:100"},
+		StorageDefaultPlan: "free",
+	})
+	require.NoError(t, err)
+
+	shop := &model.Shop{ID: primitive.NewObjectID()}
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(40), int64(100)).Return(true, nil).Once()
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(80), int64(100)).Return(false, nil).Once()
+
+	assert.NoError(t, quota.Reserve(context.Background(), shop, 40))
+
+	err = quota.Reserve(context.Background(), shop, 80)
+	var fiberErr *fiber.Error
+	require.ErrorAs(t, err, &fiberErr)
+	assert.Equal(t, fiber.StatusInsufficientStorage, fiberErr.Code)
+	repo.AssertExpectations(t)
+}
+
+func TestFileStoreService_UploadsCountStoredSize(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{StoragePlans: []string{"free:100"}, StorageDefaultPlan: "free"}
+	repo := new(MockShopRepository)
+	quota, err := service.NewQuotaService(repo, nil, cfg)
+	require.NoError(t, err)
+
+	local, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	backends := storage.NewBackends(local)
+	files := &fakeFileStoreRepository{}
+	blobs := newFakeBlobRepository()
+	scans := service.NewScanService(files, backends, scanner.Noop{}, nil, cfg)
+	fileStoreService := service.NewFileStoreService(files, service.NewBlobService(blobs, backends), backends, scans, quota, cfg)
+	shop := &model.Shop{ID: primitive.NewObjectID()}
+	upload := func(content string, declared int64) error {
+		header := multipartFiles(t, map[string][]byte{"notes.txt": []byte(content)})[0]
+		header.Size = declared
+		_, err := fileStoreService.Uploads(ctx, &dto.FileStoreRequest{Files: []multipart.FileHeader{*header}, ShopID: shop.ID}, shop)
+		return err
+	}
+
+	// Declared too large, the bytes not stored are given back
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(90), int64(100)).Return(true, nil).Once()
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(-30), int64(0)).Return(true, nil).Once()
+	require.NoError(t, upload(strings.Repeat("a", 60), 90))
+
+	// Declared too small, the stored bytes must still fit the quota
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(10), int64(100)).Return(true, nil).Once()
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(50), int64(100)).Return(false, nil).Once()
+	repo.On("AddStorageUsed", mock.Anything, shop.ID, int64(-10), int64(0)).Return(true, nil).Once()
+	assertStatus(t, upload(strings.Repeat("b", 60), 10), fiber.StatusInsufficientStorage)
+	assert.Len(t, files.files, 1)
+
+	repo.AssertExpectations(t)
+}
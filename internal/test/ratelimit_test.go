@@ -0,0 +1,93 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/pkg/middleware"
+	"go-fiber-api/pkg/ratelimit"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingStore stands for an unreachable redis
+type failingStore struct{}
+
+func (failingStore) Take(ctx context.Context, key string, limit int, window time.Duration) (*ratelimit.Result, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	app := fiber.New()
+	app.Get("/", middleware.NewRateLimiter(ratelimit.NewMemoryStore()).Limit("test", 2, time.Minute), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	for i, expected := range []struct {
+		status    int
+		remaining string
+	}{
+		{fiber.StatusOK, "1"},
+		{fiber.StatusOK, "0"},
+		{fiber.StatusTooManyRequests, "0"},
+	} {
+		resp, err := app.Test(httptest.NewRequest(fiber.MethodGet, "/", nil))
+		assert.NoError(t, err)
+		assert.Equal(t, expected.status, resp.StatusCode, "request %d", i+1)
+		assert.Equal(t, "2", resp.Header.Get(middleware.HeaderRateLimitLimit))
+		assert.Equal(t, expected.remaining, resp.Header.Get(middleware.HeaderRateLimitRemaining))
+
+		reset, err := strconv.ParseInt(resp.Header.Get(middleware.HeaderRateLimitReset), 10, 64)
+		assert.NoError(t, err)
+		assert.InDelta(t, time.Now().Add(time.Minute).Unix(), reset, 2)
+
+		if expected.status == fiber.StatusTooManyRequests {
+			retryAfter, err := strconv.Atoi(resp.Header.Get(fiber.HeaderRetryAfter))
+			assert.NoError(t, err)
+			assert.InDelta(t, 60, retryAfter, 1)
+		} else {
+			assert.Empty(t, resp.Header.Get(fiber.HeaderRetryAfter))
+		}
+	}
+}
+
+func TestMemoryStore_SlidingWindow(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewMemoryStore()
+	window := 50 * time.Millisecond
+
+	for i := 0; i < 2; i++ {
+		result, err := store.Take(ctx, "ip", 2, window)
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed)
+	}
+	result, _ := store.Take(ctx, "ip", 2, window)
+	assert.False(t, result.Allowed)
+	assert.Positive(t, result.RetryAfter)
+
+	// Other keys have their own window
+	result, _ = store.Take(ctx, "other-ip", 2, window)
+	assert.True(t, result.Allowed)
+
+	time.Sleep(window)
+	result, _ = store.Take(ctx, "ip", 2, window)
+	assert.True(t, result.Allowed)
+	assert.Equal(t, 1, result.Remaining)
+}
+
+func TestFallback_UsesSecondaryWhilePrimaryFails(t *testing.T) {
+	ctx := context.Background()
+	store := ratelimit.NewFallback(failingStore{}, ratelimit.NewMemoryStore())
+
+	result, err := store.Take(ctx, "ip", 1, time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, result.Allowed)
+
+	result, err = store.Take(ctx, "ip", 1, time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, result.Allowed)
+}
@@ -0,0 +1,131 @@
+package test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/scanner"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// eicar stands in for the EICAR test signature in the fake daemon
+const eicar = "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"
+
+// fakeClamd answers INSTREAM commands like clamd, reporting streams containing
+// eicar as infected. It returns the tcp:// address it listens on.
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				command, err := reader.ReadString(0)
+				if err != nil || command != "zINSTREAM\x00" {
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+					return
+				}
+
+				var content bytes.Buffer
+				size := make([]byte, 4)
+				for {
+					if _, err := io.ReadFull(reader, size); err != nil {
+						return
+					}
+					n := binary.BigEndian.Uint32(size)
+					if n == 0 {
+						break
+					}
+					if _, err := io.CopyN(&content, reader, int64(n)); err != nil {
+						return
+					}
+				}
+
+				if strings.Contains(content.String(), eicar) {
+					conn.Write([]byte("stream: Eicar-Signature FOUND\x00"))
+					return
+				}
+				conn.Write([]byte("stream: OK\x00"))
+			}(conn)
+		}
+	}()
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	clamav, err := scanner.NewClamAV(fakeClamd(t))
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	result, err := clamav.Scan(ctx, strings.NewReader("just a logo"))
+	require.NoError(t, err)
+	assert.True(t, result.Clean)
+
+	// Larger than one chunk, with the signature past the first one
+	infected := strings.Repeat("x", 100<<10) + eicar
+	result, err = clamav.Scan(ctx, strings.NewReader(infected))
+	require.NoError(t, err)
+	assert.False(t, result.Clean)
+	assert.Equal(t, "Eicar-Signature", result.Signature)
+}
+
+func TestClamAV_Unreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := "tcp://" + listener.Addr().String()
+	listener.Close()
+
+	clamav, err := scanner.NewClamAV(address)
+	require.NoError(t, err)
+	_, err = clamav.Scan(context.Background(), strings.NewReader("content"))
+	assert.Error(t, err)
+
+	_, err = scanner.NewClamAV("localhost:3310")
+	assert.Error(t, err)
+}
+
+func TestCheckScanned_HoldsOriginalsUntilStripped(t *testing.T) {
+	original := &model.FileStore{ScanStatus: model.ScanClean, Derivatives: model.DerivativesPending}
+	var fiberErr *fiber.Error
+	if assert.ErrorAs(t, service.CheckScanned(original), &fiberErr) {
+		assert.Equal(t, fiber.StatusConflict, fiberErr.Code)
+	}
+
+	original.Derivatives = model.DerivativesReady
+	assert.NoError(t, service.CheckScanned(original))
+
+	parentID := primitive.NewObjectID()
+	variant := &model.FileStore{ScanStatus: model.ScanClean, Derivatives: model.DerivativesPending, ParentID: &parentID}
+	assert.NoError(t, service.CheckScanned(variant))
+}
+
+func TestScanService_RecordsScanner(t *testing.T) {
+	file := &model.FileStore{ID: primitive.NewObjectID(), ScanStatus: model.ScanPending}
+	files := &fakeFileStoreRepository{files: []*model.FileStore{file}}
+	scans := service.NewScanService(files, nil, scanner.Noop{}, nil, &config.Config{})
+
+	scans.Enqueue(context.Background(), file)
+
+	assert.Equal(t, model.ScanClean, file.ScanStatus)
+	assert.Equal(t, scanner.DriverNone, file.ScannedBy)
+	assert.NotNil(t, file.ScannedAt)
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/utils"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShopMemberService_KeepsAnOwner(t *testing.T) {
+	ctx := context.Background()
+	shopID := primitive.NewObjectID()
+	owner := &model.ShopMember{ID: primitive.NewObjectID(), ShopID: shopID, Role: string(utils.ShopOwner), Status: model.MemberAccepted}
+	other := &model.ShopMember{ID: primitive.NewObjectID(), ShopID: shopID, Role: string(utils.ShopOwner), Status: model.MemberAccepted}
+	members := newFakeShopMemberRepository(owner, other)
+	shopMemberService := service.NewShopMemberService(members, nil, &config.Config{})
+
+	_, err := shopMemberService.UpdateRole(ctx, other, string(utils.ShopManager))
+	require.NoError(t, err)
+
+	_, err = shopMemberService.UpdateRole(ctx, owner, string(utils.ShopManager))
+	assertStatus(t, err, fiber.StatusConflict)
+	assertStatus(t, shopMemberService.Remove(ctx, owner), fiber.StatusConflict)
+	assert.Equal(t, string(utils.ShopOwner), members.members[owner.ID].Role)
+
+	assert.NoError(t, shopMemberService.Remove(ctx, other))
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/handlers"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/signer"
+	"go-fiber-api/pkg/storage"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSigner_SignAndVerify(t *testing.T) {
+	keys, err := signer.ParseKeys("k1:first-secret")
+	require.NoError(t, err)
+	s, err := signer.New(keys)
+	require.NoError(t, err)
+
+	now := time.Now()
+	values := s.Sign(signer.Claims{Resource: "file-1", Variant: "thumb", ExpiresAt: now.Add(time.Hour), SingleUse: true, Nonce: "n1"})
+
+	claims, err := s.Verify("file-1", values, now)
+	require.NoError(t, err)
+	assert.Equal(t, "thumb", claims.Variant)
+	assert.True(t, claims.SingleUse)
+	assert.Equal(t, "n1", claims.Nonce)
+
+	_, err = s.Verify("file-2", values, now)
+	assert.ErrorIs(t, err, signer.ErrInvalidSignature)
+
+	values.Set("variant", "full")
+	_, err = s.Verify("file-1", values, now)
+	assert.ErrorIs(t, err, signer.ErrInvalidSignature)
+
+	values = s.Sign(signer.Claims{Resource: "file-1", ExpiresAt: now.Add(time.Minute)})
+	_, err = s.Verify("file-1", values, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, signer.ErrExpired)
+}
+
+func TestSigner_KeyRotation(t *testing.T) {
+	oldKeys, _ := signer.ParseKeys("k1:first-secret")
+	old, _ := signer.New(oldKeys)
+	now := time.Now()
+	values := old.Sign(signer.Claims{Resource: "file-1", ExpiresAt: now.Add(time.Hour)})
+
+	rotatedKeys, _ := signer.ParseKeys("k2:second-secret,k1:first-secret")
+	rotated, _ := signer.New(rotatedKeys)
+	_, err := rotated.Verify("file-1", values, now)
+	assert.NoError(t, err, "links signed with the previous key stay valid")
+	assert.Equal(t, "k2", rotated.Sign(signer.Claims{Resource: "file-1", ExpiresAt: now.Add(time.Hour)}).Get("kid"))
+
+	retiredKeys, _ := signer.ParseKeys("k2:second-secret")
+	retired, _ := signer.New(retiredKeys)
+	_, err = retired.Verify("file-1", values, now)
+	assert.ErrorIs(t, err, signer.ErrUnknownKey)
+}
+
+// shopRepositoryWith finds shop whatever the query
+type shopRepositoryWith struct {
+	MockShopRepository
+	shop *model.Shop
+}
+
+func (r *shopRepositoryWith) FindOne(ctx context.Context, query bson.M) (*model.Shop, error) {
+	return r.shop, nil
+}
+
+func TestPublicDownload_SingleUseLink(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{FileSigningKeys: "k1:secret", FileLinkMaxExpiry: "1h"}
+	_, client := newFakeRedis(t)
+
+	local, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	content := "single use content"
+	require.NoError(t, local.Put(ctx, "file", strings.NewReader(content), int64(len(content)), "text/plain"))
+
+	shop := &model.Shop{ID: primitive.NewObjectID()}
+	file := &model.FileStore{
+		ID: primitive.NewObjectID(), ShopID: shop.ID, Backend: storage.DriverLocal, Key: "file", Name: "file.txt",
+		Size: int64(len(content)), Hash: "hash", ContentType: "text/plain", ScanStatus: model.ScanClean,
+	}
+	files := &fakeFileStoreRepository{files: []*model.FileStore{file}}
+	backends := storage.NewBackends(local)
+	fileStoreService := service.NewFileStoreService(files, service.NewBlobService(newFakeBlobRepository(), backends), backends, nil, nil, cfg)
+	fileLinkService, err := service.NewFileLinkService(client, cfg)
+	require.NoError(t, err)
+	handler := handlers.NewFileStoreHandler(fileStoreService, fileLinkService, service.NewShopService(&shopRepositoryWith{shop: shop}), nil)
+
+	app := fiber.New()
+	app.Get("/file/public/:file_id", handler.PublicDownload)
+
+	download := func(query, method string, headers map[string]string) *http.Response {
+		req := httptest.NewRequest(method, "/file/public/"+file.ID.Hex()+"?"+query, nil)
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		resp, err := app.Test(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	query, _, err := fileLinkService.Issue(file, "", time.Hour, true)
+	require.NoError(t, err)
+
+	// Looking at the link does not use it up
+	assert.Equal(t, fiber.StatusOK, download(query, fiber.MethodHead, nil).StatusCode)
+	assert.Equal(t, fiber.StatusNotModified, download(query, fiber.MethodGet, map[string]string{"If-None-Match": `"hash"`}).StatusCode)
+
+	resp := download(query, fiber.MethodGet, nil)
+	assert.Equal(t, fiber.StatusOK, resp.StatusCode)
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, content, string(body))
+
+	assert.Equal(t, fiber.StatusGone, download(query, fiber.MethodGet, nil).StatusCode)
+	assert.Equal(t, fiber.StatusGone, download(query, fiber.MethodGet, map[string]string{"Range": "bytes=0-"}).StatusCode)
+	assert.Equal(t, fiber.StatusGone, download(query, fiber.MethodHead, nil).StatusCode)
+
+	// A ranged response uses the link up as well
+	query, _, err = fileLinkService.Issue(file, "", time.Hour, true)
+	require.NoError(t, err)
+	assert.Equal(t, fiber.StatusPartialContent, download(query, fiber.MethodGet, map[string]string{"Range": "bytes=0-"}).StatusCode)
+	assert.Equal(t, fiber.StatusGone, download(query, fiber.MethodGet, map[string]string{"Range": "bytes=0-"}).StatusCode)
+	assert.Equal(t, fiber.StatusGone, download(query, fiber.MethodGet, nil).StatusCode)
+}
+
+func TestFileLinkService_DisabledWithoutKeys(t *testing.T) {
+	_, client := newFakeRedis(t)
+	fileLinkService, err := service.NewFileLinkService(client, &config.Config{JWTSecretKey: "secret", FileLinkMaxExpiry: "1h"})
+	require.NoError(t, err)
+
+	file := &model.FileStore{ID: primitive.NewObjectID()}
+	_, _, err = fileLinkService.Issue(file, "", time.Hour, false)
+	assertStatus(t, err, fiber.StatusServiceUnavailable)
+	_, err = fileLinkService.Verify(context.Background(), file.ID.Hex(), nil)
+	assertStatus(t, err, fiber.StatusServiceUnavailable)
+}
@@ -0,0 +1,88 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/internal/service"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCategoryService_DeleteCascadeTrashesSubtree(t *testing.T) {
+	ctx := context.Background()
+	_, drinks, _, _, _ := categoryTree()
+	deletedBy := primitive.NewObjectID()
+
+	mockRepo := &MockCategoryRepository{}
+	mockRepo.On("Delete", ctx, service.SubtreeQuery(&drinks), deletedBy).Return(int64(3), nil)
+
+	deleted, err := service.NewCategoryService(mockRepo).Delete(ctx, &drinks, true, deletedBy)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestTrashService_RestoreCategory(t *testing.T) {
+	ctx := context.Background()
+	cfg := &config.Config{TrashRetention: "720h"}
+	deletedAt := time.Now().Add(-time.Hour)
+
+	t.Run("refuses while the parent is in the trash", func(t *testing.T) {
+		_, drinks, coffee, _, _ := categoryTree()
+		coffee.DeletedAt = &deletedAt
+
+		mockRepo := &MockCategoryRepository{}
+		mockRepo.On("FindAll", ctx, bson.M{"_id": coffee.ID, "deleted_at": repository.InTrash}).Return([]model.Category{coffee}, nil)
+		mockRepo.On("Get", ctx, drinks.ID).Return((*model.Category)(nil), mongo.ErrNoDocuments)
+
+		trashService, err := service.NewTrashService(nil, &MockShopRepository{}, mockRepo, nil, cfg)
+		assert.NoError(t, err)
+		err = trashService.Restore(ctx, service.TrashCategory, coffee.ID)
+
+		var fiberErr *fiber.Error
+		assert.True(t, errors.As(err, &fiberErr))
+		assert.Equal(t, fiber.StatusConflict, fiberErr.Code)
+		mockRepo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+	})
+
+	t.Run("restores the subtree trashed along with it", func(t *testing.T) {
+		_, drinks, coffee, _, _ := categoryTree()
+		coffee.DeletedAt = &deletedAt
+
+		query := service.SubtreeQuery(&coffee)
+		query["deleted_at"] = deletedAt
+
+		mockRepo := &MockCategoryRepository{}
+		mockRepo.On("FindAll", ctx, bson.M{"_id": coffee.ID, "deleted_at": repository.InTrash}).Return([]model.Category{coffee}, nil)
+		mockRepo.On("Get", ctx, drinks.ID).Return(&drinks, nil)
+		mockRepo.On("Restore", ctx, query).Return(int64(2), nil)
+
+		trashService, err := service.NewTrashService(nil, &MockShopRepository{}, mockRepo, nil, cfg)
+		assert.NoError(t, err)
+
+		assert.NoError(t, trashService.Restore(ctx, service.TrashCategory, coffee.ID))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTrashService_ListRejectsUnknownType(t *testing.T) {
+	trashService, err := service.NewTrashService(nil, &MockShopRepository{}, &MockCategoryRepository{}, nil, &config.Config{TrashRetention: "720h"})
+	assert.NoError(t, err)
+
+	_, _, err = trashService.List(context.Background(), "product", 1, 10)
+
+	var fiberErr *fiber.Error
+	assert.True(t, errors.As(err, &fiberErr))
+	assert.Equal(t, fiber.StatusBadRequest, fiberErr.Code)
+}
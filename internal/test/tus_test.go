@@ -0,0 +1,193 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/scanner"
+	"go-fiber-api/pkg/storage"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// chunkReader runs before once the first time the chunk is read
+type chunkReader struct {
+	io.Reader
+	before func()
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	if r.before != nil {
+		r.before()
+		r.before = nil
+	}
+	return r.Reader.Read(p)
+}
+
+type tusFixture struct {
+	tusService *service.TusService
+	redis      *fakeRedis
+	files      *fakeFileStoreRepository
+	staging    string
+	shop       *model.Shop
+	user       *model.User
+}
+
+func newTusFixture(t *testing.T) *tusFixture {
+	cfg := &config.Config{
+		TusStagingPath:     t.TempDir(),
+		TusExpiresIn:       "1h",
+		StoragePlans:       []string{"free:0"},
+		StorageDefaultPlan: "free",
+	}
+	fake, client := newFakeRedis(t)
+
+	local, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	backends := storage.NewBackends(local)
+	files := &fakeFileStoreRepository{}
+	shopRepo := new(MockShopRepository)
+	shopRepo.On("AddStorageUsed", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+	quota, err := service.NewQuotaService(shopRepo, files, cfg)
+	require.NoError(t, err)
+	scans := service.NewScanService(files, backends, scanner.Noop{}, nil, cfg)
+	fileStoreService := service.NewFileStoreService(files, service.NewBlobService(newFakeBlobRepository(), backends), backends, scans, quota, cfg)
+
+	tusService, err := service.NewTusService(client, fileStoreService, cfg)
+	require.NoError(t, err)
+	return &tusFixture{
+		tusService: tusService,
+		redis:      fake,
+		files:      files,
+		staging:    cfg.TusStagingPath,
+		shop:       &model.Shop{ID: primitive.NewObjectID()},
+		user:       &model.User{ID: primitive.NewObjectID()},
+	}
+}
+
+func TestTusService_CreateFindTerminate(t *testing.T) {
+	ctx := context.Background()
+	f := newTusFixture(t)
+
+	upload, err := f.tusService.Create(ctx, f.shop, f.user, 10, "filename ZG9nLnR4dA==")
+	require.NoError(t, err)
+	assert.Equal(t, "dog.txt", upload.Filename)
+
+	found, err := f.tusService.Find(ctx, f.shop, upload.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), found.Length)
+	assert.Equal(t, int64(0), found.Offset)
+	assert.Equal(t, f.user.ID, found.UserID)
+
+	// Uploads belong to their shop
+	_, err = f.tusService.Find(ctx, &model.Shop{ID: primitive.NewObjectID()}, upload.ID)
+	assertStatus(t, err, fiber.StatusNotFound)
+
+	require.NoError(t, f.tusService.Terminate(ctx, found))
+	_, err = f.tusService.Find(ctx, f.shop, upload.ID)
+	assertStatus(t, err, fiber.StatusNotFound)
+	assert.NoFileExists(t, filepath.Join(f.staging, upload.ID))
+}
+
+func TestTusService_Append(t *testing.T) {
+	ctx := context.Background()
+	content := []byte("hello tus uploads")
+
+	t.Run("rejects a mismatched offset", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, int64(len(content)), "")
+		require.NoError(t, err)
+
+		_, err = f.tusService.Append(ctx, f.shop, upload, 5, bytes.NewReader(content[5:]))
+		assertStatus(t, err, fiber.StatusConflict)
+	})
+
+	t.Run("checks the offset written by a request it waited for", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, int64(len(content)), "")
+		require.NoError(t, err)
+		stale := *upload
+
+		_, err = f.tusService.Append(ctx, f.shop, upload, 0, bytes.NewReader(content[:5]))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), upload.Offset)
+
+		// Loaded before the first chunk, it still says 0
+		_, err = f.tusService.Append(ctx, f.shop, &stale, 0, bytes.NewReader([]byte("HELLO")))
+		assertStatus(t, err, fiber.StatusConflict)
+		assert.Equal(t, int64(5), stale.Offset)
+	})
+
+	t.Run("rejects a chunk past Upload-Length", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, 4, "")
+		require.NoError(t, err)
+
+		_, err = f.tusService.Append(ctx, f.shop, upload, 0, bytes.NewReader(content))
+		assertStatus(t, err, fiber.StatusRequestEntityTooLarge)
+
+		found, err := f.tusService.Find(ctx, f.shop, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), found.Offset)
+	})
+
+	t.Run("refuses while another request writes", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, int64(len(content)), "")
+		require.NoError(t, err)
+		f.redis.Set("tus:"+upload.ID+":lock", "other-request")
+
+		_, err = f.tusService.Append(ctx, f.shop, upload, 0, bytes.NewReader(content))
+		assertStatus(t, err, fiber.StatusLocked)
+		assert.Equal(t, "other-request", f.redis.Get("tus:"+upload.ID+":lock"))
+	})
+
+	t.Run("keeps a lock taken over after it expired", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, int64(len(content)), "")
+		require.NoError(t, err)
+
+		chunk := &chunkReader{Reader: bytes.NewReader(content[:5]), before: func() {
+			f.redis.Set("tus:"+upload.ID+":lock", "other-request")
+		}}
+		_, err = f.tusService.Append(ctx, f.shop, upload, 0, chunk)
+		assertStatus(t, err, fiber.StatusConflict)
+		assert.Equal(t, "other-request", f.redis.Get("tus:"+upload.ID+":lock"))
+
+		found, err := f.tusService.Find(ctx, f.shop, upload.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(0), found.Offset)
+	})
+
+	t.Run("turns the completed upload into a file", func(t *testing.T) {
+		f := newTusFixture(t)
+		upload, err := f.tusService.Create(ctx, f.shop, f.user, int64(len(content)), "filename ZG9nLnR4dA==")
+		require.NoError(t, err)
+
+		file, err := f.tusService.Append(ctx, f.shop, upload, 0, bytes.NewReader(content[:5]))
+		require.NoError(t, err)
+		assert.Nil(t, file)
+
+		file, err = f.tusService.Append(ctx, f.shop, upload, 5, bytes.NewReader(content[5:]))
+		require.NoError(t, err)
+		require.NotNil(t, file)
+		assert.Equal(t, "dog.txt", file.OriginalName)
+		assert.Equal(t, int64(len(content)), file.Size)
+		assert.Len(t, f.files.files, 1)
+		assert.Empty(t, f.redis.Get("tus:"+upload.ID+":lock"))
+
+		// The upload and its staged data are gone
+		_, err = f.tusService.Find(ctx, f.shop, upload.ID)
+		assertStatus(t, err, fiber.StatusNotFound)
+		assert.NoFileExists(t, filepath.Join(f.staging, upload.ID))
+	})
+}
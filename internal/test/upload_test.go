@@ -0,0 +1,120 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"go-fiber-api/pkg/storage"
+	"go-fiber-api/pkg/utils"
+	"io"
+	"mime/multipart"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x02\x00\x00\x00")
+
+// multipartFiles builds file headers the way fiber parses an upload form
+func multipartFiles(t *testing.T, files map[string][]byte) []*multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, content := range files {
+		part, err := writer.CreateFormFile("files", name)
+		require.NoError(t, err)
+		part.Write(content)
+	}
+	require.NoError(t, writer.Close())
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	require.NoError(t, err)
+	return form.File["files"]
+}
+
+// memoryBlobs counts references in memory the way BlobService does in Mongo
+type memoryBlobs struct {
+	store storage.Storage
+	refs  map[string]int
+	puts  int
+}
+
+func newMemoryBlobs(t *testing.T) *memoryBlobs {
+	store, err := storage.NewLocal(t.TempDir())
+	require.NoError(t, err)
+	return &memoryBlobs{store: store, refs: map[string]int{}}
+}
+
+func (m *memoryBlobs) Store(ctx context.Context, hash string, src io.Reader, size int64, contentType string) (string, string, error) {
+	key := "blobs/" + hash
+	if m.refs[key] == 0 {
+		if err := m.store.Put(ctx, key, src, size, contentType); err != nil {
+			return "", "", err
+		}
+		m.puts++
+	}
+	m.refs[key]++
+	return m.store.Name(), key, nil
+}
+
+func (m *memoryBlobs) Release(ctx context.Context, backend, key string) error {
+	m.refs[key]--
+	if m.refs[key] > 0 {
+		return nil
+	}
+	delete(m.refs, key)
+	return m.store.Delete(ctx, key)
+}
+
+func TestUpload_SniffsContentType(t *testing.T) {
+	blobs := newMemoryBlobs(t)
+	limits := utils.UploadLimits{AllowedTypes: []string{"image/*"}}
+
+	files := multipartFiles(t, map[string][]byte{"logo.txt": pngHeader})
+	uploaded, err := utils.Upload(context.Background(), blobs, files, limits)
+	require.NoError(t, err)
+	require.Len(t, uploaded, 1)
+	assert.Equal(t, "image/png", uploaded[0].ContentType)
+	assert.Equal(t, ".png", uploaded[0].Extension)
+	assert.Equal(t, "logo.txt", uploaded[0].OriginalName)
+	assert.Equal(t, int64(len(pngHeader)), uploaded[0].Size)
+
+	object, err := blobs.store.Stat(context.Background(), uploaded[0].Key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(pngHeader)), object.Size)
+
+	files = multipartFiles(t, map[string][]byte{"logo.png": []byte("just some text")})
+	_, err = utils.Upload(context.Background(), blobs, files, limits)
+	assert.ErrorIs(t, err, utils.ErrUnsupportedFileType)
+}
+
+func TestUpload_DeduplicatesContent(t *testing.T) {
+	blobs := newMemoryBlobs(t)
+
+	files := multipartFiles(t, map[string][]byte{"a.png": pngHeader, "b.png": pngHeader})
+	uploaded, err := utils.Upload(context.Background(), blobs, files, utils.UploadLimits{})
+	require.NoError(t, err)
+	require.Len(t, uploaded, 2)
+	assert.Equal(t, uploaded[0].Key, uploaded[1].Key)
+	assert.Equal(t, utils.HashBytes(pngHeader), uploaded[0].Hash)
+	assert.NotEqual(t, uploaded[0].Name, uploaded[1].Name)
+	assert.Equal(t, 1, blobs.puts)
+	assert.Equal(t, 2, blobs.refs[uploaded[0].Key])
+
+	// A rejected file releases what the same request already stored
+	files = multipartFiles(t, map[string][]byte{"a.png": pngHeader, "b.txt": []byte("just some text")})
+	_, err = utils.Upload(context.Background(), blobs, files, utils.UploadLimits{AllowedTypes: []string{"image/*"}})
+	assert.ErrorIs(t, err, utils.ErrUnsupportedFileType)
+	assert.Equal(t, 2, blobs.refs[uploaded[0].Key])
+}
+
+func TestUpload_Limits(t *testing.T) {
+	blobs := newMemoryBlobs(t)
+
+	files := multipartFiles(t, map[string][]byte{"a.png": pngHeader, "b.png": pngHeader})
+
+	_, err := utils.Upload(context.Background(), blobs, files, utils.UploadLimits{MaxFileSize: 10})
+	assert.ErrorIs(t, err, utils.ErrFileTooLarge)
+
+	_, err = utils.Upload(context.Background(), blobs, files, utils.UploadLimits{MaxRequestSize: int64(len(pngHeader)) + 1})
+	assert.ErrorIs(t, err, utils.ErrRequestTooLarge)
+}
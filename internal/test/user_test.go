@@ -0,0 +1,234 @@
+package test
+
+import (
+	"context"
+	"go-fiber-api/internal/config"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/repository"
+	"go-fiber-api/internal/service"
+	"go-fiber-api/pkg/dto"
+	"go-fiber-api/pkg/notifier"
+	"go-fiber-api/pkg/utils"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// fakeUserRepository keeps the users in memory, UpdateRoles applies the last
+// admin check together with the update as the transaction does
+type fakeUserRepository struct {
+	mu    sync.Mutex
+	users map[primitive.ObjectID]*model.User
+}
+
+func newFakeUserRepository(users ...*model.User) *fakeUserRepository {
+	r := &fakeUserRepository{users: map[primitive.ObjectID]*model.User{}}
+	for _, user := range users {
+		r.users[user.ID] = user
+	}
+	return r
+}
+
+func (r *fakeUserRepository) Create(ctx context.Context, user *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := *user
+	r.users[user.ID] = &stored
+	return nil
+}
+
+func (r *fakeUserRepository) FindOne(ctx context.Context, query bson.M) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, user := range r.users {
+		if id, ok := query["_id"]; ok && id != user.ID {
+			continue
+		}
+		if email, ok := query["email"]; ok && email != user.Email {
+			continue
+		}
+		found := *user
+		return &found, nil
+	}
+	return nil, nil
+}
+
+func (r *fakeUserRepository) UpdateByID(ctx context.Context, id primitive.ObjectID, payload *dto.UpdateUserRequest) (*model.User, error) {
+	return nil, nil
+}
+
+func (r *fakeUserRepository) UpdatePassword(ctx context.Context, id primitive.ObjectID, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id].Password = hashedPassword
+	return nil
+}
+
+func (r *fakeUserRepository) UpdateEmailVerified(ctx context.Context, id primitive.ObjectID, verified bool) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[id].EmailVerified = verified
+	updated := *r.users[id]
+	return &updated, nil
+}
+
+func (r *fakeUserRepository) UpdateRoles(ctx context.Context, id primitive.ObjectID, roles []string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	previous := r.users[id].Roles
+	r.users[id].Roles = roles
+	for _, user := range r.users {
+		for _, role := range user.Roles {
+			if role == string(utils.AdminRole) {
+				updated := *r.users[id]
+				return &updated, nil
+			}
+		}
+	}
+	r.users[id].Roles = previous
+	return nil, repository.ErrLastAdmin
+}
+
+func (r *fakeUserRepository) Delete(ctx context.Context, id primitive.ObjectID, deletedBy primitive.ObjectID) error {
+	return nil
+}
+
+func (r *fakeUserRepository) Restore(ctx context.Context, id primitive.ObjectID) error {
+	return nil
+}
+
+func (r *fakeUserRepository) FindAll(ctx context.Context, query bson.D, opts *options.FindOptions) ([]model.User, error) {
+	return nil, nil
+}
+
+func (r *fakeUserRepository) Count(ctx context.Context, query bson.D) (int64, error) {
+	return int64(len(r.users)), nil
+}
+
+func (r *fakeUserRepository) MigrateEmailVerified(ctx context.Context) error {
+	return nil
+}
+
+// outbox keeps the messages sent to users
+type outbox struct {
+	messages []*notifier.Message
+}
+
+func (o *outbox) Send(ctx context.Context, msg *notifier.Message) error {
+	o.messages = append(o.messages, msg)
+	return nil
+}
+
+func newUserService(t *testing.T, cfg *config.Config, users *fakeUserRepository) (*service.UserService, *outbox) {
+	cfg.JWTSecretKey, cfg.JWTExpiresIn = "access-secret", "15m"
+	cfg.JWTRefreshKey, cfg.JWTRefreshIn = "refresh-secret", "24h"
+	_, client := newFakeRedis(t)
+
+	roleRepo := new(MockRoleRepository)
+	roleRepo.On("FindByNames", mock.Anything, []string{"user"}).Return([]model.Role{{Name: "user"}}, nil)
+
+	sent := &outbox{}
+	return service.NewUserService(users, roleRepo, client, sent, cfg), sent
+}
+
+func TestUserService_UpdateRolesKeepsAnAdmin(t *testing.T) {
+	ctx := context.Background()
+	admin := &model.User{ID: primitive.NewObjectID(), Email: "admin@example.com", Roles: []string{"user", "admin"}}
+	other := &model.User{ID: primitive.NewObjectID(), Email: "other@example.com", Roles: []string{"user", "admin"}}
+	users := newFakeUserRepository(admin, other)
+	userService, _ := newUserService(t, &config.Config{}, users)
+
+	updated, err := userService.UpdateRoles(ctx, other, []string{"user"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user"}, updated.Roles)
+
+	_, err = userService.UpdateRoles(ctx, admin, []string{"user"})
+	assertStatus(t, err, fiber.StatusConflict)
+	assert.Equal(t, []string{"user", "admin"}, users.users[admin.ID].Roles)
+}
+
+// sentToken is the token in the link of the last message sent
+func sentToken(t *testing.T, sent *outbox) string {
+	t.Helper()
+	require.NotEmpty(t, sent.messages)
+	_, token, found := strings.Cut(sent.messages[len(sent.messages)-1].Body, "token=")
+	require.True(t, found)
+	return token
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUserRepository()
+	userService, sent := newUserService(t, &config.Config{PasswordResetExpiresIn: "30m"}, users)
+
+	user, err := userService.Create(ctx, &dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "old-password"})
+	require.NoError(t, err)
+	session, err := userService.Login(ctx, "old-password", user)
+	require.NoError(t, err)
+
+	require.NoError(t, userService.ForgotPassword(ctx, user.Email))
+	replaced := sentToken(t, sent)
+	require.NoError(t, userService.ForgotPassword(ctx, user.Email))
+	token := sentToken(t, sent)
+
+	// A new token replaces the previous one
+	assertStatus(t, userService.ResetPassword(ctx, replaced, "new-password"), fiber.StatusBadRequest)
+
+	require.NoError(t, userService.ResetPassword(ctx, token, "new-password"))
+
+	// The token is single use, and the sessions from before the reset are gone
+	assertStatus(t, userService.ResetPassword(ctx, token, "other-password"), fiber.StatusBadRequest)
+	assertStatus(t, userService.ValidateTokenWithRedis(ctx, session.AccessToken), fiber.StatusUnauthorized)
+	_, err = userService.RefreshToken(ctx, session.RefreshToken)
+	assert.Error(t, err)
+
+	user, _ = userService.FindByEmail(ctx, user.Email)
+	_, err = userService.Login(ctx, "old-password", user)
+	assert.Error(t, err)
+	_, err = userService.Login(ctx, "new-password", user)
+	assert.NoError(t, err)
+}
+
+func TestUserService_ResetTokenExpires(t *testing.T) {
+	ctx := context.Background()
+	user := &model.User{ID: primitive.NewObjectID(), Email: "ann@example.com"}
+	userService, sent := newUserService(t, &config.Config{PasswordResetExpiresIn: "50ms"}, newFakeUserRepository(user))
+
+	require.NoError(t, userService.ForgotPassword(ctx, user.Email))
+	time.Sleep(100 * time.Millisecond)
+
+	assertStatus(t, userService.ResetPassword(ctx, sentToken(t, sent), "new-password"), fiber.StatusBadRequest)
+}
+
+func TestUserService_LoginNeedsVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	users := newFakeUserRepository()
+	userService, sent := newUserService(t, &config.Config{EmailVerificationMode: "block", EmailVerificationExpiresIn: "24h"}, users)
+
+	user, err := userService.Create(ctx, &dto.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "password"})
+	require.NoError(t, err)
+
+	_, err = userService.Login(ctx, "password", user)
+	assertStatus(t, err, fiber.StatusForbidden)
+
+	require.NoError(t, userService.SendVerification(ctx, user))
+	token := sentToken(t, sent)
+	verified, err := userService.VerifyEmail(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, verified.EmailVerified)
+
+	_, err = userService.VerifyEmail(ctx, token)
+	assertStatus(t, err, fiber.StatusBadRequest)
+
+	_, err = userService.Login(ctx, "password", verified)
+	assert.NoError(t, err)
+}
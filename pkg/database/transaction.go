@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithTransaction runs fn inside a MongoDB transaction and commits it when fn
+// succeeds. Transactions need MongoDB to run as a replica set.
+func WithTransaction(ctx context.Context, client *mongo.Client, fn func(sessCtx mongo.SessionContext) (interface{}, error)) (interface{}, error) {
+	session, err := client.StartSession()
+	if err != nil {
+		return nil, err
+	}
+	defer session.EndSession(ctx)
+
+	return session.WithTransaction(ctx, fn)
+}
@@ -0,0 +1,17 @@
+package dto
+
+import "go-fiber-api/internal/model"
+
+type BudgetTransactionRequest struct {
+	// Amount is a positive decimal string such as "12.50"
+	Amount         string `json:"amount" binding:"required"`
+	Reason         string `json:"reason" binding:"required,max=200"`
+	IdempotencyKey string `json:"idempotency_key" binding:"omitempty,max=100"`
+}
+
+// BudgetTransactionResponse is a ledger entry with its amounts as decimal strings
+type BudgetTransactionResponse struct {
+	*model.BudgetTransaction
+	Amount       string `json:"amount"`
+	BalanceAfter string `json:"balance_after"`
+}
@@ -1,10 +1,40 @@
 package dto
 
+import (
+	"go-fiber-api/internal/model"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 type CategoryRequest struct {
-	ShopId string `json:"shop_id" binding:"required"`
-	Name   string `json:"name" binding:"required,min=3,max=30"`
+	ShopId   string `json:"shop_id" binding:"required"`
+	ParentId string `json:"parent_id" binding:"omitempty"`
+	Name     string `json:"name" binding:"required,min=3,max=30"`
 }
 
+// UpdateCategoryRequest renames a category. ShopId may be sent back as it was
+// read, any other shop is refused.
 type UpdateCategoryRequest struct {
-	Name string `json:"name" binding:"omitempty,min=3,max=30"`
+	ShopId string `json:"shop_id" binding:"omitempty"`
+	Name   string `json:"name" binding:"omitempty,min=3,max=30"`
+}
+
+// MoveCategoryRequest moves a category with its subtree below ParentId, or to
+// the roots when it is empty. Without Position it becomes the last child.
+type MoveCategoryRequest struct {
+	ParentId string `json:"parent_id" binding:"omitempty"`
+	Position *int   `json:"position" binding:"omitempty,min=0"`
+}
+
+// ReorderCategoriesRequest lists every child of ParentId, or every root when it
+// is empty, in their new order
+type ReorderCategoriesRequest struct {
+	ParentId    string               `json:"parent_id" binding:"omitempty"`
+	CategoryIDs []primitive.ObjectID `json:"category_ids" binding:"required,min=1"`
+}
+
+// CategoryNode is a category with its children, in order
+type CategoryNode struct {
+	model.Category
+	Children []*CategoryNode `json:"children"`
 }
@@ -0,0 +1,21 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// DeletionReport tells what deleting a shop or a user does, or would do on a dry run
+type DeletionReport struct {
+	Resource  string              `json:"resource"`
+	ID        primitive.ObjectID  `json:"id"`
+	DryRun    bool                `json:"dry_run"`
+	Deleted   bool                `json:"deleted"`
+	Relations []DeletionRelation  `json:"relations"`
+	Blocked   []string            `json:"blocked,omitempty"`
+	NewOwner  *primitive.ObjectID `json:"new_owner,omitempty"`
+}
+
+// DeletionRelation is how many related documents the policy applies to
+type DeletionRelation struct {
+	Relation string `json:"relation"`
+	Policy   string `json:"policy"`
+	Count    int64  `json:"count"`
+}
@@ -0,0 +1,13 @@
+package dto
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type UpdateEmailVerifiedRequest struct {
+	EmailVerified *bool `json:"email_verified" binding:"required"`
+}
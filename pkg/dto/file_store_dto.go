@@ -2,6 +2,7 @@ package dto
 
 import (
 	"mime/multipart"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
@@ -11,12 +12,55 @@ type FileRequest struct {
 }
 
 type FileStoreRequest struct {
-	Files  []multipart.FileHeader `json:"files" binding:"required"`
-	ShopID primitive.ObjectID     `json:"shop_id" binding:"required"`
+	Files      []multipart.FileHeader `json:"files" binding:"required"`
+	ShopID     primitive.ObjectID     `json:"shop_id" binding:"required"`
+	UploadedBy primitive.ObjectID     `json:"uploaded_by"`
 }
 
 type FileStoreUploadResponse struct {
-	Name      string `json:"name"`
-	BasePath  string `json:"base_path"`
-	Extension string `json:"extension"`
+	Name         string `json:"name"`
+	OriginalName string `json:"original_name"`
+	Backend      string `json:"backend"`
+	Key          string `json:"key"`
+	Extension    string `json:"extension"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"content_type"`
+	Hash         string `json:"hash"`
+}
+
+type ReorderFilesRequest struct {
+	FileIDs []primitive.ObjectID `json:"file_ids" binding:"required,min=1"`
+}
+
+type FileLinkRequest struct {
+	// ExpiresIn is the lifetime of the link in seconds
+	ExpiresIn int    `json:"expires_in" binding:"required,min=1"`
+	SingleUse bool   `json:"single_use"`
+	Variant   string `json:"variant" binding:"omitempty,max=50"`
+}
+
+type FileLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// ArchiveManifest is the manifest.json of a shop file archive
+type ArchiveManifest struct {
+	ShopID    primitive.ObjectID    `json:"shop_id"`
+	ShopName  string                `json:"shop_name"`
+	CreatedAt time.Time             `json:"created_at"`
+	Files     []ArchiveManifestFile `json:"files"`
+}
+
+type ArchiveManifestFile struct {
+	ID           primitive.ObjectID `json:"id"`
+	Path         string             `json:"path,omitempty"`
+	OriginalName string             `json:"original_name"`
+	ContentType  string             `json:"content_type"`
+	Size         int64              `json:"size"`
+	Hash         string             `json:"hash,omitempty"`
+	Position     int                `json:"position"`
+	CreatedAt    time.Time          `json:"created_at"`
+	// Error tells why the file is missing from the archive
+	Error string `json:"error,omitempty"`
 }
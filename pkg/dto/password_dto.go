@@ -0,0 +1,11 @@
+package dto
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token" binding:"required"`
+	Password        string `json:"password" binding:"required,min=6,password_validator"`
+	ConfirmPassword string `json:"confirm_password" binding:"required,eqfield=Password"`
+}
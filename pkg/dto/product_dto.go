@@ -0,0 +1,19 @@
+package dto
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+type ProductRequest struct {
+	Name        string  `json:"name" binding:"required,min=3,max=50"`
+	Description string  `json:"description" binding:"omitempty,max=500"`
+	Price       float64 `json:"price" binding:"gte=0"`
+	ImageID     string  `json:"image_id" binding:"omitempty"`
+	CategoryID  string  `json:"category_id" binding:"omitempty"`
+}
+
+type UpdateProductRequest struct {
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description" bson:"description"`
+	Price       float64            `json:"price" bson:"price"`
+	ImageID     primitive.ObjectID `json:"image_id" bson:"image_id,omitempty"`
+	CategoryID  primitive.ObjectID `json:"category_id" bson:"category_id,omitempty"`
+}
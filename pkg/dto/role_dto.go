@@ -0,0 +1,5 @@
+package dto
+
+type RoleRequest struct {
+	Permissions []string `json:"permissions" binding:"required"`
+}
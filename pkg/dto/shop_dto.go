@@ -8,20 +8,42 @@ import (
 )
 
 type ShopRequest struct {
-	Name   string  `json:"name" form:"name" binding:"required,min=3,max=30"`
-	Budget float64 `json:"budget" form:"budget" binding:"required"`
+	Name   string `json:"name" form:"name" binding:"required,min=3,max=30"`
+	Budget int64  `json:"budget" form:"budget" binding:"gte=0"`
 }
 
 type UpdateShopRequest struct {
-	Name   string  `json:"name" form:"name" binding:"omitempty,min=3,max=30"`
-	Budget float64 `json:"budget" form:"budget" binding:"omitempty"`
+	Name string `json:"name" form:"name" binding:"required,min=3,max=30"`
+}
+
+// ShopResponse is a shop with its budget as a decimal string such as "12.50",
+// the form budgets are given in
+type ShopResponse struct {
+	*model.Shop
+	Budget string `json:"budget"`
 }
 
 type UpdateShopResponse struct {
 	ID        primitive.ObjectID `json:"_id"`
 	Name      string             `json:"name"`
-	Budget    float64            `json:"budget"`
-	Files     []*model.FileStore `json:"files"`
+	Budget    string             `json:"budget"`
+	Files     []*model.FileStore `json:"files,omitempty"`
 	CreatedAt time.Time          `json:"created_at"`
 	UpdatedAt time.Time          `json:"updated_at"`
 }
+
+type UpdateStoragePlanRequest struct {
+	Plan string `json:"plan" binding:"required,max=50"`
+	// Quota overrides the plan quota in bytes, leave it out to use the plan quota
+	Quota *int64 `json:"quota" binding:"omitempty,min=0"`
+}
+
+type StorageUsageResponse struct {
+	Plan string `json:"plan"`
+	// Quota is in bytes, 0 means unlimited
+	Quota         int64                    `json:"quota"`
+	Used          int64                    `json:"used"`
+	Files         int64                    `json:"files"`
+	Bytes         int64                    `json:"bytes"`
+	ByContentType []model.ContentTypeUsage `json:"by_content_type"`
+}
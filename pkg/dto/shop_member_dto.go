@@ -0,0 +1,10 @@
+package dto
+
+type InviteMemberRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required"`
+}
+
+type UpdateMemberRequest struct {
+	Role string `json:"role" binding:"required"`
+}
@@ -0,0 +1,18 @@
+package dto
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TrashItem is a soft deleted user, shop or category with the time it gets purged
+type TrashItem struct {
+	Type      string              `json:"type"`
+	ID        primitive.ObjectID  `json:"id"`
+	Name      string              `json:"name"`
+	ShopID    *primitive.ObjectID `json:"shop_id,omitempty"`
+	DeletedAt time.Time           `json:"deleted_at"`
+	DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty"`
+	PurgeAt   time.Time           `json:"purge_at"`
+}
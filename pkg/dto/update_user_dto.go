@@ -3,3 +3,7 @@ package dto
 type UpdateUserRequest struct {
 	Name string `json:"name" binding:"omitempty,min=3,max=30"`
 }
+
+type UpdateUserRolesRequest struct {
+	Roles []string `json:"roles" binding:"required,min=1"`
+}
@@ -0,0 +1,128 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+const jpegQuality = 85
+
+var (
+	ErrUnsupportedImage = errors.New("imaging: unsupported image")
+	ErrImageTooLarge    = errors.New("imaging: image has too many pixels")
+)
+
+// Supported reports whether derivatives can be generated for contentType
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// Decode reads an image and turns it upright according to its EXIF orientation.
+// The header is checked first, images of more than maxPixels (0 for no limit)
+// are refused before their pixels are allocated.
+func Decode(data []byte, maxPixels int64) (image.Image, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Join(ErrUnsupportedImage, err)
+	}
+	if maxPixels > 0 && int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, ErrImageTooLarge
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Join(ErrUnsupportedImage, err)
+	}
+	return orient(img, Orientation(data)), nil
+}
+
+// Render resizes img to fit the variant and encodes it. Go encoders write no
+// metadata, so the result never carries EXIF.
+func Render(img image.Image, v Variant) ([]byte, error) {
+	img = fit(img, v.MaxWidth, v.MaxHeight)
+
+	var buf bytes.Buffer
+	var err error
+	if v.Format == FormatPNG {
+		err = png.Encode(&buf, img)
+	} else {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// fit scales img down to fit in maxWidth x maxHeight, images are never enlarged
+func fit(img image.Image, maxWidth, maxHeight int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	scale := 1.0
+	if maxWidth > 0 && width > maxWidth {
+		scale = float64(maxWidth) / float64(width)
+	}
+	if maxHeight > 0 && height > maxHeight {
+		if s := float64(maxHeight) / float64(height); s < scale {
+			scale = s
+		}
+	}
+	if scale == 1.0 {
+		return img
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, max(1, int(float64(width)*scale)), max(1, int(float64(height)*scale))))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// orient applies an EXIF orientation (1 to 8) so the image is displayed upright
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for dy := 0; dy < dh; dy++ {
+		for dx := 0; dx < dw; dx++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-dx, dy
+			case 3:
+				sx, sy = w-1-dx, h-1-dy
+			case 4:
+				sx, sy = dx, h-1-dy
+			case 5:
+				sx, sy = dy, dx
+			case 6:
+				sx, sy = dy, h-1-dx
+			case 7:
+				sx, sy = w-1-dy, h-1-dx
+			case 8:
+				sx, sy = w-1-dy, dx
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,253 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Orientation returns the EXIF orientation of a JPEG, 1 (upright) when unknown
+func Orientation(data []byte) int {
+	for _, segment := range jpegSegments(data) {
+		if segment.marker != 0xE1 || !bytes.HasPrefix(segment.payload, []byte("Exif\x00\x00")) {
+			continue
+		}
+		if o := exifOrientation(segment.payload[6:]); o != 0 {
+			return o
+		}
+	}
+	return 1
+}
+
+// StripMetadata removes EXIF, XMP, IPTC and comments from a JPEG, textual and
+// EXIF chunks from a PNG, EXIF and XMP chunks from a WebP and comments and XMP
+// from a GIF, without re-encoding. Other formats are returned as is.
+func StripMetadata(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return stripJPEG(data)
+	case bytes.HasPrefix(data, pngSignature):
+		return stripPNG(data)
+	case len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		return stripWebP(data)
+	case bytes.HasPrefix(data, []byte("GIF87a")), bytes.HasPrefix(data, []byte("GIF89a")):
+		return stripGIF(data)
+	}
+	return data
+}
+
+type jpegSegment struct {
+	marker  byte
+	start   int
+	end     int
+	payload []byte
+}
+
+// jpegSegments lists the marker segments before the image data
+func jpegSegments(data []byte) []jpegSegment {
+	if !bytes.HasPrefix(data, []byte{0xFF, 0xD8}) {
+		return nil
+	}
+
+	var segments []jpegSegment
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			return segments
+		}
+		marker := data[i+1]
+		if marker == 0xFF {
+			// Fill byte
+			i++
+			continue
+		}
+		if marker == 0xDA || marker == 0xD9 {
+			// Start of scan, the entropy coded data follows
+			return segments
+		}
+
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + length
+		if length < 2 || end > len(data) {
+			return segments
+		}
+		segments = append(segments, jpegSegment{marker: marker, start: i, end: end, payload: data[i+4 : end]})
+		i = end
+	}
+	return segments
+}
+
+func stripJPEG(data []byte) []byte {
+	segments := jpegSegments(data)
+	if len(segments) == 0 {
+		return data
+	}
+
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	for _, segment := range segments {
+		switch segment.marker {
+		case 0xE1, 0xED, 0xFE:
+			// APP1 (EXIF, XMP), APP13 (IPTC) and comments
+			continue
+		}
+		out = append(out, data[segment.start:segment.end]...)
+	}
+	return append(out, data[segments[len(segments)-1].end:]...)
+}
+
+func stripPNG(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	for i := len(pngSignature); i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		end := i + 12 + length
+		if end > len(data) {
+			return data
+		}
+
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	return out
+}
+
+// stripWebP drops the EXIF and XMP chunks of a RIFF container and clears their
+// flags in the extended header
+func stripWebP(data []byte) []byte {
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+	for i := 12; i+8 <= len(data); {
+		size := int(binary.LittleEndian.Uint32(data[i+4:]))
+		// Chunks are padded to an even size
+		end := i + 8 + size + size&1
+		if end > len(data) {
+			// Tolerate a missing pad byte at the very end
+			if i+8+size != len(data) {
+				return data
+			}
+			end = len(data)
+		}
+
+		switch string(data[i : i+4]) {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			start := len(out)
+			out = append(out, data[i:end]...)
+			if size > 0 {
+				// Flags: 0x08 EXIF, 0x04 XMP
+				out[start+8] &^= 0x08 | 0x04
+			}
+		default:
+			out = append(out, data[i:end]...)
+		}
+		i = end
+	}
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out
+}
+
+// stripGIF drops the comment extensions and the XMP application extension
+func stripGIF(data []byte) []byte {
+	// Header and logical screen descriptor, then the global color table
+	i := 13
+	if len(data) < i {
+		return data
+	}
+	if flags := data[10]; flags&0x80 != 0 {
+		i += 3 << (flags&0x07 + 1)
+	}
+	if i > len(data) {
+		return data
+	}
+	out := append(make([]byte, 0, len(data)), data[:i]...)
+
+	// skipSubBlocks returns the end of the data sub-blocks starting at j
+	skipSubBlocks := func(j int) int {
+		for j < len(data) {
+			size := int(data[j])
+			j++
+			if size == 0 {
+				return j
+			}
+			j += size
+		}
+		return -1
+	}
+
+	for i < len(data) {
+		switch data[i] {
+		case 0x21:
+			// Extension: introducer, label and sub-blocks
+			if i+2 > len(data) {
+				return data
+			}
+			end := skipSubBlocks(i + 2)
+			if end < 0 {
+				return data
+			}
+			label := data[i+1]
+			xmp := label == 0xFF && bytes.HasPrefix(data[i+2:end], []byte{11, 'X', 'M', 'P', ' ', 'D', 'a', 't', 'a', 'X', 'M', 'P'})
+			if label != 0xFE && !xmp {
+				out = append(out, data[i:end]...)
+			}
+			i = end
+		case 0x2C:
+			// Image descriptor, local color table, LZW code size and image data
+			j := i + 10
+			if j > len(data) {
+				return data
+			}
+			if flags := data[i+9]; flags&0x80 != 0 {
+				j += 3 << (flags&0x07 + 1)
+			}
+			end := skipSubBlocks(j + 1)
+			if end < 0 {
+				return data
+			}
+			out = append(out, data[i:end]...)
+			i = end
+		default:
+			// Trailer and anything after it
+			return append(out, data[i:]...)
+		}
+	}
+	return out
+}
+
+// exifOrientation reads tag 0x0112 from the first IFD of a TIFF structure
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 0
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 0
+	}
+	entries := int(order.Uint16(tiff[offset:]))
+	for i := 0; i < entries; i++ {
+		entry := offset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 0
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 0
+}
@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// Variant is a derivative generated for every uploaded image
+type Variant struct {
+	Name string
+	// MaxWidth and MaxHeight bound the derivative keeping the aspect ratio, zero keeps the original size
+	MaxWidth  int
+	MaxHeight int
+	Format    string
+}
+
+// ParseVariants reads a list such as "thumb:200x200:jpeg,medium:800x800:jpeg,full:0x0:jpeg"
+func ParseVariants(spec string) ([]Variant, error) {
+	var variants []Variant
+	seen := map[string]bool{}
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		parts := strings.Split(item, ":")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("imaging: variant %q must look like name:WIDTHxHEIGHT:format", item)
+		}
+
+		width, height, ok := strings.Cut(parts[1], "x")
+		if !ok {
+			return nil, fmt.Errorf("imaging: variant %q has an invalid size", item)
+		}
+		w, errW := strconv.Atoi(width)
+		h, errH := strconv.Atoi(height)
+		if errW != nil || errH != nil || w < 0 || h < 0 {
+			return nil, fmt.Errorf("imaging: variant %q has an invalid size", item)
+		}
+
+		format := strings.ToLower(parts[2])
+		if format == "jpg" {
+			format = FormatJPEG
+		}
+		if format != FormatJPEG && format != FormatPNG {
+			return nil, fmt.Errorf("imaging: variant %q must be encoded as jpeg or png", item)
+		}
+
+		if seen[parts[0]] {
+			return nil, fmt.Errorf("imaging: variant %q is defined twice", parts[0])
+		}
+		seen[parts[0]] = true
+
+		variants = append(variants, Variant{Name: parts[0], MaxWidth: w, MaxHeight: h, Format: format})
+	}
+	return variants, nil
+}
+
+// ContentType is the MIME type of the derivative
+func (v Variant) ContentType() string {
+	if v.Format == FormatPNG {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
+
+// Extension is the file extension of the derivative
+func (v Variant) Extension() string {
+	if v.Format == FormatPNG {
+		return ".png"
+	}
+	return ".jpg"
+}
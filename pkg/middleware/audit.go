@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"errors"
+	"go-fiber-api/internal/model"
+	"go-fiber-api/internal/service"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// auditKey holds the details a handler attaches to its request for the audit log
+const auditKey = "audit"
+
+type auditDetails struct {
+	action       string
+	resourceType string
+	resourceID   string
+	before       interface{}
+	after        interface{}
+}
+
+type auditRequest struct {
+	details []*auditDetails
+	skip    bool
+}
+
+func auditRequestOf(c *fiber.Ctx) *auditRequest {
+	request, _ := c.Locals(auditKey).(*auditRequest)
+	if request == nil {
+		request = &auditRequest{}
+		c.Locals(auditKey, request)
+	}
+	return request
+}
+
+// Audit describes the write the handler made: before is nil for a creation and
+// after nil for a deletion. A handler writing several resources calls it for
+// each, every call is an entry. Requests without details are still recorded,
+// by method and route.
+func Audit(c *fiber.Ctx, action, resourceType, resourceID string, before, after interface{}) {
+	request := auditRequestOf(c)
+	request.details = append(request.details, &auditDetails{
+		action:       action,
+		resourceType: resourceType,
+		resourceID:   resourceID,
+		before:       before,
+		after:        after,
+	})
+}
+
+// SkipAudit leaves the request out of the audit log
+func SkipAudit(c *fiber.Ctx) {
+	auditRequestOf(c).skip = true
+}
+
+type AuditMiddleware struct {
+	auditService *service.AuditService
+}
+
+func NewAuditMiddleware(auditService *service.AuditService) *AuditMiddleware {
+	return &AuditMiddleware{
+		auditService: auditService,
+	}
+}
+
+// Record writes an audit entry for every request that may change something,
+// once the handler is done, whether it succeeded or not
+func (m *AuditMiddleware) Record() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		switch c.Method() {
+		case fiber.MethodGet, fiber.MethodHead, fiber.MethodOptions:
+			return c.Next()
+		}
+
+		err := c.Next()
+
+		request, _ := c.Locals(auditKey).(*auditRequest)
+		if request != nil && request.skip {
+			return err
+		}
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			// The error handler has not written the response yet
+			status = fiber.StatusInternalServerError
+			var fiberErr *fiber.Error
+			if errors.As(err, &fiberErr) {
+				status = fiberErr.Code
+			}
+		}
+
+		// Request values are reused by fiber once the handler returns, keep copies
+		entry := &model.AuditEntry{
+			Action:    strings.ToLower(c.Method()) + " " + c.Route().Path,
+			Method:    c.Method(),
+			Path:      strings.Clone(c.Path()),
+			Status:    status,
+			IP:        strings.Clone(c.IP()),
+			UserAgent: strings.Clone(c.Get(fiber.HeaderUserAgent)),
+			RequestID: strings.Clone(c.GetRespHeader(fiber.HeaderXRequestID)),
+			CreatedAt: time.Now(),
+		}
+		if user, ok := GetUserFromContext(c); ok {
+			entry.ActorID, entry.ActorEmail = &user.ID, user.Email
+		}
+		if request == nil || len(request.details) == 0 {
+			m.auditService.Record(entry)
+			return err
+		}
+		for _, details := range request.details {
+			resourceEntry := *entry
+			resourceEntry.Action = details.action
+			resourceEntry.ResourceType = details.resourceType
+			resourceEntry.ResourceID = details.resourceID
+			resourceEntry.Changes = service.AuditChanges(details.before, details.after)
+			m.auditService.Record(&resourceEntry)
+		}
+		return err
+	}
+}
@@ -11,15 +11,24 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
+// unverifiedPaths stay reachable for accounts that have not verified their email yet
+var unverifiedPaths = map[string]bool{
+	"/api/v1/auth/logout":             true,
+	"/api/v1/auth/refresh":            true,
+	"/api/v1/auth/email/verification": true,
+}
+
 type AuthMiddleware struct {
-	userService *service.UserService
-	config      *config.Config
+	userService   *service.UserService
+	policyService *service.PolicyService
+	config        *config.Config
 }
 
-func NewAuthMiddleware(userService *service.UserService, config *config.Config) *AuthMiddleware {
+func NewAuthMiddleware(userService *service.UserService, policyService *service.PolicyService, config *config.Config) *AuthMiddleware {
 	return &AuthMiddleware{
-		userService: userService,
-		config:      config,
+		userService:   userService,
+		policyService: policyService,
+		config:        config,
 	}
 }
 
@@ -48,10 +57,22 @@ func (m *AuthMiddleware) Protected() fiber.Handler {
 		}
 
 		user, err := m.userService.FindByID(c.Context(), claims.UserID)
-		if err != nil {
+		if err != nil || user == nil {
 			return utils.SendError(c, http.StatusUnauthorized, "User not found")
 		}
 
+		if !user.EmailVerified && !unverifiedPaths[strings.TrimSuffix(c.Path(), "/")] {
+			switch m.config.EmailVerificationMode {
+			case "block":
+				return utils.SendError(c, http.StatusForbidden, "Email address is not verified")
+			case "limit":
+				// Unverified accounts can read but not change anything
+				if c.Method() != fiber.MethodGet && c.Method() != fiber.MethodHead {
+					return utils.SendError(c, http.StatusForbidden, "Email address must be verified to perform this action")
+				}
+			}
+		}
+
 		c.Locals("user", user)
 		c.Locals("token", token)
 		c.Locals("claims", claims)
@@ -80,6 +101,26 @@ func (m *AuthMiddleware) RequireRoles(roles ...utils.Role) fiber.Handler {
 	}
 }
 
+// RequirePermission checks if user has any of the required permissions through their roles
+func (m *AuthMiddleware) RequirePermission(permissions ...utils.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		user, ok := c.Locals("user").(*model.User)
+		if !ok {
+			return utils.SendError(c, http.StatusUnauthorized, "User not found in context")
+		}
+
+		allowed, err := m.policyService.HasPermission(c.Context(), user, permissions...)
+		if err != nil {
+			return utils.SendError(c, http.StatusInternalServerError, "Failed to check permissions")
+		}
+		if !allowed {
+			return utils.SendError(c, http.StatusForbidden, "Insufficient permissions")
+		}
+
+		return c.Next()
+	}
+}
+
 // GetUserFromContext retrieves user from context
 func GetUserFromContext(c *fiber.Ctx) (*model.User, bool) {
 	user, ok := c.Locals("user").(*model.User)
@@ -1,71 +1,63 @@
 package middleware
 
 import (
+	"context"
+	"go-fiber-api/pkg/ratelimit"
 	"go-fiber-api/pkg/utils"
+	"log"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 )
 
+const (
+	HeaderRateLimitLimit     = "X-RateLimit-Limit"
+	HeaderRateLimitRemaining = "X-RateLimit-Remaining"
+	HeaderRateLimitReset     = "X-RateLimit-Reset"
+)
+
 type RateLimiter struct {
-	rate     int
-	interval time.Duration
-	mu       sync.Mutex
-	tokens   map[string][]time.Time
+	store ratelimit.Store
 }
 
-func NewRateLimiter(rate int, interval time.Duration) *RateLimiter {
+func NewRateLimiter(store ratelimit.Store) *RateLimiter {
 	return &RateLimiter{
-		rate:     rate,
-		interval: interval,
-		tokens:   make(map[string][]time.Time),
-	}
-}
-
-func (rl *RateLimiter) Allow(key string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	windowStart := now.Add(-rl.interval)
-
-	if _, exists := rl.tokens[key]; !exists {
-		rl.tokens[key] = []time.Time{now}
-		return true
-	}
-
-	var validTokens []time.Time
-	for _, t := range rl.tokens[key] {
-		if t.After(windowStart) {
-			validTokens = append(validTokens, t)
-		}
-	}
-
-	if len(validTokens) < rl.rate {
-		validTokens = append(validTokens, now)
-		rl.tokens[key] = validTokens
-		return true
+		store: store,
 	}
-
-	rl.tokens[key] = validTokens
-	return false
 }
 
-func RateLimit(rate int, interval time.Duration) fiber.Handler {
-	limiter := NewRateLimiter(rate, interval)
-
+// Limit allows rate requests per interval from each IP. Name separates the
+// windows of the limits, a request passing several limits counts in each.
+// X-RateLimit-Reset is the unix time a slot frees up.
+func (rl *RateLimiter) Limit(name string, rate int, interval time.Duration) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// use ip
-		key := c.IP()
+		key := name + ":" + c.IP()
 
 		// or use user id on jwt
 		// if user, ok := c.Locals("user").(string); ok {
 		// 		key = user
 		// }
 
-		if !limiter.Allow(key) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		result, err := rl.store.Take(ctx, key, rate, interval)
+		if err != nil {
+			// Better to serve without limits than not at all
+			log.Printf("ratelimit: %s: %v", name, err)
+			return c.Next()
+		}
+
+		c.Set(HeaderRateLimitLimit, strconv.Itoa(result.Limit))
+		c.Set(HeaderRateLimitRemaining, strconv.Itoa(result.Remaining))
+		c.Set(HeaderRateLimitReset, strconv.FormatInt(int64(math.Ceil(float64(result.Reset.UnixMilli())/1000)), 10))
+
+		if !result.Allowed {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(result.RetryAfter.Seconds()))))
 			return utils.SendError(c, http.StatusTooManyRequests, "Rate limit exceeded. Please try again later.")
 		}
 
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Message is a single notification sent to a user
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users (email, sms, ...)
+type Notifier interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns the notifier selected by NOTIFIER_DRIVER, defaulting to the log notifier
+func New(cfg *config.Config) Notifier {
+	switch cfg.NotifierDriver {
+	case "file":
+		return NewFileNotifier(cfg.NotifierFilePath)
+	default:
+		return NewLogNotifier()
+	}
+}
+
+type logNotifier struct{}
+
+// NewLogNotifier writes messages to the application log, useful for local development
+func NewLogNotifier() Notifier {
+	return &logNotifier{}
+}
+
+func (n *logNotifier) Send(ctx context.Context, msg *Message) error {
+	log.Printf("notify to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+type fileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileNotifier appends messages to a file so they can be read back in local setups and tests
+func NewFileNotifier(path string) Notifier {
+	if path == "" {
+		path = "./tmp/notifications.log"
+	}
+	return &fileNotifier{path: path}
+}
+
+func (n *fileNotifier) Send(ctx context.Context, msg *Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(n.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "[%s] to=%s subject=%q\n%s\n\n", time.Now().Format(time.RFC3339), msg.To, msg.Subject, msg.Body)
+	return err
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often keys without requests in their window are evicted
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+// MemoryStore keeps the windows in process, limits are per replica and reset on restart
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]*memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		s.sweep(now)
+	}
+
+	entry, ok := s.entries[key]
+	if !ok {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.window = window
+
+	// Hits are in time order, drop the ones that left the window
+	windowStart := now.Add(-window)
+	expired := 0
+	for expired < len(entry.hits) && !entry.hits[expired].After(windowStart) {
+		expired++
+	}
+	entry.hits = entry.hits[expired:]
+
+	allowed := len(entry.hits) < limit
+	if allowed {
+		entry.hits = append(entry.hits, now)
+	}
+
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: limit - len(entry.hits),
+		Reset:     now,
+	}
+	if len(entry.hits) > 0 {
+		result.Reset = entry.hits[0].Add(window)
+	}
+	if !allowed {
+		result.RetryAfter = result.Reset.Sub(now)
+	}
+	return result, nil
+}
+
+// sweep evicts the keys whose last request is out of their window
+func (s *MemoryStore) sweep(now time.Time) {
+	for key, entry := range s.entries {
+		if len(entry.hits) == 0 || !entry.hits[len(entry.hits)-1].Add(entry.window).After(now) {
+			delete(s.entries, key)
+		}
+	}
+	s.lastSweep = now
+}
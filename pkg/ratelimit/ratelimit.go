@@ -0,0 +1,86 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StoreRedis  = "redis"
+	StoreMemory = "memory"
+)
+
+// Result is the state of a key's window after a request was counted. Reset is
+// when the oldest request in the window expires and frees a slot.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Time
+	RetryAfter time.Duration
+}
+
+// Store counts requests per key over a sliding window
+type Store interface {
+	// Take counts a request on key when fewer than limit were made in the last window
+	Take(ctx context.Context, key string, limit int, window time.Duration) (*Result, error)
+}
+
+// New builds the store selected by RATE_LIMIT_STORE
+func New(cfg *config.Config, client *redis.Client) (Store, error) {
+	switch cfg.RateLimitStore {
+	case StoreRedis, "":
+		return NewFallback(NewRedisStore(client), NewMemoryStore()), nil
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("ratelimit: unknown store %q", cfg.RateLimitStore)
+	}
+}
+
+// Fallback counts in secondary while primary fails, so an unreachable redis
+// degrades the limits to per process instead of turning them off
+type Fallback struct {
+	primary   Store
+	secondary Store
+
+	mu      sync.Mutex
+	failing bool
+}
+
+func NewFallback(primary, secondary Store) *Fallback {
+	return &Fallback{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+func (f *Fallback) Take(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	result, err := f.primary.Take(ctx, key, limit, window)
+	f.setFailing(err)
+	if err != nil {
+		return f.secondary.Take(ctx, key, limit, window)
+	}
+	return result, nil
+}
+
+// setFailing logs when the primary store goes down and comes back, not on every request
+func (f *Fallback) setFailing(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if failing := err != nil; failing != f.failing {
+		f.failing = failing
+		if failing {
+			log.Printf("ratelimit: primary store failed, falling back: %v", err)
+		} else {
+			log.Println("ratelimit: primary store recovered")
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const redisKeyPrefix = "ratelimit:"
+
+// slidingWindow keeps the requests of the window in a sorted set scored by
+// their time in microseconds. It reads the clock of redis so replicas with
+// drifting clocks share the same window, which needs redis 5 or later.
+// Returns allowed, requests in the window, now and reset.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+	redis.call('ZADD', key, now, ARGV[3])
+	count = count + 1
+	allowed = 1
+end
+redis.call('PEXPIRE', key, math.ceil(window / 1000))
+
+local reset = now
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+	reset = tonumber(oldest[2]) + window
+end
+return {allowed, count, now, reset}
+`)
+
+// RedisStore shares the windows between every replica using the same redis,
+// a key expires on its own once its window is empty
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit int, window time.Duration) (*Result, error) {
+	// The member only has to be unique, requests in the same microsecond all count
+	values, err := slidingWindow.Run(ctx, s.client, []string{redisKeyPrefix + key},
+		limit, window.Microseconds(), uuid.NewString()).Int64Slice()
+	if err != nil {
+		return nil, err
+	}
+
+	allowed, count, now, reset := values[0] == 1, int(values[1]), time.UnixMicro(values[2]), time.UnixMicro(values[3])
+	result := &Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: limit - count,
+		Reset:     reset,
+	}
+	if !allowed {
+		result.RetryAfter = reset.Sub(now)
+	}
+	return result, nil
+}
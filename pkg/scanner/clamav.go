@@ -0,0 +1,110 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// clamdChunkSize is the size of the INSTREAM chunks, clamd limits a whole
+// stream with StreamMaxLength, not single chunks
+const clamdChunkSize = 64 << 10
+
+// ErrStreamTooLarge is returned when clamd refuses content above its StreamMaxLength
+var ErrStreamTooLarge = errors.New("scanner: content exceeds the clamd stream limit")
+
+type clamAV struct {
+	network string
+	address string
+}
+
+// NewClamAV talks to a clamd daemon at address, tcp://host:port or unix:///path/clamd.sock
+func NewClamAV(address string) (Scanner, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("scanner: invalid clamd address %q: %w", address, err)
+	}
+	switch u.Scheme {
+	case "tcp":
+		return &clamAV{network: "tcp", address: u.Host}, nil
+	case "unix":
+		return &clamAV{network: "unix", address: u.Path}, nil
+	}
+	return nil, fmt.Errorf("scanner: clamd address %q must start with tcp:// or unix://", address)
+}
+
+func (s *clamAV) Name() string {
+	return DriverClamAV
+}
+
+// Scan streams r to clamd with the INSTREAM command
+func (s *clamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, s.network, s.address)
+	if err != nil {
+		return nil, fmt.Errorf("scanner: connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(5 * time.Minute))
+	}
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, err
+	}
+
+	chunk := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := io.ReadFull(r, chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			_, err := conn.Write(size)
+			if err == nil {
+				_, err = conn.Write(chunk[:n])
+			}
+			// clamd closes the stream early when it is over its limit, its reply says so
+			if err != nil {
+				break
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	conn.Write(size)
+
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && len(reply) == 0 {
+		return nil, fmt.Errorf("scanner: read clamd reply: %w", err)
+	}
+	return parseClamdReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamdReply reads "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+func parseClamdReply(reply string) (*Result, error) {
+	_, verdict, _ := strings.Cut(reply, ": ")
+	switch {
+	case verdict == "OK":
+		return &Result{Clean: true}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.Contains(reply, "size limit exceeded"):
+		return nil, ErrStreamTooLarge
+	}
+	return nil, fmt.Errorf("scanner: clamd: %s", reply)
+}
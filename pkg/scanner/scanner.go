@@ -0,0 +1,50 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"io"
+)
+
+const (
+	DriverNone   = "none"
+	DriverClamAV = "clamav"
+)
+
+// Result is the verdict on scanned content. Signature names the threat found
+// when Clean is false.
+type Result struct {
+	Clean     bool
+	Signature string
+}
+
+// Scanner checks uploaded content for malware
+type Scanner interface {
+	// Name is recorded on the files it scanned
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// New builds the scanner selected by SCANNER_DRIVER
+func New(cfg *config.Config) (Scanner, error) {
+	switch cfg.ScannerDriver {
+	case DriverNone, "":
+		return Noop{}, nil
+	case DriverClamAV:
+		return NewClamAV(cfg.ClamdAddress)
+	default:
+		return nil, fmt.Errorf("scanner: unknown driver %q", cfg.ScannerDriver)
+	}
+}
+
+// Noop accepts everything, it is the default when no scanner is configured
+type Noop struct{}
+
+func (Noop) Name() string {
+	return DriverNone
+}
+
+func (Noop) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	return &Result{Clean: true}, nil
+}
@@ -0,0 +1,138 @@
+package signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("signer: invalid signature")
+	ErrExpired          = errors.New("signer: link expired")
+	ErrUnknownKey       = errors.New("signer: unknown signing key")
+)
+
+// Key is a named HMAC secret, the name travels with the signature so the
+// secret can be found again after the active key changed
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// Claims is what a signed link grants
+type Claims struct {
+	Resource  string
+	Variant   string
+	ExpiresAt time.Time
+	SingleUse bool
+	Nonce     string
+}
+
+// Signer signs with its first key and verifies with any of them, so keys can
+// be rotated by prepending a new one and dropping the old one once its links expired
+type Signer struct {
+	keys []Key
+}
+
+func New(keys []Key) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("signer: at least one key is required")
+	}
+	for _, key := range keys {
+		if key.ID == "" || len(key.Secret) == 0 {
+			return nil, errors.New("signer: keys need an id and a secret")
+		}
+	}
+	return &Signer{keys: keys}, nil
+}
+
+// ParseKeys reads "id:secret,id2:secret2", the first key is the active one
+func ParseKeys(spec string) ([]Key, error) {
+	var keys []Key
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(item, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("signer: key %q must look like id:secret", item)
+		}
+		keys = append(keys, Key{ID: id, Secret: []byte(secret)})
+	}
+	return keys, nil
+}
+
+// Sign returns the query parameters authorizing claims
+func (s *Signer) Sign(claims Claims) url.Values {
+	key := s.keys[0]
+
+	values := url.Values{}
+	values.Set("exp", strconv.FormatInt(claims.ExpiresAt.Unix(), 10))
+	values.Set("kid", key.ID)
+	if claims.Variant != "" {
+		values.Set("variant", claims.Variant)
+	}
+	if claims.SingleUse {
+		values.Set("once", "1")
+		values.Set("nonce", claims.Nonce)
+	}
+	values.Set("sig", signature(key.Secret, claims.Resource, values))
+	return values
+}
+
+// Verify checks the signature and expiry of values for resource
+func (s *Signer) Verify(resource string, values url.Values, now time.Time) (*Claims, error) {
+	var key *Key
+	for i := range s.keys {
+		if s.keys[i].ID == values.Get("kid") {
+			key = &s.keys[i]
+		}
+	}
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+
+	expected := signature(key.Secret, resource, values)
+	if !hmac.Equal([]byte(expected), []byte(values.Get("sig"))) {
+		return nil, ErrInvalidSignature
+	}
+
+	exp, err := strconv.ParseInt(values.Get("exp"), 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	claims := &Claims{
+		Resource:  resource,
+		Variant:   values.Get("variant"),
+		ExpiresAt: time.Unix(exp, 0),
+		SingleUse: values.Get("once") == "1",
+		Nonce:     values.Get("nonce"),
+	}
+	if !now.Before(claims.ExpiresAt) {
+		return nil, ErrExpired
+	}
+	return claims, nil
+}
+
+// signature covers the resource and every signed parameter
+func signature(secret []byte, resource string, values url.Values) string {
+	payload := strings.Join([]string{
+		resource,
+		values.Get("exp"),
+		values.Get("kid"),
+		values.Get("variant"),
+		values.Get("once"),
+		values.Get("nonce"),
+	}, "\n")
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,188 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+type localStorage struct {
+	root string
+}
+
+// NewLocal stores objects as files below root
+func NewLocal(root string) (Storage, error) {
+	if root == "" {
+		root = "./uploads"
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &localStorage{root: root}, nil
+}
+
+func (s *localStorage) Name() string {
+	return DriverLocal
+}
+
+func (s *localStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	dst, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+
+	// Write next to the destination and rename, readers never see a partial file
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+func (s *localStorage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, s.object(key, info), nil
+}
+
+func (s *localStorage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(file, length), file}, nil
+}
+
+func (s *localStorage) Delete(ctx context.Context, key string) error {
+	p, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStorage) Stat(ctx context.Context, key string) (*Object, error) {
+	p, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.object(key, info), nil
+}
+
+func (s *localStorage) List(ctx context.Context, prefix string, fn func(*Object) error) error {
+	return filepath.WalkDir(s.root, func(p string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// Hidden entries are writes in progress
+		if strings.HasPrefix(entry.Name(), ".") && p != s.root {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(s.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		return fn(s.object(key, info))
+	})
+}
+
+func (s *localStorage) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "", ErrPresignNotSupported
+}
+
+// path maps key below root, refusing keys that would escape it
+func (s *localStorage) path(key string) (string, error) {
+	clean := path.Clean("/" + key)
+	if key == "" || clean == "/" || strings.Contains(key, "..") {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func (s *localStorage) object(key string, info os.FileInfo) *Object {
+	return &Object{
+		Key:          key,
+		Size:         info.Size(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(key)),
+		LastModified: info.ModTime(),
+	}
+}
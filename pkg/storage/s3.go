@@ -0,0 +1,410 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm      = "AWS4-HMAC-SHA256"
+	s3UnsignedBody   = "UNSIGNED-PAYLOAD"
+	s3TimeFormat     = "20060102T150405Z"
+	s3DateFormat     = "20060102"
+	s3MaxPresignTime = 7 * 24 * time.Hour
+)
+
+// S3Config configures an S3 compatible backend such as AWS S3 or MinIO
+type S3Config struct {
+	// Endpoint is the service URL, e.g. https://s3.amazonaws.com or http://minio:9000
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// UsePathStyle puts the bucket in the path instead of the host, MinIO needs it
+	UsePathStyle bool
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+	// Now defaults to time.Now, tests set it to sign with a fixed time
+	Now func() time.Time
+}
+
+type s3Storage struct {
+	cfg      S3Config
+	endpoint *url.URL
+}
+
+// NewS3 returns a driver talking to an S3 compatible API with AWS signature version 4
+func NewS3(cfg S3Config) (Storage, error) {
+	if cfg.Endpoint == "" || cfg.Bucket == "" || cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, errors.New("storage: s3 needs an endpoint, bucket, access key and secret key")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("storage: invalid s3 endpoint: %w", err)
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = http.DefaultClient
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &s3Storage{cfg: cfg, endpoint: endpoint}, nil
+}
+
+func (s *s3Storage) Name() string {
+	return DriverS3
+}
+
+func (s *s3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *s3Storage) Get(ctx context.Context, key string) (io.ReadCloser, *Object, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res.Body, s.object(key, res), nil
+}
+
+func (s *s3Storage) GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	return nil
+}
+
+func (s *s3Storage) Stat(ctx context.Context, key string) (*Object, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	res.Body.Close()
+	return s.object(key, res), nil
+}
+
+// List pages through ListObjectsV2
+func (s *s3Storage) List(ctx context.Context, prefix string, fn func(*Object) error) error {
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.UsePathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/"
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/"
+	}
+
+	var token string
+	for {
+		query := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			query.Set("prefix", prefix)
+		}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		u.RawQuery = canonicalQuery(query)
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+		if err != nil {
+			return err
+		}
+		res, err := s.do(req)
+		if err != nil {
+			return err
+		}
+		var page struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				ETag         string    `xml:"ETag"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		err = xml.NewDecoder(res.Body).Decode(&page)
+		res.Body.Close()
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Contents {
+			if err := fn(&Object{
+				Key:          item.Key,
+				Size:         item.Size,
+				ETag:         strings.Trim(item.ETag, `"`),
+				LastModified: item.LastModified,
+			}); err != nil {
+				return err
+			}
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *s3Storage) Presign(ctx context.Context, key string, expires time.Duration) (string, error) {
+	if expires <= 0 || expires > s3MaxPresignTime {
+		return "", fmt.Errorf("storage: presign expiry must be between 1s and %s", s3MaxPresignTime)
+	}
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	now := s.cfg.Now().UTC()
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", s.cfg.AccessKey+"/"+s.scope(now))
+	query.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	u.RawQuery = canonicalQuery(query)
+
+	header := http.Header{}
+	header.Set("Host", u.Host)
+	signature := s.signature(http.MethodGet, u, header, s3UnsignedBody, now)
+	u.RawQuery += "&X-Amz-Signature=" + signature
+	return u.String(), nil
+}
+
+func (s *s3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req, turning error responses into errors
+func (s *s3Storage) do(req *http.Request) (*http.Response, error) {
+	now := s.cfg.Now().UTC()
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", now.Format(s3TimeFormat))
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedBody)
+
+	signedHeaders, _ := canonicalHeaders(req.Header)
+	signature := s.signature(req.Method, req.URL, req.Header, s3UnsignedBody, now)
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, s.cfg.AccessKey, s.scope(now), signedHeaders, signature,
+	))
+	req.Header.Del("Host")
+
+	res, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode < 300 {
+		return res, nil
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	var apiErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+	if xml.Unmarshal(body, &apiErr) == nil && apiErr.Code != "" {
+		return nil, fmt.Errorf("storage: s3 %s %s: %s: %s", req.Method, req.URL.Path, apiErr.Code, apiErr.Message)
+	}
+	return nil, fmt.Errorf("storage: s3 %s %s: %s", req.Method, req.URL.Path, res.Status)
+}
+
+func (s *s3Storage) objectURL(key string) (*url.URL, error) {
+	if key == "" || strings.HasPrefix(key, "/") {
+		return nil, ErrInvalidKey
+	}
+
+	u := *s.endpoint
+	base := strings.TrimSuffix(u.Path, "/")
+	if s.cfg.UsePathStyle {
+		u.Path = base + "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path = base + "/" + key
+	}
+	u.RawPath = encodePath(u.Path)
+	return &u, nil
+}
+
+func (s *s3Storage) object(key string, res *http.Response) *Object {
+	obj := &Object{
+		Key:         key,
+		Size:        res.ContentLength,
+		ContentType: res.Header.Get("Content-Type"),
+		ETag:        strings.Trim(res.Header.Get("ETag"), `"`),
+	}
+	if modified, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		obj.LastModified = modified
+	}
+	return obj
+}
+
+func (s *s3Storage) scope(t time.Time) string {
+	return t.Format(s3DateFormat) + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature computes the AWS signature version 4 of a request
+func (s *s3Storage) signature(method string, u *url.URL, header http.Header, payloadHash string, t time.Time) string {
+	signedHeaders, headers := canonicalHeaders(header)
+	canonicalRequest := strings.Join([]string{
+		method,
+		encodePath(u.Path),
+		canonicalQuery(u.Query()),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		t.Format(s3TimeFormat),
+		s.scope(t),
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+s.cfg.SecretKey), t.Format(s3DateFormat))
+	key = hmacSHA256(key, s.cfg.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+// canonicalHeaders signs host and every x-amz-* header
+func canonicalHeaders(header http.Header) (string, string) {
+	var names []string
+	for name := range header {
+		lower := strings.ToLower(name)
+		if lower == "host" || strings.HasPrefix(lower, "x-amz-") {
+			names = append(names, lower)
+		}
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(header.Get(name)) + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		values := append([]string(nil), query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			parts = append(parts, encodeURIComponent(k)+"="+encodeURIComponent(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+func encodePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, segment := range segments {
+		segments[i] = encodeURIComponent(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// encodeURIComponent escapes everything but the unreserved characters of RFC 3986
+func encodeURIComponent(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
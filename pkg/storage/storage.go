@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-fiber-api/internal/config"
+	"io"
+	"time"
+)
+
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+var (
+	ErrNotFound            = errors.New("storage: object not found")
+	ErrPresignNotSupported = errors.New("storage: presigned urls are not supported by this driver")
+	ErrInvalidKey          = errors.New("storage: invalid object key")
+)
+
+// Object describes a stored blob
+type Object struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	LastModified time.Time
+	ETag         string
+}
+
+// Storage keeps file contents by key. Keys use forward slashes whatever the driver.
+type Storage interface {
+	// Name is the driver name recorded on model.FileStore.Backend
+	Name() string
+	// Put stores size bytes read from r under key, replacing any previous content
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the content of key, the caller must close the reader
+	Get(ctx context.Context, key string) (io.ReadCloser, *Object, error)
+	// GetRange opens length bytes of key starting at offset
+	GetRange(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete removes key, deleting a missing key is not an error
+	Delete(ctx context.Context, key string) error
+	Stat(ctx context.Context, key string) (*Object, error)
+	// List calls fn with every object whose key starts with prefix
+	List(ctx context.Context, prefix string, fn func(*Object) error) error
+	// Presign returns a URL that downloads key without credentials until expires
+	Presign(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// Backends holds every configured driver by name. New files go to Default,
+// existing files are read from the driver they were written with.
+type Backends struct {
+	Default Storage
+	drivers map[string]Storage
+}
+
+func NewBackends(defaultDriver Storage, others ...Storage) *Backends {
+	b := &Backends{Default: defaultDriver, drivers: map[string]Storage{}}
+	for _, s := range append(others, defaultDriver) {
+		b.drivers[s.Name()] = s
+	}
+	return b
+}
+
+// Get returns the driver called name, an empty name is the local driver files used before backends existed
+func (b *Backends) Get(name string) (Storage, error) {
+	if name == "" {
+		name = DriverLocal
+	}
+	s, ok := b.drivers[name]
+	if !ok {
+		return nil, fmt.Errorf("storage: backend %q is not configured", name)
+	}
+	return s, nil
+}
+
+// New builds the backends from STORAGE_DRIVER. The local driver is always
+// available so files written before switching to S3 can still be read.
+func New(cfg *config.Config) (*Backends, error) {
+	local, err := NewLocal(cfg.StorageLocalPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cfg.StorageDriver {
+	case DriverS3:
+		s3, err := NewS3(S3Config{
+			Endpoint:     cfg.S3Endpoint,
+			Region:       cfg.S3Region,
+			Bucket:       cfg.S3Bucket,
+			AccessKey:    cfg.S3AccessKey,
+			SecretKey:    cfg.S3SecretKey,
+			UsePathStyle: cfg.S3UsePathStyle,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewBackends(s3, local), nil
+	case DriverLocal, "":
+		return NewBackends(local), nil
+	default:
+		return nil, fmt.Errorf("storage: unknown driver %q", cfg.StorageDriver)
+	}
+}
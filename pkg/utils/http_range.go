@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// maxRanges bounds how many ranges a request may ask for, to avoid tiny-range floods
+const maxRanges = 16
+
+var ErrUnsatisfiableRange = errors.New("range not satisfiable")
+
+// ByteRange is Length bytes of a resource starting at Start
+type ByteRange struct {
+	Start  int64
+	Length int64
+}
+
+// ContentRange formats the range for a Content-Range header
+func (r ByteRange) ContentRange(size int64) string {
+	return "bytes " + strconv.FormatInt(r.Start, 10) + "-" + strconv.FormatInt(r.Start+r.Length-1, 10) + "/" + strconv.FormatInt(size, 10)
+}
+
+// ParseRange parses a Range header (RFC 9110) against a resource of size bytes.
+// It returns nil when the header is absent or not a bytes range, in which case
+// the whole resource should be sent, and ErrUnsatisfiableRange when no range fits.
+func ParseRange(header string, size int64) ([]ByteRange, error) {
+	unit, spec, ok := strings.Cut(strings.TrimSpace(header), "=")
+	if !ok || strings.TrimSpace(unit) != "bytes" {
+		return nil, nil
+	}
+
+	var ranges []ByteRange
+	parts := strings.Split(spec, ",")
+	if len(parts) > maxRanges {
+		return nil, ErrUnsatisfiableRange
+	}
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		first, last, ok := strings.Cut(part, "-")
+		if !ok {
+			return nil, nil
+		}
+		first, last = strings.TrimSpace(first), strings.TrimSpace(last)
+
+		var r ByteRange
+		if first == "" {
+			// Suffix range: the last N bytes
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return nil, nil
+			}
+			if n == 0 || size == 0 {
+				continue
+			}
+			if n > size {
+				n = size
+			}
+			r = ByteRange{Start: size - n, Length: n}
+		} else {
+			start, err := strconv.ParseInt(first, 10, 64)
+			if err != nil || start < 0 {
+				return nil, nil
+			}
+			if start >= size {
+				continue
+			}
+			end := size - 1
+			if last != "" {
+				end, err = strconv.ParseInt(last, 10, 64)
+				if err != nil || end < start {
+					return nil, nil
+				}
+				if end >= size {
+					end = size - 1
+				}
+			}
+			r = ByteRange{Start: start, Length: end - start + 1}
+		}
+		ranges = append(ranges, r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrUnsatisfiableRange
+	}
+	return ranges, nil
+}
+
+// ETagMatches reports whether an If-None-Match or If-Match header lists etag.
+// Weak comparison is used, as RFC 9110 requires for If-None-Match.
+func ETagMatches(header, etag string) bool {
+	if strings.TrimSpace(header) == "*" {
+		return true
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(candidate), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -28,6 +28,16 @@ func SendError(c *fiber.Ctx, status int, message string) error {
 	})
 }
 
+// SendErrorFrom sends the status and message of a *fiber.Error returned by a service,
+// falling back to the given status and message for any other error
+func SendErrorFrom(c *fiber.Ctx, err error, status int, message string) error {
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		return SendError(c, e.Code, e.Message)
+	}
+	return SendError(c, status, message)
+}
+
 func SendValidationError(c *fiber.Ctx, err error) error {
 	errors := FormatValidationError(err)
 	return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -51,6 +61,8 @@ func FormatValidationError(err error) []string {
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must be at least %s characters", e.Field(), e.Param()))
 			case "max":
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must not exceed %s characters", e.Field(), e.Param()))
+			case "gte":
+				errorMessages = append(errorMessages, fmt.Sprintf("%s must be greater than or equal to %s", e.Field(), e.Param()))
 			case "eqfield":
 				errorMessages = append(errorMessages, fmt.Sprintf("%s must be equal to %s", e.Field(), e.Param()))
 			case "password_validator":
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MoneyScale is the number of minor units (cents) in one unit of currency.
+// Amounts are always stored as int64 minor units, never as float.
+const MoneyScale = 100
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+// ParseMoney parses a decimal string such as "12.50" into minor units
+func ParseMoney(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	negative := strings.HasPrefix(value, "-")
+	value = strings.TrimPrefix(strings.TrimPrefix(value, "-"), "+")
+
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if whole == "" && (!hasFraction || fraction == "") {
+		return 0, ErrInvalidAmount
+	}
+	if len(fraction) > 2 {
+		return 0, ErrInvalidAmount
+	}
+	for len(fraction) < 2 {
+		fraction += "0"
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	units, err := strconv.ParseUint(whole, 10, 63)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	cents, err := strconv.ParseUint(fraction, 10, 8)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+	if units > (1<<63-1-cents)/MoneyScale {
+		return 0, ErrInvalidAmount
+	}
+
+	amount := int64(units)*MoneyScale + int64(cents)
+	if negative {
+		amount = -amount
+	}
+	return amount, nil
+}
+
+// FormatMoney formats minor units as a decimal string such as "12.50"
+func FormatMoney(amount int64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, amount/MoneyScale, amount%MoneyScale)
+}
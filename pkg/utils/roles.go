@@ -3,10 +3,142 @@ package utils
 type Role string
 
 const (
-	UserRole  Role = "user"
-	AdminRole Role = "admin"
+	UserRole      Role = "user"
+	ModeratorRole Role = "moderator"
+	AdminRole     Role = "admin"
 )
 
+// Permission is a named capability granted to roles, e.g. shop:update:own
+type Permission string
+
+// Action is a permission that can be granted on own resources or on any resource
+type Action string
+
+const (
+	ShopUpdate     Action = "shop:update"
+	ShopDelete     Action = "shop:delete"
+	CategoryManage Action = "category:manage"
+	CategoryRead   Action = "category:read"
+	ProductManage  Action = "product:manage"
+	FileUpload     Action = "file:upload"
+	FileDownload   Action = "file:download"
+	MemberManage   Action = "member:manage"
+	BudgetManage   Action = "budget:manage"
+)
+
+// Own is the permission to perform the action on resources created by the user
+func (a Action) Own() Permission {
+	return Permission(a + ":own")
+}
+
+// Any is the permission to perform the action on every resource
+func (a Action) Any() Permission {
+	return Permission(a + ":any")
+}
+
+const (
+	ShopCreate      Permission = "shop:create"
+	UserRead        Permission = "user:read"
+	UserUpdate      Permission = "user:update"
+	UserDelete      Permission = "user:delete"
+	UserAssignRoles Permission = "user:roles"
+	RoleManage      Permission = "role:manage"
+	StorageManage   Permission = "storage:manage"
+	TrashManage     Permission = "trash:manage"
+	AuditRead       Permission = "audit:read"
+)
+
+// AllPermissions lists every permission the API checks
+var AllPermissions = []Permission{
+	ShopCreate,
+	ShopUpdate.Own(), ShopUpdate.Any(),
+	ShopDelete.Own(), ShopDelete.Any(),
+	CategoryManage.Own(), CategoryManage.Any(),
+	CategoryRead.Any(),
+	ProductManage.Own(), ProductManage.Any(),
+	FileUpload.Own(), FileUpload.Any(),
+	FileDownload.Own(), FileDownload.Any(),
+	MemberManage.Own(), MemberManage.Any(),
+	BudgetManage.Own(), BudgetManage.Any(),
+	UserRead, UserUpdate, UserDelete, UserAssignRoles,
+	RoleManage,
+	StorageManage,
+	TrashManage,
+	AuditRead,
+}
+
+// DefaultRolePermissions is seeded into the roles collection when a role does not exist yet
+var DefaultRolePermissions = map[Role][]Permission{
+	UserRole: {
+		ShopCreate,
+		ShopUpdate.Own(),
+		ShopDelete.Own(),
+		CategoryManage.Own(),
+		ProductManage.Own(),
+		FileUpload.Own(),
+		FileDownload.Own(),
+		MemberManage.Own(),
+		BudgetManage.Own(),
+	},
+	ModeratorRole: {
+		ShopCreate,
+		ShopUpdate.Own(), ShopUpdate.Any(),
+		ShopDelete.Own(), ShopDelete.Any(),
+		CategoryManage.Own(), CategoryManage.Any(),
+		CategoryRead.Any(),
+		ProductManage.Own(), ProductManage.Any(),
+		FileUpload.Own(), FileUpload.Any(),
+		FileDownload.Own(), FileDownload.Any(),
+		MemberManage.Own(),
+		BudgetManage.Own(),
+		UserRead,
+	},
+	AdminRole: AllPermissions,
+}
+
+// ShopRole is the role of a member inside a single shop
+type ShopRole string
+
+const (
+	ShopOwner   ShopRole = "owner"
+	ShopManager ShopRole = "manager"
+	ShopStaff   ShopRole = "staff"
+)
+
+// ShopRoleActions lists what each shop role may do in its shop. The :own
+// permissions of a user apply to the shops where their role allows the action.
+var ShopRoleActions = map[ShopRole][]Action{
+	ShopOwner:   {ShopUpdate, ShopDelete, CategoryManage, ProductManage, FileUpload, FileDownload, MemberManage, BudgetManage},
+	ShopManager: {ShopUpdate, CategoryManage, ProductManage, FileUpload, FileDownload, BudgetManage},
+	ShopStaff:   {ProductManage, FileDownload},
+}
+
+// IsKnownShopRole reports whether role is one of ShopRoleActions
+func IsKnownShopRole(role string) bool {
+	_, ok := ShopRoleActions[ShopRole(role)]
+	return ok
+}
+
+// ShopRoleAllows reports whether the shop role may perform the action
+func ShopRoleAllows(role ShopRole, action Action) bool {
+	for _, a := range ShopRoleActions[role] {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// IsKnownPermission reports whether p is one of AllPermissions
+func IsKnownPermission(p string) bool {
+	for _, permission := range AllPermissions {
+		if string(permission) == p {
+			return true
+		}
+	}
+	return false
+}
+
 func IsValidRole(r []Role, reqRole []Role) bool {
 	hasRequiredRole := false
 	for _, requiredRole := range reqRole {
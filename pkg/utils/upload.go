@@ -0,0 +1,161 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-fiber-api/pkg/dto"
+	"io"
+	"mime/multipart"
+	"path/filepath"
+	"strings"
+
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// sniffLength is how many leading bytes are read to detect the content type
+const sniffLength = 3072
+
+var (
+	ErrFileTooLarge        = errors.New("file is too large")
+	ErrRequestTooLarge     = errors.New("upload request is too large")
+	ErrUnsupportedFileType = errors.New("file type is not allowed")
+)
+
+// UploadLimits bounds what Upload accepts. Zero sizes and an empty allow-list
+// mean no limit.
+type UploadLimits struct {
+	MaxFileSize    int64
+	MaxRequestSize int64
+	// AllowedTypes holds MIME types such as image/png or wildcards such as image/*
+	AllowedTypes []string
+}
+
+// Allows reports whether contentType matches the allow-list
+func (l UploadLimits) Allows(contentType string) bool {
+	if len(l.AllowedTypes) == 0 {
+		return true
+	}
+	for _, allowed := range l.AllowedTypes {
+		if allowed == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(allowed, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// BlobStore keeps content under its SHA-256 hash, so identical uploads share
+// one stored copy. Every Store must be balanced by a Release of the returned key.
+type BlobStore interface {
+	// Store reads src only when no blob with hash exists yet
+	Store(ctx context.Context, hash string, src io.Reader, size int64, contentType string) (backend, key string, err error)
+	Release(ctx context.Context, backend, key string) error
+}
+
+// Upload streams files into blobs. The content type and extension come from
+// sniffing the content, not from the client. When a file is rejected the
+// files already stored by this call are released.
+func Upload(ctx context.Context, blobs BlobStore, files []*multipart.FileHeader, limits UploadLimits) ([]*dto.FileStoreUploadResponse, error) {
+	var total int64
+	for _, file := range files {
+		if limits.MaxFileSize > 0 && file.Size > limits.MaxFileSize {
+			return nil, fmt.Errorf("%w: %s", ErrFileTooLarge, file.Filename)
+		}
+		total += file.Size
+	}
+	if limits.MaxRequestSize > 0 && total > limits.MaxRequestSize {
+		return nil, ErrRequestTooLarge
+	}
+
+	var filesInfo []*dto.FileStoreUploadResponse
+	for _, file := range files {
+		info, err := uploadOne(ctx, blobs, file, limits)
+		if err != nil {
+			for _, stored := range filesInfo {
+				_ = blobs.Release(ctx, stored.Backend, stored.Key)
+			}
+			return nil, err
+		}
+		filesInfo = append(filesInfo, info)
+	}
+
+	return filesInfo, nil
+}
+
+func uploadOne(ctx context.Context, blobs BlobStore, file *multipart.FileHeader, limits UploadLimits) (*dto.FileStoreUploadResponse, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	return UploadReader(ctx, blobs, src, file.Filename, limits)
+}
+
+// UploadReader stores the content of src like Upload stores a file of a form.
+// src is read twice: once to hash it, once to store it when it is new.
+func UploadReader(ctx context.Context, blobs BlobStore, src io.ReadSeeker, filename string, limits UploadLimits) (*dto.FileStoreUploadResponse, error) {
+	head := make([]byte, sniffLength)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, err
+	}
+	head = head[:n]
+
+	detected := mimetype.Detect(head)
+	contentType, _, _ := strings.Cut(detected.String(), ";")
+	if !limits.Allows(contentType) {
+		return nil, fmt.Errorf("%w: %s is %s", ErrUnsupportedFileType, filename, contentType)
+	}
+
+	content := io.MultiReader(bytes.NewReader(head), src)
+	if limits.MaxFileSize > 0 {
+		content = io.LimitReader(content, limits.MaxFileSize+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(hash, content)
+	if err != nil {
+		return nil, err
+	}
+	if limits.MaxFileSize > 0 && size > limits.MaxFileSize {
+		return nil, fmt.Errorf("%w: %s", ErrFileTooLarge, filename)
+	}
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	backend, key, err := blobs.Store(ctx, sum, io.LimitReader(src, size), size, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := GenerateRandomFilename("file" + detected.Extension())
+	if err != nil {
+		_ = blobs.Release(ctx, backend, key)
+		return nil, err
+	}
+
+	return &dto.FileStoreUploadResponse{
+		Name:         name,
+		OriginalName: filepath.Base(filename),
+		Backend:      backend,
+		Key:          key,
+		Extension:    filepath.Ext(name),
+		Size:         size,
+		ContentType:  contentType,
+		Hash:         sum,
+	}, nil
+}
+
+// HashBytes returns the hex SHA-256 of data, the format of uploaded file hashes
+func HashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}